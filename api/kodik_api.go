@@ -1 +1,287 @@
 package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Адрес API kodik по умолчанию
+const kodikDefaultBaseURL = "https://kodikapi.com"
+
+// Скрипт публичного плеера kodik, из которого берется токен, если он не указан
+const kodikDefaultTokenURL = "https://kodik-add.com/add-players.min.js?v=2"
+
+// Типы материалов kodik, которые относятся к аниме
+var kodikAnimeTypes = []string{"anime", "anime-serial"}
+
+var kodik_token_re = regexp.MustCompile(`token\s*=\s*"([^"]+)"`)
+
+// Типы переводов kodik (KodikTranslation.Type)
+const (
+	KodikVoice     = "voice"
+	KodikSubtitles = "subtitles"
+)
+
+// Клиент API kodik (поиск материалов по названию и id на других сайтах).
+//
+// Для тестов BaseURL, TokenURL и HTTPClient можно заменить на адрес и клиент тестового сервера
+type KodikClient struct {
+	// Адрес API без / в конце (прим: https://kodikapi.com)
+	BaseURL string
+	// Ссылка на скрипт, из которого берется токен, если он не указан
+	TokenURL string
+	// По умолчанию - клиент tools.NewHTTPClient (прокси из tools.SetTransport, cookies сессии из контекста, распознавание страниц проверки)
+	HTTPClient *http.Client
+
+	token    string
+	token_mu sync.Mutex
+	context  context.Context
+}
+
+// Создает клиент API kodik.
+//
+// :token: токен API kodik. Пустая строка - токен будет получен из скрипта публичного плеера при первом запросе
+func NewKodikClient(token string) *KodikClient {
+	return &KodikClient{
+		BaseURL:    kodikDefaultBaseURL,
+		TokenURL:   kodikDefaultTokenURL,
+		HTTPClient: t.NewHTTPClient(15 * time.Second),
+		token:      token,
+		context:    context.Background(),
+	}
+}
+
+// Задает контекст для всех запросов клиента
+func (c *KodikClient) SetContext(ctx context.Context) {
+	c.context = ctx
+}
+
+// Возвращает токен API, при необходимости получая его из скрипта публичного плеера kodik
+func (c *KodikClient) Token() (string, error) {
+	c.token_mu.Lock()
+	defer c.token_mu.Unlock()
+	if c.token != "" {
+		return c.token, nil
+	}
+
+	body, err := c.get("Token", c.TokenURL)
+	if err != nil {
+		return "", err
+	}
+	match := kodik_token_re.FindSubmatch(body)
+	if match == nil {
+		error_message := fmt.Sprintf("Kodik API error : Token : в скрипте %s не найден токен", c.TokenURL)
+		log.Println(error_message)
+		return "", errs.NewTokenError(error_message)
+	}
+	c.token = string(match[1])
+	return c.token, nil
+}
+
+// Выполняет GET запрос и возвращает тело ответа. Ошибки сопоставляются по коду ответа
+func (c *KodikClient) get(method, URL string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(c.context, "GET", URL, nil)
+	if err != nil {
+		error_message := fmt.Sprintf("Kodik API error : %s : http не смог создать request. Ошибка: %v", method, err)
+		log.Println(error_message)
+		return nil, errs.NewServiceError(error_message)
+	}
+	resp, err := c.HTTPClient.Do(request)
+	if err != nil {
+		error_message := fmt.Sprintf("Kodik API error : %s : http клиент не смог выполнить запрос. Ошибка: %v", method, err)
+		log.Println(error_message)
+		return nil, t.WrapRequestError(err, error_message)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		error_message := fmt.Sprintf("Kodik API error : %s : не удалось прочитать тело ответа. Ошибка: %v", method, err)
+		log.Println(error_message)
+		return nil, errs.NewServiceError(error_message)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return body, nil
+	}
+
+	// На неверный токен и параметры kodik отвечает json с полем error (в том числе с кодом 500)
+	var api_error struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(body, &api_error)
+	error_message := fmt.Sprintf("Kodik API error : %s : сервер вернул код %d. Ответ: %s", method, resp.StatusCode, strings.TrimSpace(string(body)))
+	log.Println(error_message)
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || strings.Contains(strings.ToLower(api_error.Error), "токен"):
+		return nil, errs.NewTokenError(error_message)
+	case api_error.Error != "" || resp.StatusCode == http.StatusBadRequest:
+		return nil, errs.NewPostArgumentsError(error_message)
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errs.NewNoResultsError(error_message)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, errs.NewTooManyRequestsError(error_message)
+	default:
+		return nil, errs.NewServiceError(error_message)
+	}
+}
+
+// Перевод материала kodik
+type KodikTranslation struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	// KodikVoice или KodikSubtitles
+	Type string `json:"type"`
+}
+
+// Данные об аниме из баз shikimori и kinopoisk (возвращаются с with_material_data)
+type KodikMaterialData struct {
+	Title            string   `json:"title"`
+	AnimeTitle       string   `json:"anime_title"`
+	TitleEn          string   `json:"title_en"`
+	OtherTitles      []string `json:"other_titles"`
+	OtherTitlesEn    []string `json:"other_titles_en"`
+	OtherTitlesJp    []string `json:"other_titles_jp"`
+	AnimeKind        string   `json:"anime_kind"`
+	AnimeStatus      string   `json:"anime_status"`
+	Year             int      `json:"year"`
+	Description      string   `json:"description"`
+	AnimeDescription string   `json:"anime_description"`
+	PosterURL        string   `json:"poster_url"`
+	AnimePosterURL   string   `json:"anime_poster_url"`
+	Duration         int      `json:"duration"`
+	ShikimoriRating  float64  `json:"shikimori_rating"`
+	RatingMPAA       string   `json:"rating_mpaa"`
+	EpisodesTotal    int      `json:"episodes_total"`
+	EpisodesAired    int      `json:"episodes_aired"`
+	AnimeGenres      []string `json:"anime_genres"`
+	AnimeStudios     []string `json:"anime_studios"`
+}
+
+// Материал kodik: одно аниме в одном переводе
+type KodikResult struct {
+	// id материала (прим: serial-12345, movie-678)
+	ID string `json:"id"`
+	// Тип материала (прим: anime-serial, anime)
+	Type string `json:"type"`
+	// Ссылка на плеер (прим: //kodik.info/serial/12345/hash/720p)
+	Link          string           `json:"link"`
+	Title         string           `json:"title"`
+	TitleOrig     string           `json:"title_orig"`
+	OtherTitle    string           `json:"other_title"`
+	Translation   KodikTranslation `json:"translation"`
+	Year          int              `json:"year"`
+	LastSeason    int              `json:"last_season"`
+	LastEpisode   int              `json:"last_episode"`
+	EpisodesCount int              `json:"episodes_count"`
+	KinopoiskID   string           `json:"kinopoisk_id"`
+	ImdbID        string           `json:"imdb_id"`
+	ShikimoriID   string           `json:"shikimori_id"`
+	Quality       string           `json:"quality"`
+	Screenshots   []string         `json:"screenshots"`
+	// nil, если поиск был без WithMaterialData
+	MaterialData *KodikMaterialData `json:"material_data"`
+}
+
+// Параметры поиска kodik. Нужно указать хотя бы одно из Title, ShikimoriID, KinopoiskID, ImdbID, PlayerLink или ID
+type KodikSearchParams struct {
+	Title       string
+	ShikimoriID string
+	KinopoiskID string
+	ImdbID      string
+	// Ссылка на плеер kodik (прим: //kodik.info/serial/12345/hash/720p)
+	PlayerLink string
+	// id материала (прим: serial-12345)
+	ID string
+	// id перевода (KodikTranslation.ID), 0 - все переводы
+	TranslationID int
+	// Типы материалов (nil - только аниме: anime, anime-serial)
+	Types []string
+	// Максимум результатов (0 - по умолчанию API)
+	Limit            int
+	WithMaterialData bool
+}
+
+func (p *KodikSearchParams) values() url.Values {
+	params := url.Values{}
+	set := func(key, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			params.Set(key, value)
+		}
+	}
+	set("title", p.Title)
+	set("shikimori_id", p.ShikimoriID)
+	set("kinopoisk_id", p.KinopoiskID)
+	set("imdb_id", p.ImdbID)
+	set("player_link", p.PlayerLink)
+	set("id", p.ID)
+	if p.TranslationID != 0 {
+		params.Set("translation_id", strconv.Itoa(p.TranslationID))
+	}
+	types := p.Types
+	if types == nil {
+		types = kodikAnimeTypes
+	}
+	set("types", strings.Join(types, ","))
+	if p.Limit > 0 {
+		params.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.WithMaterialData {
+		params.Set("with_material_data", "true")
+	}
+	return params
+}
+
+// Поиск материалов kodik.
+//
+// :params: параметры поиска
+//
+// # Если ничего не найдено, возвращает ошибку errs.NoResults
+//
+// Возвращает материалы (каждый перевод аниме - отдельный материал)
+func (c *KodikClient) Search(params KodikSearchParams) ([]*KodikResult, error) {
+	values := params.values()
+	if values.Get("title") == "" && values.Get("shikimori_id") == "" && values.Get("kinopoisk_id") == "" &&
+		values.Get("imdb_id") == "" && values.Get("player_link") == "" && values.Get("id") == "" {
+		error_message := "Kodik API error : Search : не указаны название, id или ссылка на плеер"
+		log.Println(error_message)
+		return nil, errs.NewPostArgumentsError(error_message)
+	}
+	token, err := c.Token()
+	if err != nil {
+		return nil, err
+	}
+	values.Set("token", token)
+
+	body, err := c.get("Search", c.BaseURL+"/search?"+values.Encode())
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Total   int            `json:"total"`
+		Results []*KodikResult `json:"results"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		error_message := fmt.Sprintf("Kodik API error : Search : ошибка декодирования json: %v", err)
+		log.Println(error_message)
+		return nil, errs.NewJsonDecodeFailureError(error_message)
+	}
+	if len(data.Results) == 0 {
+		error_message := fmt.Sprintf("Kodik API error : Search : по запросу %s ничего не найдено", params.values().Encode())
+		log.Println(error_message)
+		return nil, errs.NewNoResultsError(error_message)
+	}
+	return data.Results, nil
+}
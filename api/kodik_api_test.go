@@ -0,0 +1,153 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Тестовый сервер kodik: отдает скрипт с токеном и ищет по shikimori_id
+type test_kodik struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func new_test_kodik(test *testing.T, token string) (*test_kodik, *KodikClient) {
+	s := &test_kodik{}
+	server := httptest.NewServer(s)
+	test.Cleanup(server.Close)
+
+	client := NewKodikClient(token)
+	client.BaseURL = server.URL
+	client.TokenURL = server.URL + "/add-players.min.js"
+	client.HTTPClient = server.Client()
+	return s, client
+}
+
+func (s *test_kodik) calls() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := strings.Join(s.requests, ", ")
+	s.requests = nil
+	return calls
+}
+
+func (s *test_kodik) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.Path)
+	s.mu.Unlock()
+
+	if r.URL.Path == "/add-players.min.js" {
+		w.Write([]byte(`!function(){var e={domain:"kodik.info",token="script-token",mode:"iframe"};}();`))
+		return
+	}
+	query := r.URL.Query()
+	if query.Get("token") != "script-token" {
+		write_json(w, http.StatusInternalServerError, map[string]string{"error": "Отсутствует или неверный токен"})
+		return
+	}
+	if query.Get("types") != "anime,anime-serial" {
+		write_json(w, http.StatusInternalServerError, map[string]string{"error": "Неверный тип"})
+		return
+	}
+	results := make([]map[string]any, 0)
+	if query.Get("shikimori_id") == "20" {
+		results = append(results, map[string]any{
+			"id":           "serial-1",
+			"type":         "anime-serial",
+			"link":         "//kodik.info/serial/1/hash/720p",
+			"title":        "Наруто",
+			"title_orig":   "Naruto",
+			"translation":  map[string]any{"id": 610, "title": "AniLibria.TV", "type": "voice"},
+			"year":         2002,
+			"last_episode": 220,
+			"shikimori_id": "20",
+			"kinopoisk_id": "420337",
+		})
+	}
+	write_json(w, http.StatusOK, map[string]any{"total": len(results), "results": results})
+}
+
+func TestKodikClientSearch(test *testing.T) {
+	server, client := new_test_kodik(test, "")
+
+	results, err := client.Search(KodikSearchParams{ShikimoriID: "20"})
+	if err != nil {
+		test.Fatalf("Search вернул ошибку: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "serial-1" || results[0].Translation.ID != 610 || results[0].Translation.Type != KodikVoice ||
+		results[0].KinopoiskID != "420337" || results[0].LastEpisode != 220 {
+		test.Errorf("Search = %+v", results[0])
+	}
+
+	// Токен получается из скрипта один раз
+	if _, err := client.Search(KodikSearchParams{ShikimoriID: "20"}); err != nil {
+		test.Fatalf("повторный Search вернул ошибку: %v", err)
+	}
+	if got := server.calls(); got != "/add-players.min.js, /search, /search" {
+		test.Errorf("запросы: %s", got)
+	}
+
+	if _, err := client.Search(KodikSearchParams{ShikimoriID: "1"}); err == nil {
+		test.Error("Search без результатов должен вернуть ошибку")
+	} else if _, ok := err.(*errs.NoResults); !ok {
+		test.Errorf("Search без результатов вернул %T, want *errs.NoResults", err)
+	}
+	if _, err := client.Search(KodikSearchParams{}); err == nil {
+		test.Error("Search без параметров должен вернуть ошибку")
+	} else if _, ok := err.(*errs.PostArgumentsError); !ok {
+		test.Errorf("Search без параметров вернул %T, want *errs.PostArgumentsError", err)
+	}
+}
+
+func TestKodikClientBadToken(test *testing.T) {
+	_, client := new_test_kodik(test, "wrong-token")
+	if _, err := client.Search(KodikSearchParams{ShikimoriID: "20"}); err == nil {
+		test.Error("Search с неверным токеном должен вернуть ошибку")
+	} else if _, ok := err.(*errs.TokenError); !ok {
+		test.Errorf("Search с неверным токеном вернул %T, want *errs.TokenError", err)
+	}
+}
+
+// Подменяет все сайты обработчиком handler (см. tools.SetTransport)
+type test_transport http.HandlerFunc
+
+func (handler test_transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	handler(recorder, r)
+	resp := recorder.Result()
+	resp.Request = r
+	return resp, nil
+}
+
+func TestKodikClientTransport(test *testing.T) {
+	server := &test_kodik{}
+	t.SetTransport(test_transport(server.ServeHTTP))
+	test.Cleanup(func() { t.SetTransport(nil) })
+
+	// Клиент по умолчанию отправляет запросы через транспорт tools.SetTransport
+	client := NewKodikClient("")
+	if results, err := client.Search(KodikSearchParams{ShikimoriID: "20"}); err != nil || len(results) != 1 {
+		test.Fatalf("Search через tools.SetTransport вернул %v, %v", results, err)
+	}
+	if got := server.calls(); got != "/add-players.min.js, /search" {
+		test.Errorf("запросы: %s", got)
+	}
+
+	// Страница проверки возвращается как errs.Challenge
+	t.SetTransport(test_transport(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<html><head><title>Just a moment...</title></head></html>"))
+	}))
+	var challenge *errs.Challenge
+	if _, err := client.Search(KodikSearchParams{ShikimoriID: "20"}); !errors.As(err, &challenge) {
+		test.Errorf("Search для страницы проверки вернул %T: %v, want *errs.Challenge", err, err)
+	}
+}
@@ -0,0 +1,514 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+// Адрес shikimori по умолчанию
+const shikimoriDefaultBaseURL = "https://shikimori.one"
+
+// Права приложения по умолчанию (список аниме пользователя)
+var shikimoriDefaultScopes = []string{"user_rates"}
+
+// Статусы аниме в списке пользователя
+const (
+	UserRatePlanned    = "planned"
+	UserRateWatching   = "watching"
+	UserRateRewatching = "rewatching"
+	UserRateCompleted  = "completed"
+	UserRateOnHold     = "on_hold"
+	UserRateDropped    = "dropped"
+)
+
+// Типы записей для user_rates и избранного
+const (
+	TargetAnime = "Anime"
+	TargetManga = "Manga"
+)
+
+// Клиент API shikimori с авторизацией OAuth2 (authorization code flow).
+//
+// Shikimori требует, чтобы User-Agent совпадал с названием приложения из https://shikimori.one/oauth/applications.
+// Для тестов BaseURL и HTTPClient можно заменить на адрес и клиент тестового сервера
+type ShikimoriClient struct {
+	// Адрес сайта без / в конце (прим: https://shikimori.one)
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	// Название приложения (отправляется в User-Agent)
+	UserAgent  string
+	Scopes     []string
+	HTTPClient *http.Client
+
+	store   TokenStore
+	context context.Context
+	// Не дает обновлять токен одновременно из нескольких запросов
+	refresh_mu sync.Mutex
+}
+
+// Создает клиент API shikimori.
+//
+// :client_id:, :client_secret:, :redirect_uri: - данные приложения на shikimori
+//
+// :app_name: название приложения (User-Agent)
+//
+// :store: хранилище токена (nil - хранилище в памяти)
+func NewShikimoriClient(client_id, client_secret, redirect_uri, app_name string, store TokenStore) *ShikimoriClient {
+	if store == nil {
+		store = NewMemoryTokenStore()
+	}
+	return &ShikimoriClient{
+		BaseURL:      shikimoriDefaultBaseURL,
+		ClientID:     client_id,
+		ClientSecret: client_secret,
+		RedirectURI:  redirect_uri,
+		UserAgent:    app_name,
+		Scopes:       append([]string{}, shikimoriDefaultScopes...),
+		HTTPClient:   &http.Client{Timeout: 15 * time.Second},
+		store:        store,
+		context:      context.Background(),
+	}
+}
+
+// Задает контекст для всех запросов клиента
+func (c *ShikimoriClient) SetContext(ctx context.Context) {
+	c.context = ctx
+}
+
+// Хранилище токена клиента
+func (c *ShikimoriClient) Store() TokenStore {
+	return c.store
+}
+
+// OAuth2
+
+// Возвращает ссылку, по которой пользователь разрешает приложению доступ. После подтверждения shikimori
+// перенаправляет на RedirectURI с параметром code, который передается в Exchange.
+//
+// :state: произвольная строка для защиты от CSRF (возвращается в RedirectURI без изменений)
+func (c *ShikimoriClient) AuthCodeURL(state string) string {
+	params := url.Values{
+		"client_id":     {c.ClientID},
+		"redirect_uri":  {c.RedirectURI},
+		"response_type": {"code"},
+		"scope":         {strings.Join(c.Scopes, " ")},
+	}
+	if state != "" {
+		params.Set("state", state)
+	}
+	return fmt.Sprintf("%s/oauth/authorize?%s", c.BaseURL, params.Encode())
+}
+
+// Получает токен по коду авторизации и сохраняет его в хранилище.
+//
+// :code: параметр code из RedirectURI
+func (c *ShikimoriClient) Exchange(code string) (*SHToken, error) {
+	return c.request_token(url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+		"code":          {code},
+		"redirect_uri":  {c.RedirectURI},
+	})
+}
+
+// Обновляет токен по refresh token из хранилища и сохраняет новый токен.
+// Обычно вызывать не требуется: запросы обновляют истекший токен сами
+func (c *ShikimoriClient) Refresh() (*SHToken, error) {
+	c.refresh_mu.Lock()
+	defer c.refresh_mu.Unlock()
+	token, err := c.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	return c.refresh(token)
+}
+
+// Вызывающий держит refresh_mu
+func (c *ShikimoriClient) refresh(token *SHToken) (*SHToken, error) {
+	if token == nil || token.RefreshToken == "" {
+		error_message := "Shikimori API error : Refresh : нет refresh token, нужна авторизация через AuthCodeURL и Exchange"
+		log.Println(error_message)
+		return nil, errs.NewTokenError(error_message)
+	}
+	return c.request_token(url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+		"refresh_token": {token.RefreshToken},
+	})
+}
+
+func (c *ShikimoriClient) request_token(form url.Values) (*SHToken, error) {
+	request, err := http.NewRequestWithContext(c.context, "POST", c.BaseURL+"/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		error_message := fmt.Sprintf("Shikimori API error : request_token : http не смог создать request. Ошибка: %v", err)
+		log.Println(error_message)
+		return nil, errs.NewServiceError(error_message)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("User-Agent", c.UserAgent)
+
+	token := &SHToken{}
+	if err := c.send(request, "request_token", token); err != nil {
+		return nil, err
+	}
+	if token.CreatedAt == 0 {
+		token.CreatedAt = time.Now().Unix()
+	}
+	if err := c.store.Save(token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Возвращает действующий access token, при необходимости обновляя его.
+//
+// :rejected: access token, который отклонил сервер ("" - нет). Токен обновляется, только если в хранилище все еще он:
+// при одновременных запросах с отклоненным токеном обновление выполняется один раз
+func (c *ShikimoriClient) access_token(rejected string) (string, error) {
+	c.refresh_mu.Lock()
+	defer c.refresh_mu.Unlock()
+	token, err := c.store.Load()
+	if err != nil {
+		return "", err
+	}
+	if token == nil {
+		error_message := "Shikimori API error : access_token : нет токена, нужна авторизация через AuthCodeURL и Exchange"
+		log.Println(error_message)
+		return "", errs.NewTokenError(error_message)
+	}
+	if (rejected != "" && token.AccessToken == rejected) || token.Expired(time.Now()) {
+		if token, err = c.refresh(token); err != nil {
+			return "", err
+		}
+	}
+	return token.AccessToken, nil
+}
+
+// Запросы
+
+// Выполняет запрос и декодирует json ответа в out (nil - ответ не нужен). Ошибки сопоставляются по коду ответа
+func (c *ShikimoriClient) send(request *http.Request, method string, out any) error {
+	resp, err := c.HTTPClient.Do(request)
+	if err != nil {
+		error_message := fmt.Sprintf("Shikimori API error : %s : http клиент не смог выполнить запрос. Ошибка: %v", method, err)
+		log.Println(error_message)
+		return errs.NewServiceError(error_message)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		error_message := fmt.Sprintf("Shikimori API error : %s : не удалось прочитать тело ответа. Ошибка: %v", method, err)
+		log.Println(error_message)
+		return errs.NewServiceError(error_message)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		error_message := fmt.Sprintf("Shikimori API error : %s : сервер вернул код %d. Ответ: %s", method, resp.StatusCode, strings.TrimSpace(string(body)))
+		log.Println(error_message)
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return errs.NewTokenError(error_message)
+		case resp.StatusCode == http.StatusNotFound:
+			return errs.NewNoResultsError(error_message)
+		case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity:
+			return errs.NewPostArgumentsError(error_message)
+		case resp.StatusCode == http.StatusTooManyRequests:
+			return errs.NewTooManyRequestsError(error_message)
+		default:
+			return errs.NewServiceError(error_message)
+		}
+	}
+
+	if out == nil || len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		error_message := fmt.Sprintf("Shikimori API error : %s : ошибка декодирования json: %v", method, err)
+		log.Println(error_message)
+		return errs.NewJsonDecodeFailureError(error_message)
+	}
+	return nil
+}
+
+// Выполняет запрос к API с access token. Если сервер отклонил токен, токен обновляется и запрос повторяется один раз
+//
+// :path: путь запроса (прим: /api/users/whoami)
+//
+// :query: параметры ссылки
+//
+// :payload: тело запроса (будет преобразовано в json), nil - без тела
+func (c *ShikimoriClient) do(method_name, http_method, path string, query url.Values, payload any, out any) error {
+	var data []byte
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			error_message := fmt.Sprintf("Shikimori API error : %s : не удалось преобразовать тело запроса в json. Ошибка: %v", method_name, err)
+			log.Println(error_message)
+			return errs.NewServiceError(error_message)
+		}
+	}
+	URL := c.BaseURL + path
+	if len(query) > 0 {
+		URL += "?" + query.Encode()
+	}
+
+	rejected := ""
+	for attempt := 1; ; attempt++ {
+		access_token, err := c.access_token(rejected)
+		if err != nil {
+			return err
+		}
+		request, err := http.NewRequestWithContext(c.context, http_method, URL, bytes.NewReader(data))
+		if err != nil {
+			error_message := fmt.Sprintf("Shikimori API error : %s : http не смог создать request. Ошибка: %v", method_name, err)
+			log.Println(error_message)
+			return errs.NewServiceError(error_message)
+		}
+		request.Header.Set("Authorization", "Bearer "+access_token)
+		request.Header.Set("User-Agent", c.UserAgent)
+		request.Header.Set("Accept", "application/json")
+		if payload != nil {
+			request.Header.Set("Content-Type", "application/json")
+		}
+
+		err = c.send(request, method_name, out)
+		if _, unauthorized := err.(*errs.TokenError); unauthorized && attempt == 1 {
+			log.Printf("Shikimori API warning : %s : сервер отклонил токен, обновляю токен", method_name)
+			rejected = access_token
+			continue
+		}
+		return err
+	}
+}
+
+// Пользователь
+
+// Изображение в нескольких размерах (ключи: x160, x148, x80, x64, x48, x32, x16 или original, preview, x96, x48)
+type SHImage map[string]string
+
+// Пользователь shikimori
+type SHUser struct {
+	ID           int64     `json:"id"`
+	Nickname     string    `json:"nickname"`
+	Avatar       string    `json:"avatar"`
+	Image        SHImage   `json:"image"`
+	LastOnlineAt time.Time `json:"last_online_at"`
+	URL          string    `json:"url"`
+	Name         string    `json:"name"`
+	Sex          string    `json:"sex"`
+	Website      string    `json:"website"`
+	BirthOn      string    `json:"birth_on"`
+	FullYears    int       `json:"full_years"`
+	Locale       string    `json:"locale"`
+}
+
+// Возвращает пользователя, которому принадлежит токен
+func (c *ShikimoriClient) WhoAmI() (*SHUser, error) {
+	user := &SHUser{}
+	if err := c.do("WhoAmI", "GET", "/api/users/whoami", nil, nil, user); err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		error_message := "Shikimori API error : WhoAmI : сервер не вернул пользователя (токен не принадлежит пользователю)"
+		log.Println(error_message)
+		return nil, errs.NewTokenError(error_message)
+	}
+	return user, nil
+}
+
+// Список пользователя (user_rates)
+
+// Запись в списке пользователя
+type SHUserRate struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	TargetID   int64     `json:"target_id"`
+	TargetType string    `json:"target_type"`
+	Score      int       `json:"score"`
+	Status     string    `json:"status"`
+	Rewatches  int       `json:"rewatches"`
+	Episodes   int       `json:"episodes"`
+	Volumes    int       `json:"volumes"`
+	Chapters   int       `json:"chapters"`
+	Text       string    `json:"text"`
+	TextHTML   string    `json:"text_html"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Фильтры списка пользователя. Пустые поля не передаются
+type SHUserRatesQuery struct {
+	UserID   int64
+	TargetID int64
+	// TargetAnime или TargetManga
+	TargetType string
+	// UserRateWatching, UserRateCompleted, ...
+	Status string
+	Page   int
+	// Записей на странице (максимум 1000 на shikimori)
+	Limit int
+}
+
+func (q *SHUserRatesQuery) values() url.Values {
+	values := url.Values{}
+	set_int := func(key string, value int64) {
+		if value != 0 {
+			values.Set(key, strconv.FormatInt(value, 10))
+		}
+	}
+	set_int("user_id", q.UserID)
+	set_int("target_id", q.TargetID)
+	set_int("page", int64(q.Page))
+	set_int("limit", int64(q.Limit))
+	if q.TargetType != "" {
+		values.Set("target_type", q.TargetType)
+	}
+	if q.Status != "" {
+		values.Set("status", q.Status)
+	}
+	return values
+}
+
+// Возвращает записи списка пользователя (GET /api/v2/user_rates)
+func (c *ShikimoriClient) UserRates(query SHUserRatesQuery) ([]*SHUserRate, error) {
+	rates := make([]*SHUserRate, 0)
+	if err := c.do("UserRates", "GET", "/api/v2/user_rates", query.values(), nil, &rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// Возвращает запись списка по id
+func (c *ShikimoriClient) UserRate(id int64) (*SHUserRate, error) {
+	rate := &SHUserRate{}
+	if err := c.do("UserRate", "GET", fmt.Sprintf("/api/v2/user_rates/%d", id), nil, nil, rate); err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+// Поля записи для создания и изменения. nil - поле не передается
+type SHUserRateFields struct {
+	// Обязательны при создании
+	UserID     *int64  `json:"user_id,omitempty"`
+	TargetID   *int64  `json:"target_id,omitempty"`
+	TargetType *string `json:"target_type,omitempty"`
+
+	Status    *string `json:"status,omitempty"`
+	Score     *int    `json:"score,omitempty"`
+	Episodes  *int    `json:"episodes,omitempty"`
+	Rewatches *int    `json:"rewatches,omitempty"`
+	Text      *string `json:"text,omitempty"`
+}
+
+// Добавляет аниме в список пользователя (POST /api/v2/user_rates).
+//
+// :user_id: id пользователя (можно получить из WhoAmI)
+//
+// :anime_id: id аниме на shikimori
+//
+// :fields: остальные поля (статус, оценка, эпизоды). nil - статус planned
+func (c *ShikimoriClient) CreateUserRate(user_id, anime_id int64, fields *SHUserRateFields) (*SHUserRate, error) {
+	payload := SHUserRateFields{}
+	if fields != nil {
+		payload = *fields
+	}
+	target_type := TargetAnime
+	payload.UserID, payload.TargetID, payload.TargetType = &user_id, &anime_id, &target_type
+
+	rate := &SHUserRate{}
+	if err := c.do("CreateUserRate", "POST", "/api/v2/user_rates", nil, map[string]any{"user_rate": payload}, rate); err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+// Изменяет запись списка (PATCH /api/v2/user_rates/:id): статус, оценку, количество просмотренных эпизодов и т.д.
+//
+// :id: id записи (SHUserRate.ID)
+//
+// :fields: изменяемые поля
+func (c *ShikimoriClient) UpdateUserRate(id int64, fields SHUserRateFields) (*SHUserRate, error) {
+	rate := &SHUserRate{}
+	if err := c.do("UpdateUserRate", "PATCH", fmt.Sprintf("/api/v2/user_rates/%d", id), nil, map[string]any{"user_rate": fields}, rate); err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+// Увеличивает количество просмотренных эпизодов на 1 (POST /api/v2/user_rates/:id/increment)
+func (c *ShikimoriClient) IncrementUserRate(id int64) (*SHUserRate, error) {
+	rate := &SHUserRate{}
+	if err := c.do("IncrementUserRate", "POST", fmt.Sprintf("/api/v2/user_rates/%d/increment", id), nil, nil, rate); err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+// Удаляет запись из списка (DELETE /api/v2/user_rates/:id)
+func (c *ShikimoriClient) DeleteUserRate(id int64) error {
+	return c.do("DeleteUserRate", "DELETE", fmt.Sprintf("/api/v2/user_rates/%d", id), nil, nil, nil)
+}
+
+// Избранное
+
+// Запись в избранном
+type SHFavourite struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Russian string `json:"russian"`
+	Image   string `json:"image"`
+	URL     string `json:"url"`
+}
+
+// Избранное пользователя по категориям
+type SHFavourites struct {
+	Animes     []*SHFavourite `json:"animes"`
+	Mangas     []*SHFavourite `json:"mangas"`
+	Ranobe     []*SHFavourite `json:"ranobe"`
+	Characters []*SHFavourite `json:"characters"`
+	People     []*SHFavourite `json:"people"`
+	Mangakas   []*SHFavourite `json:"mangakas"`
+	Seyu       []*SHFavourite `json:"seyu"`
+	Producers  []*SHFavourite `json:"producers"`
+}
+
+// Возвращает избранное пользователя (GET /api/users/:id/favourites)
+func (c *ShikimoriClient) Favourites(user_id int64) (*SHFavourites, error) {
+	favourites := &SHFavourites{}
+	if err := c.do("Favourites", "GET", fmt.Sprintf("/api/users/%d/favourites", user_id), nil, nil, favourites); err != nil {
+		return nil, err
+	}
+	return favourites, nil
+}
+
+// Добавляет запись в избранное пользователя токена (POST /api/favorites/:linked_type/:linked_id).
+//
+// :linked_type: Anime, Manga, Ranobe, Person или Character
+//
+// :linked_id: id записи на shikimori
+func (c *ShikimoriClient) AddFavourite(linked_type string, linked_id int64) error {
+	return c.do("AddFavourite", "POST", fmt.Sprintf("/api/favorites/%s/%d", linked_type, linked_id), nil, nil, nil)
+}
+
+// Удаляет запись из избранного пользователя токена (DELETE /api/favorites/:linked_type/:linked_id)
+func (c *ShikimoriClient) RemoveFavourite(linked_type string, linked_id int64) error {
+	return c.do("RemoveFavourite", "DELETE", fmt.Sprintf("/api/favorites/%s/%d", linked_type, linked_id), nil, nil, nil)
+}
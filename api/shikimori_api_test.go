@@ -0,0 +1,323 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+const testAppName = "AnimeParsersGo test"
+
+// Тестовый сервер shikimori: выдает токены, отклоняет устаревший access token и хранит user_rates в памяти
+type test_shikimori struct {
+	mu       sync.Mutex
+	access   string
+	refresh  string
+	refreshs int
+	next     int
+	rates    map[int64]map[string]any
+	requests []string
+}
+
+func new_test_shikimori(test *testing.T) (*test_shikimori, *ShikimoriClient) {
+	s := &test_shikimori{rates: make(map[int64]map[string]any)}
+	server := httptest.NewServer(s)
+	test.Cleanup(server.Close)
+
+	client := NewShikimoriClient("client-id", "client-secret", "urn:ietf:wg:oauth:2.0:oob", testAppName, nil)
+	client.BaseURL = server.URL
+	client.HTTPClient = server.Client()
+	return s, client
+}
+
+// Выдает новую пару токенов (старые перестают действовать). Вызывающий держит mu
+func (s *test_shikimori) issue(w http.ResponseWriter) {
+	s.next++
+	s.access = fmt.Sprintf("access-%d", s.next)
+	s.refresh = fmt.Sprintf("refresh-%d", s.next)
+	write_json(w, http.StatusOK, map[string]any{
+		"access_token":  s.access,
+		"refresh_token": s.refresh,
+		"token_type":    "Bearer",
+		"expires_in":    86400,
+		"scope":         "user_rates",
+		"created_at":    time.Now().Unix(),
+	})
+}
+
+// Запросы к серверу с последнего вызова (прим: "GET /api/users/whoami")
+func (s *test_shikimori) calls() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := strings.Join(s.requests, ", ")
+	s.requests = nil
+	return calls
+}
+
+// Делает текущий access token недействительным (как после его отзыва на сервере)
+func (s *test_shikimori) revoke() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.access = "revoked"
+}
+
+func write_json(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func (s *test_shikimori) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	if r.Header.Get("User-Agent") != testAppName {
+		write_json(w, http.StatusForbidden, map[string]string{"error": "user agent"})
+		return
+	}
+	if r.URL.Path == "/oauth/token" {
+		r.ParseForm()
+		if r.Form.Get("client_id") != "client-id" || r.Form.Get("client_secret") != "client-secret" {
+			write_json(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+			return
+		}
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			if r.Form.Get("code") != "good-code" || r.Form.Get("redirect_uri") != "urn:ietf:wg:oauth:2.0:oob" {
+				write_json(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+				return
+			}
+			s.issue(w)
+		case "refresh_token":
+			// refresh token одноразовый: повторное обновление тем же токеном отклоняется
+			if r.Form.Get("refresh_token") != s.refresh {
+				write_json(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+				return
+			}
+			s.refreshs++
+			s.issue(w)
+		default:
+			write_json(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		}
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.access {
+		write_json(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/api/users/whoami":
+		write_json(w, http.StatusOK, map[string]any{"id": 42, "nickname": "tester", "last_online_at": "2024-10-19T17:30:00.000+03:00"})
+	case r.Method == "POST" && r.URL.Path == "/api/v2/user_rates":
+		var body struct {
+			UserRate map[string]any `json:"user_rate"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || r.Header.Get("Content-Type") != "application/json" {
+			write_json(w, http.StatusUnprocessableEntity, map[string]string{"error": "bad body"})
+			return
+		}
+		id := int64(len(s.rates) + 1)
+		rate := body.UserRate
+		rate["id"] = id
+		if rate["status"] == nil {
+			rate["status"] = UserRatePlanned
+		}
+		s.rates[id] = rate
+		write_json(w, http.StatusCreated, rate)
+	case strings.HasPrefix(r.URL.Path, "/api/v2/user_rates/"):
+		var id int64
+		fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/api/v2/user_rates/"), "%d", &id)
+		rate := s.rates[id]
+		if rate == nil {
+			write_json(w, http.StatusNotFound, map[string]string{"error": "not found"})
+			return
+		}
+		switch r.Method {
+		case "PATCH":
+			var body struct {
+				UserRate map[string]any `json:"user_rate"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			for key, value := range body.UserRate {
+				rate[key] = value
+			}
+			write_json(w, http.StatusOK, rate)
+		case "DELETE":
+			delete(s.rates, id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			write_json(w, http.StatusOK, rate)
+		}
+	default:
+		write_json(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+func TestShikimoriClientExchange(test *testing.T) {
+	server, client := new_test_shikimori(test)
+
+	auth, err := url.Parse(client.AuthCodeURL("state-1"))
+	if err != nil {
+		test.Fatal(err)
+	}
+	query := auth.Query()
+	if auth.Path != "/oauth/authorize" || query.Get("client_id") != "client-id" || query.Get("response_type") != "code" ||
+		query.Get("scope") != "user_rates" || query.Get("state") != "state-1" {
+		test.Errorf("AuthCodeURL = %s", auth)
+	}
+
+	if _, err := client.Exchange("bad-code"); err == nil {
+		test.Error("Exchange с неверным кодом должен вернуть ошибку")
+	} else if _, ok := err.(*errs.PostArgumentsError); !ok {
+		test.Errorf("Exchange с неверным кодом вернул %T, want *errs.PostArgumentsError", err)
+	}
+	if token, _ := client.Store().Load(); token != nil {
+		test.Errorf("после неудачного Exchange в хранилище токен %+v", token)
+	}
+
+	token, err := client.Exchange("good-code")
+	if err != nil {
+		test.Fatalf("Exchange вернул ошибку: %v", err)
+	}
+	if token.AccessToken != server.access || token.RefreshToken != server.refresh || token.Expired(time.Now()) {
+		test.Errorf("Exchange вернул токен %+v", token)
+	}
+	if stored, _ := client.Store().Load(); stored == nil || *stored != *token {
+		test.Errorf("Exchange не сохранил токен в хранилище: %+v", stored)
+	}
+
+	user, err := client.WhoAmI()
+	if err != nil {
+		test.Fatalf("WhoAmI вернул ошибку: %v", err)
+	}
+	if user.ID != 42 || user.Nickname != "tester" || user.LastOnlineAt.IsZero() {
+		test.Errorf("WhoAmI = %+v", user)
+	}
+}
+
+func TestShikimoriClientRefreshOnUnauthorized(test *testing.T) {
+	server, client := new_test_shikimori(test)
+	if _, err := client.Exchange("good-code"); err != nil {
+		test.Fatalf("Exchange вернул ошибку: %v", err)
+	}
+	server.revoke()
+
+	const callers = 8
+	wg := sync.WaitGroup{}
+	errors_list := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.WhoAmI(); err != nil {
+				errors_list <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errors_list)
+	for err := range errors_list {
+		test.Errorf("WhoAmI после отзыва токена вернул ошибку: %v", err)
+	}
+
+	// Все запросы получили 401 со старым токеном, но обновление выполнено один раз
+	if server.refreshs != 1 {
+		test.Errorf("токен обновлен %d раз, want 1", server.refreshs)
+	}
+	if token, _ := client.Store().Load(); token == nil || token.AccessToken != server.access {
+		test.Errorf("в хранилище токен %+v, want %s", token, server.access)
+	}
+
+	// Истекший токен обновляется до запроса, без 401
+	token, _ := client.Store().Load()
+	token.CreatedAt = time.Now().Add(-48 * time.Hour).Unix()
+	if stored, _ := client.Store().Load(); stored.CreatedAt == token.CreatedAt {
+		test.Error("изменение загруженного токена изменило токен в хранилище")
+	}
+	if err := client.Store().Save(token); err != nil {
+		test.Fatalf("Save вернул ошибку: %v", err)
+	}
+	server.calls()
+	if _, err := client.WhoAmI(); err != nil {
+		test.Fatalf("WhoAmI с истекшим токеном вернул ошибку: %v", err)
+	}
+	if got := server.calls(); got != "POST /oauth/token, GET /api/users/whoami" {
+		test.Errorf("запросы с истекшим токеном: %s", got)
+	}
+
+	// Сервер отклоняет и новый токен: ошибка возвращается после одного повтора
+	server.revoke()
+	server.mu.Lock()
+	server.refresh = "unknown"
+	server.mu.Unlock()
+	if _, err := client.WhoAmI(); err == nil {
+		test.Error("WhoAmI с недействительным refresh token должен вернуть ошибку")
+	}
+}
+
+func TestShikimoriClientUserRates(test *testing.T) {
+	server, client := new_test_shikimori(test)
+	if _, err := client.Exchange("good-code"); err != nil {
+		test.Fatalf("Exchange вернул ошибку: %v", err)
+	}
+
+	status, episodes := UserRateWatching, 3
+	rate, err := client.CreateUserRate(42, 20, &SHUserRateFields{Status: &status, Episodes: &episodes})
+	if err != nil {
+		test.Fatalf("CreateUserRate вернул ошибку: %v", err)
+	}
+	if rate.ID != 1 || rate.UserID != 42 || rate.TargetID != 20 || rate.TargetType != TargetAnime || rate.Status != UserRateWatching || rate.Episodes != 3 {
+		test.Errorf("CreateUserRate = %+v", rate)
+	}
+	if created := server.rates[1]; created["score"] != nil || created["text"] != nil {
+		test.Errorf("CreateUserRate отправил пустые поля: %v", created)
+	}
+
+	completed, score := UserRateCompleted, 9
+	rate, err = client.UpdateUserRate(rate.ID, SHUserRateFields{Status: &completed, Score: &score})
+	if err != nil {
+		test.Fatalf("UpdateUserRate вернул ошибку: %v", err)
+	}
+	if rate.Status != UserRateCompleted || rate.Score != 9 || rate.Episodes != 3 {
+		test.Errorf("UpdateUserRate = %+v", rate)
+	}
+
+	if err := client.DeleteUserRate(rate.ID); err != nil {
+		test.Fatalf("DeleteUserRate вернул ошибку: %v", err)
+	}
+	if len(server.rates) != 0 {
+		test.Errorf("после DeleteUserRate осталось записей: %d", len(server.rates))
+	}
+	if err := client.DeleteUserRate(rate.ID); err == nil {
+		test.Error("DeleteUserRate для удаленной записи должен вернуть ошибку")
+	} else if _, ok := err.(*errs.NoResults); !ok {
+		test.Errorf("DeleteUserRate для удаленной записи вернул %T, want *errs.NoResults", err)
+	}
+
+	want := "POST /oauth/token, POST /api/v2/user_rates, PATCH /api/v2/user_rates/1, DELETE /api/v2/user_rates/1, DELETE /api/v2/user_rates/1"
+	if got := server.calls(); got != want {
+		test.Errorf("запросы: %s, want %s", got, want)
+	}
+}
+
+func TestShikimoriClientWithoutToken(test *testing.T) {
+	server, client := new_test_shikimori(test)
+	if _, err := client.WhoAmI(); err == nil {
+		test.Error("WhoAmI без токена должен вернуть ошибку")
+	} else if _, ok := err.(*errs.TokenError); !ok {
+		test.Errorf("WhoAmI без токена вернул %T, want *errs.TokenError", err)
+	}
+	if calls := server.calls(); calls != "" {
+		test.Errorf("без токена выполнены запросы: %s", calls)
+	}
+}
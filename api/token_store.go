@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+// Запас времени до истечения access token, при котором он обновляется заранее
+const tokenExpiryMargin = time.Minute
+
+// OAuth2 токен shikimori (ответ /oauth/token)
+type SHToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	// Время жизни access token в секундах
+	ExpiresIn int64  `json:"expires_in"`
+	Scope     string `json:"scope"`
+	// Время выдачи токена (unix)
+	CreatedAt int64 `json:"created_at"`
+}
+
+// Время истечения access token. Нулевое время - неизвестно
+func (t *SHToken) Expiry() time.Time {
+	if t.CreatedAt == 0 || t.ExpiresIn == 0 {
+		return time.Time{}
+	}
+	return time.Unix(t.CreatedAt+t.ExpiresIn, 0)
+}
+
+// Проверяет, истек ли (или скоро истечет) access token
+func (t *SHToken) Expired(now time.Time) bool {
+	expiry := t.Expiry()
+	return !expiry.IsZero() && !now.Add(tokenExpiryMargin).Before(expiry)
+}
+
+// Хранилище токена пользователя. Токен сохраняется после получения и каждого обновления
+type TokenStore interface {
+	// Возвращает сохраненный токен или nil, если токена нет
+	Load() (*SHToken, error)
+	Save(token *SHToken) error
+}
+
+// Хранилище токена в памяти. Load и Save работают с копиями токена, поэтому изменение полученного токена не меняет сохраненный
+type MemoryTokenStore struct {
+	mu    sync.RWMutex
+	token *SHToken
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{}
+}
+
+func (s *MemoryTokenStore) Load() (*SHToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.token == nil {
+		return nil, nil
+	}
+	token := *s.token
+	return &token, nil
+}
+
+func (s *MemoryTokenStore) Save(token *SHToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token == nil {
+		s.token = nil
+		return nil
+	}
+	saved := *token
+	s.token = &saved
+	return nil
+}
+
+// Хранилище токена в json файле
+type FileTokenStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// :path: путь до файла (прим: data/shikimori_token.json). Файл создается при первом Save
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+func (s *FileTokenStore) Load() (*SHToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errs.NewServiceError(fmt.Sprintf("Shikimori API error : FileTokenStore.Load : не удалось прочитать файл %s. Ошибка: %v", s.path, err))
+	}
+	token := &SHToken{}
+	if err := json.Unmarshal(data, token); err != nil {
+		return nil, errs.NewJsonDecodeFailureError(fmt.Sprintf("Shikimori API error : FileTokenStore.Load : не удалось разобрать файл %s. Ошибка: %v", s.path, err))
+	}
+	return token, nil
+}
+
+func (s *FileTokenStore) Save(token *SHToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return errs.NewServiceError(fmt.Sprintf("Shikimori API error : FileTokenStore.Save : не удалось преобразовать токен в json. Ошибка: %v", err))
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return errs.NewServiceError(fmt.Sprintf("Shikimori API error : FileTokenStore.Save : не удалось создать папку %s. Ошибка: %v", dir, err))
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return errs.NewServiceError(fmt.Sprintf("Shikimori API error : FileTokenStore.Save : не удалось записать файл %s. Ошибка: %v", tmp, err))
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return errs.NewServiceError(fmt.Sprintf("Shikimori API error : FileTokenStore.Save : не удалось переименовать %s в %s. Ошибка: %v", tmp, s.path, err))
+	}
+	return nil
+}
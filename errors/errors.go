@@ -222,3 +222,57 @@ func (e *AttributeError) Error() string {
 	}
 	return "Не удалось найти атрибут"
 }
+
+// Ошибка для обозначения неизвестного (не зарегистрированного) источника
+type UnknownSource struct {
+	message string
+}
+
+func NewUnknownSourceError(message string) error {
+	return &UnknownSource{message: message}
+}
+
+func (e *UnknownSource) Error() string {
+	if e.message != "" {
+		return e.message
+	}
+	return "Источник не зарегистрирован"
+}
+
+// Ошибка для обозначения страницы проверки от анти-бот защиты (Cloudflare, DDoS-Guard) вместо ответа сайта
+type Challenge struct {
+	message string
+	// Название защиты (прим: cloudflare, ddos-guard)
+	Provider string
+	// Ссылка, на которую пришла страница проверки
+	URL string
+	// Код ответа (страница проверки приходит с кодом 200, 403 или 503)
+	StatusCode int
+}
+
+func NewChallengeError(provider, URL string, status_code int, message string) error {
+	return &Challenge{message: message, Provider: provider, URL: URL, StatusCode: status_code}
+}
+
+func (e *Challenge) Error() string {
+	if e.message != "" {
+		return e.message
+	}
+	return "Сайт вернул страницу проверки " + e.Provider
+}
+
+// Ошибка для обозначения недоступного сайта: запрос не получил ответа сервера (сетевая ошибка или таймаут)
+type NetworkError struct {
+	message string
+}
+
+func NewNetworkError(message string) error {
+	return &NetworkError{message: message}
+}
+
+func (e *NetworkError) Error() string {
+	if e.message != "" {
+		return e.message
+	}
+	return "Сайт недоступен"
+}
@@ -70,8 +70,11 @@ func aniboom_test() {
 	}
 	search, err := AniboomParser.Search(title)
 	if err != nil {
+		// Search возвращает найденные результаты вместе с ошибками отдельных аниме
 		fmt.Printf("Search вернул ошибку: %v", err)
-		return
+		if len(search) == 0 {
+			return
+		}
 	}
 	for _, v := range search {
 		fmt.Printf("Search: %+v\n\n", *v)
@@ -103,7 +106,14 @@ func aniboom_test() {
 		fmt.Printf("GetTranslationsInfo: %+v\n\n", *v)
 	}
 
-	err = AniboomParser.GetAsFile(anime_info.AnimegoID, anime_info.Translations[0].TranslationID, "output", 1)
+	translation_id := ""
+	for _, translation := range anime_info.Translations {
+		if translation.Provider == "aniboom" {
+			translation_id = translation.TranslationID
+			break
+		}
+	}
+	err = AniboomParser.GetAsFile(anime_info.AnimegoID, translation_id, "output", 1)
 	if err != nil {
 		fmt.Printf("GetAsFile вернул ошибку: %v", err)
 		return
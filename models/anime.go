@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Названия источников (Anime.Source)
+const (
+	SourceAniboom   = "aniboom"
+	SourceShikimori = "shikimori"
+	SourceKodik     = "kodik"
+)
+
+// Аниме в едином для всех источников виде. Заполняется нормализаторами парсеров (прим: ABSearchResult.Normalize)
+type Anime struct {
+	// Источник данных (SourceAniboom, SourceShikimori, ...)
+	Source string `json:"source"`
+	// Ссылка на страницу аниме в источнике
+	Link          string   `json:"link"`
+	Title         string   `json:"title"`
+	OriginalTitle string   `json:"original_title"`
+	OtherTitles   []string `json:"other_titles"`
+
+	AnimegoID   string `json:"animego_id,omitempty"`
+	ShikimoriID string `json:"shikimori_id,omitempty"`
+	KinopoiskID string `json:"kinopoisk_id,omitempty"`
+
+	Kind   AnimeKind   `json:"kind"`
+	Status AnimeStatus `json:"status"`
+	Rating MPAARating  `json:"rating"`
+	Year   int         `json:"year"`
+	// Начало и конец выхода. Нулевое время, если не известно
+	AiredFrom time.Time `json:"aired_from"`
+	AiredTo   time.Time `json:"aired_to"`
+
+	// Всего и вышедших эпизодов. 0, если не известно
+	Episodes        int           `json:"episodes"`
+	EpisodesAired   int           `json:"episodes_aired"`
+	EpisodeDuration time.Duration `json:"episode_duration"`
+
+	Studios []string `json:"studios"`
+	Genres  []Genre  `json:"genres"`
+	// Жанры и темы источника, которые не удалось сопоставить с Genre
+	UnknownGenres []string `json:"unknown_genres"`
+
+	Description string `json:"description"`
+	Poster      string `json:"poster"`
+	// Оценка по 10-бальной шкале. 0, если не известна
+	Score float64 `json:"score"`
+}
+
+// Эпизод в едином для всех источников виде
+type Episode struct {
+	Number int           `json:"number"`
+	Title  string        `json:"title"`
+	Status EpisodeStatus `json:"status"`
+	// Дата выхода. Нулевое время, если не известна
+	AiredAt time.Time `json:"aired_at"`
+}
+
+// Перевод (озвучка или субтитры) в едином для всех источников виде
+type Translation struct {
+	// id перевода, который передается в Stream. Пустой, если перевод нельзя получить через Stream источника
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Озвучка или субтитры ("dub" или "subtitles")
+	Kind string `json:"kind"`
+}
+
+// Видео эпизода
+type Stream struct {
+	// "playlist" - Content содержит mpd/m3u8 плейлист, "embed" - URL содержит ссылку на плеер
+	Kind    string `json:"kind"`
+	URL     string `json:"url"`
+	Content string `json:"content,omitempty"`
+}
@@ -0,0 +1,127 @@
+package models
+
+import "strings"
+
+// Статус выхода аниме
+type AnimeStatus string
+
+const (
+	AnimeStatusUnknown   AnimeStatus = ""
+	AnimeStatusAnnounced AnimeStatus = "announced"
+	AnimeStatusOngoing   AnimeStatus = "ongoing"
+	AnimeStatusReleased  AnimeStatus = "released"
+)
+
+// Преобразует статус с animego или shikimori (прим: "Онгоинг", "вышло", "анонс") в AnimeStatus
+func ParseAnimeStatus(raw string) AnimeStatus {
+	switch value := strings.ToLower(strings.TrimSpace(raw)); {
+	case strings.HasPrefix(value, "анонс"), value == "anons", value == "announced":
+		return AnimeStatusAnnounced
+	case strings.HasPrefix(value, "онгоинг"), strings.HasPrefix(value, "сейчас выходит"), value == "ongoing":
+		return AnimeStatusOngoing
+	case strings.HasPrefix(value, "вышл"), strings.HasPrefix(value, "вышел"), value == "released":
+		return AnimeStatusReleased
+	}
+	return AnimeStatusUnknown
+}
+
+// Статус эпизода в расписании animego
+type EpisodeStatus string
+
+const (
+	EpisodeStatusUnknown   EpisodeStatus = ""
+	EpisodeStatusAnnounced EpisodeStatus = "announced"
+	EpisodeStatusReleased  EpisodeStatus = "released"
+)
+
+// Преобразует статус эпизода с animego ("анонс" или "вышел") в EpisodeStatus
+func ParseEpisodeStatus(raw string) EpisodeStatus {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "анонс":
+		return EpisodeStatusAnnounced
+	case "вышел":
+		return EpisodeStatusReleased
+	}
+	return EpisodeStatusUnknown
+}
+
+// Тип аниме
+type AnimeKind string
+
+const (
+	AnimeKindUnknown   AnimeKind = ""
+	AnimeKindTV        AnimeKind = "tv"
+	AnimeKindMovie     AnimeKind = "movie"
+	AnimeKindOVA       AnimeKind = "ova"
+	AnimeKindONA       AnimeKind = "ona"
+	AnimeKindSpecial   AnimeKind = "special"
+	AnimeKindTVSpecial AnimeKind = "tv_special"
+	AnimeKindMusic     AnimeKind = "music"
+	AnimeKindPV        AnimeKind = "pv"
+	AnimeKindCM        AnimeKind = "cm"
+)
+
+// Преобразует тип с animego или shikimori (прим: "ТВ Сериал", "TV Сериал", "Фильм", "OVA", "Клип") в AnimeKind
+func ParseAnimeKind(raw string) AnimeKind {
+	value := strings.ToLower(strings.TrimSpace(raw))
+	switch {
+	case value == "":
+		return AnimeKindUnknown
+	case strings.Contains(value, "спешл") || strings.Contains(value, "special"):
+		if strings.HasPrefix(value, "tv") || strings.HasPrefix(value, "тв") {
+			return AnimeKindTVSpecial
+		}
+		return AnimeKindSpecial
+	case strings.Contains(value, "сериал"), value == "tv", value == "тв":
+		return AnimeKindTV
+	case strings.Contains(value, "фильм"), value == "movie":
+		return AnimeKindMovie
+	case strings.Contains(value, "ova"):
+		return AnimeKindOVA
+	case strings.Contains(value, "ona"):
+		return AnimeKindONA
+	case strings.Contains(value, "клип"), value == "music":
+		return AnimeKindMusic
+	case strings.Contains(value, "проморолик"), value == "pv":
+		return AnimeKindPV
+	case strings.Contains(value, "реклама"), value == "cm":
+		return AnimeKindCM
+	}
+	return AnimeKindUnknown
+}
+
+// Рейтинг MPAA
+type MPAARating string
+
+const (
+	MPAARatingUnknown MPAARating = ""
+	MPAARatingG       MPAARating = "g"
+	MPAARatingPG      MPAARating = "pg"
+	MPAARatingPG13    MPAARating = "pg_13"
+	MPAARatingR       MPAARating = "r"
+	MPAARatingRPlus   MPAARating = "r_plus"
+	MPAARatingRx      MPAARating = "rx"
+)
+
+// Преобразует рейтинг с animego или shikimori (прим: "PG-13", "R-17", "R+", "Rx") в MPAARating
+func ParseMPAARating(raw string) MPAARating {
+	value := strings.ToLower(strings.TrimSpace(raw))
+	if index := strings.IndexAny(value, " ("); index != -1 {
+		value = value[:index]
+	}
+	switch value {
+	case "g":
+		return MPAARatingG
+	case "pg":
+		return MPAARatingPG
+	case "pg-13", "pg_13":
+		return MPAARatingPG13
+	case "r", "r-17", "r_17":
+		return MPAARatingR
+	case "r+", "r_plus":
+		return MPAARatingRPlus
+	case "rx":
+		return MPAARatingRx
+	}
+	return MPAARatingUnknown
+}
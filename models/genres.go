@@ -0,0 +1,230 @@
+package models
+
+import "strings"
+
+// Канонический жанр (или тема) аниме. Значения совпадают с англоязычными названиями жанров shikimori
+type Genre string
+
+const (
+	GenreAction          Genre = "action"
+	GenreAdventure       Genre = "adventure"
+	GenreRacing          Genre = "racing"
+	GenreComedy          Genre = "comedy"
+	GenreAvantGarde      Genre = "avant_garde"
+	GenreMythology       Genre = "mythology"
+	GenreMystery         Genre = "mystery"
+	GenreDrama           Genre = "drama"
+	GenreEcchi           Genre = "ecchi"
+	GenreFantasy         Genre = "fantasy"
+	GenreStrategyGame    Genre = "strategy_game"
+	GenreHistorical      Genre = "historical"
+	GenreHorror          Genre = "horror"
+	GenreKids            Genre = "kids"
+	GenreMartialArts     Genre = "martial_arts"
+	GenreMecha           Genre = "mecha"
+	GenreMusic           Genre = "music"
+	GenreParody          Genre = "parody"
+	GenreSamurai         Genre = "samurai"
+	GenreRomance         Genre = "romance"
+	GenreSchool          Genre = "school"
+	GenreSciFi           Genre = "sci_fi"
+	GenreShoujo          Genre = "shoujo"
+	GenreShounen         Genre = "shounen"
+	GenreSpace           Genre = "space"
+	GenreSports          Genre = "sports"
+	GenreSuperPower      Genre = "super_power"
+	GenreVampire         Genre = "vampire"
+	GenreHarem           Genre = "harem"
+	GenreSliceOfLife     Genre = "slice_of_life"
+	GenreSupernatural    Genre = "supernatural"
+	GenreMilitary        Genre = "military"
+	GenreDetective       Genre = "detective"
+	GenrePsychological   Genre = "psychological"
+	GenreSeinen          Genre = "seinen"
+	GenreJosei           Genre = "josei"
+	GenreTeamSports      Genre = "team_sports"
+	GenreVideoGame       Genre = "video_game"
+	GenreAdultCast       Genre = "adult_cast"
+	GenreGore            Genre = "gore"
+	GenreReincarnation   Genre = "reincarnation"
+	GenreLovePolygon     Genre = "love_polygon"
+	GenreVisualArts      Genre = "visual_arts"
+	GenreTimeTravel      Genre = "time_travel"
+	GenreGagHumor        Genre = "gag_humor"
+	GenreAwardWinning    Genre = "award_winning"
+	GenreSuspense        Genre = "suspense"
+	GenreCombatSports    Genre = "combat_sports"
+	GenreCGDCT           Genre = "cgdct"
+	GenreMahouShoujo     Genre = "mahou_shoujo"
+	GenreReverseHarem    Genre = "reverse_harem"
+	GenreIsekai          Genre = "isekai"
+	GenreDelinquents     Genre = "delinquents"
+	GenreChildcare       Genre = "childcare"
+	GenreMagicalSexShift Genre = "magical_sex_shift"
+	GenreShowbiz         Genre = "showbiz"
+	GenreOtakuCulture    Genre = "otaku_culture"
+	GenreOrganizedCrime  Genre = "organized_crime"
+	GenreWorkplace       Genre = "workplace"
+	GenreIyashikei       Genre = "iyashikei"
+	GenreSurvival        Genre = "survival"
+	GenrePerformingArts  Genre = "performing_arts"
+	GenreAnthropomorphic Genre = "anthropomorphic"
+	GenreCrossdressing   Genre = "crossdressing"
+	GenreIdolsFemale     Genre = "idols_female"
+	GenreHighStakesGame  Genre = "high_stakes_game"
+	GenreMedical         Genre = "medical"
+	GenrePets            Genre = "pets"
+	GenreEducational     Genre = "educational"
+	GenreIdolsMale       Genre = "idols_male"
+	GenreRomanticSubtext Genre = "romantic_subtext"
+	GenreGourmet         Genre = "gourmet"
+	GenreErotica         Genre = "erotica"
+	GenreHentai          Genre = "hentai"
+	GenreBoysLove        Genre = "boys_love"
+	GenreGirlsLove       Genre = "girls_love"
+	GenreThriller        Genre = "thriller"
+	GenreDementia        Genre = "dementia"
+	GenrePolice          Genre = "police"
+	GenreMagic           Genre = "magic"
+	GenreGame            Genre = "game"
+	GenreShoujoAi        Genre = "shoujo_ai"
+	GenreShounenAi       Genre = "shounen_ai"
+)
+
+// Русские названия жанров animego и shikimori (в нижнем регистре, ё заменена на е)
+var russian_genres = map[string]Genre{
+	"экшен":               GenreAction,
+	"приключения":         GenreAdventure,
+	"гонки":               GenreRacing,
+	"комедия":             GenreComedy,
+	"авангард":            GenreAvantGarde,
+	"мифология":           GenreMythology,
+	"тайна":               GenreMystery,
+	"мистика":             GenreMystery,
+	"драма":               GenreDrama,
+	"этти":                GenreEcchi,
+	"фэнтези":             GenreFantasy,
+	"стратегические игры": GenreStrategyGame,
+	"исторический":        GenreHistorical,
+	"история":             GenreHistorical,
+	"ужасы":               GenreHorror,
+	"детское":             GenreKids,
+	"детский":             GenreKids,
+	"боевые искусства":    GenreMartialArts,
+	"меха":                GenreMecha,
+	"музыка":              GenreMusic,
+	"пародия":             GenreParody,
+	"самураи":             GenreSamurai,
+	"романтика":           GenreRomance,
+	"школа":               GenreSchool,
+	"фантастика":          GenreSciFi,
+	"седзе":               GenreShoujo,
+	"седзе-ай":            GenreShoujoAi,
+	"сенен":               GenreShounen,
+	"сенен-ай":            GenreShounenAi,
+	"космос":              GenreSpace,
+	"спорт":               GenreSports,
+	"супер сила":          GenreSuperPower,
+	"суперсила":           GenreSuperPower,
+	"вампиры":             GenreVampire,
+	"гарем":               GenreHarem,
+	"повседневность":      GenreSliceOfLife,
+	"сверхъестественное":  GenreSupernatural,
+	"военное":             GenreMilitary,
+	"детектив":            GenreDetective,
+	"психологическое":     GenrePsychological,
+	"психологический":     GenrePsychological,
+	"сэйнэн":              GenreSeinen,
+	"сейнен":              GenreSeinen,
+	"дзесей":              GenreJosei,
+	"дзесэй":              GenreJosei,
+	"командный спорт":     GenreTeamSports,
+	"видеоигры":           GenreVideoGame,
+	"взрослые персонажи":  GenreAdultCast,
+	"жестокость":          GenreGore,
+	"реинкарнация":        GenreReincarnation,
+	"любовный многоугольник":    GenreLovePolygon,
+	"изобразительное искусство": GenreVisualArts,
+	"путешествие во времени":    GenreTimeTravel,
+	"гэг-юмор":                        GenreGagHumor,
+	"удостоено наград":                GenreAwardWinning,
+	"саспенс":                         GenreSuspense,
+	"спортивные единоборства":         GenreCombatSports,
+	"милые девочки делают милые вещи": GenreCGDCT,
+	"cgdct":          GenreCGDCT,
+	"махо-седзе":     GenreMahouShoujo,
+	"реверс-гарем":   GenreReverseHarem,
+	"исэкай":         GenreIsekai,
+	"исекай":         GenreIsekai,
+	"хулиганы":       GenreDelinquents,
+	"забота о детях": GenreChildcare,
+	"магическая смена пола":       GenreMagicalSexShift,
+	"шоу-бизнес":                  GenreShowbiz,
+	"культура отаку":              GenreOtakuCulture,
+	"организованная преступность": GenreOrganizedCrime,
+	"работа":                      GenreWorkplace,
+	"иясикэй":                     GenreIyashikei,
+	"выживание":                   GenreSurvival,
+	"исполнительское искусство":   GenrePerformingArts,
+	"антропоморфизм":              GenreAnthropomorphic,
+	"кроссдрессинг":               GenreCrossdressing,
+	"идолы (жен.)":                GenreIdolsFemale,
+	"игра с высокими ставками":    GenreHighStakesGame,
+	"медицина":                    GenreMedical,
+	"питомцы":                     GenrePets,
+	"образовательное":             GenreEducational,
+	"идолы (муж.)":                GenreIdolsMale,
+	"романтический подтекст":      GenreRomanticSubtext,
+	"гурман":                      GenreGourmet,
+	"эротика":                     GenreErotica,
+	"хентай":                      GenreHentai,
+	"яой":                         GenreBoysLove,
+	"юри":                         GenreGirlsLove,
+	"триллер":                     GenreThriller,
+	"безумие":                     GenreDementia,
+	"полиция":                     GenrePolice,
+	"магия":                       GenreMagic,
+	"игры":                        GenreGame,
+}
+
+func normalize_genre(raw string) string {
+	value := strings.ToLower(strings.TrimSpace(raw))
+	return strings.ReplaceAll(value, "ё", "е")
+}
+
+// Преобразует название жанра с animego или shikimori (русское или английское, прим: "Сёнен", "Slice of Life") в Genre.
+//
+// Возвращает false, если жанр не известен
+func ParseGenre(raw string) (Genre, bool) {
+	value := normalize_genre(raw)
+	if genre, exists := russian_genres[value]; exists {
+		return genre, true
+	}
+	// Английские названия (прим: "Slice of Life", "Sci-Fi", "Idols (Female)")
+	slug := strings.NewReplacer(" ", "_", "-", "_", "(", "", ")", "").Replace(value)
+	for _, genre := range russian_genres {
+		if string(genre) == slug {
+			return genre, true
+		}
+	}
+	return "", false
+}
+
+// Преобразует названия жанров в канонические. Неизвестные жанры возвращаются отдельно без изменений
+func ParseGenres(raw []string) (genres []Genre, unknown []string) {
+	genres = make([]Genre, 0, len(raw))
+	unknown = make([]string, 0)
+	seen := make(map[Genre]bool)
+	for _, name := range raw {
+		genre, ok := ParseGenre(name)
+		if !ok {
+			unknown = append(unknown, strings.TrimSpace(name))
+			continue
+		}
+		if !seen[genre] {
+			seen[genre] = true
+			genres = append(genres, genre)
+		}
+	}
+	return genres, unknown
+}
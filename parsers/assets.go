@@ -0,0 +1,52 @@
+package parsers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Загрузчик картинок animego (постеры, скриншоты) с Referer активного зеркала и контекстом парсера (включая сессию).
+//
+// :dir: папка для файлов (прим: data/assets)
+//
+// Возвращает ссылку на tools.AssetFetcher
+func (ab *AniboomParser) Assets(dir string) *t.AssetFetcher {
+	fetcher := t.NewAssetFetcher(dir, fmt.Sprintf("https://%s/", ab.domain()))
+	fetcher.SetContext(ab.context)
+	return fetcher
+}
+
+// Загрузчик картинок shikimori (постеры, скриншоты, персонажи) с Referer активного зеркала и контекстом парсера (включая сессию).
+// Для выбора варианта 2x передайте в Fetch значение PosterSrcset или PictureSrcset
+//
+// :dir: папка для файлов (прим: data/assets)
+//
+// Возвращает ссылку на tools.AssetFetcher
+func (sh *ShikimoriParser) Assets(dir string) *t.AssetFetcher {
+	fetcher := t.NewAssetFetcher(dir, fmt.Sprintf("https://%s/", sh.domain()))
+	fetcher.SetContext(sh.context)
+	return fetcher
+}
+
+// Варианты картинки img в формате srcset. На shikimori вариант 1x указан только в src, а srcset содержит "... 2x",
+// поэтому src добавляется в начало как вариант 1x (прим: "/a.jpg, /a_2x.jpg 2x")
+func img_srcset(img *goquery.Selection) string {
+	src, _ := img.Attr("src")
+	srcset, _ := img.Attr("srcset")
+	src, srcset = strings.TrimSpace(src), strings.TrimSpace(srcset)
+	if src == "" || strings.HasPrefix(src, "data:") {
+		return srcset
+	}
+	if srcset == "" {
+		return src
+	}
+	for _, candidate := range t.ParseSrcset(srcset) {
+		if candidate.URL == src {
+			return srcset
+		}
+	}
+	return src + ", " + srcset
+}
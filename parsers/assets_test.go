@@ -0,0 +1,37 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+func TestImgSrcset(test *testing.T) {
+	tests := []struct {
+		html string
+		want string
+		one  string
+	}{
+		{`<img src="/a.jpg" srcset="/a_2x.jpg 2x">`, "/a.jpg, /a_2x.jpg 2x", "/a.jpg"},
+		{`<img src="/a.jpg" srcset="/a.jpg, /a_2x.jpg 2x">`, "/a.jpg, /a_2x.jpg 2x", "/a.jpg"},
+		{`<img srcset="/a_2x.jpg 2x">`, "/a_2x.jpg 2x", "/a_2x.jpg"},
+		{`<img src="/a.jpg">`, "/a.jpg", "/a.jpg"},
+		{`<img src="data:image/gif;base64,R0lGOD" srcset="/a_2x.jpg 2x">`, "/a_2x.jpg 2x", "/a_2x.jpg"},
+		{`<span></span>`, "", ""},
+	}
+	for _, tt := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+		if err != nil {
+			test.Fatal(err)
+		}
+		got := img_srcset(doc.Find("img").First())
+		if got != tt.want {
+			test.Errorf("img_srcset(%s) = %q, want %q", tt.html, got, tt.want)
+		}
+		if one := t.SrcsetVariant(got, 1); one != tt.one {
+			test.Errorf("SrcsetVariant(img_srcset(%s), 1) = %q, want %q", tt.html, one, tt.one)
+		}
+	}
+}
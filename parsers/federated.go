@@ -0,0 +1,217 @@
+package parsers
+
+import (
+	"log"
+	"sync"
+
+	"github.com/Quavke/AnimeParsersGo/models"
+	"github.com/Quavke/AnimeParsersGo/titles"
+)
+
+// Аниме, найденное в одном или нескольких источниках
+type FederatedResult struct {
+	// Объединенная запись. Поля берутся из первого источника, в котором они заполнены
+	Anime *models.Anime `json:"anime"`
+	// Ссылки на страницу аниме по названиям источников
+	Links map[string]string `json:"links"`
+	// Исходные записи по названиям источников
+	Sources map[string]*models.Anime `json:"sources"`
+}
+
+// Результат FederatedSearch
+type FederatedSearchResult struct {
+	Results []*FederatedResult `json:"results"`
+	// Тексты ошибок по названиям источников. Источники без ошибок сюда не попадают
+	Errors map[string]string `json:"errors"`
+}
+
+// Минимальное сходство названий, при котором записи разных источников считаются одним аниме
+const sameTitleSimilarity = 0.92
+
+// Проверяет, что записи из разных источников относятся к одному аниме:
+// совпадает id shikimori, либо похоже оригинальное (или русское) название и совпадает год (если он известен в обеих записях).
+// Названия с разными номерами сезона не считаются похожими
+func same_anime(a, b *models.Anime) bool {
+	if a.ShikimoriID != "" && b.ShikimoriID != "" {
+		return a.ShikimoriID == b.ShikimoriID
+	}
+	if a.Year != 0 && b.Year != 0 && a.Year != b.Year {
+		return false
+	}
+	return same_title(a.OriginalTitle, b.OriginalTitle) || same_title(a.Title, b.Title)
+}
+
+func same_title(a, b string) bool {
+	if titles.Key(a) == "" || titles.Key(b) == "" {
+		return false
+	}
+	if titles.Key(a) == titles.Key(b) {
+		return true
+	}
+	// Разные сезоны одного тайтла имеют одинаковый SeriesKey, но разные Key
+	if titles.SeriesKey(a) == titles.SeriesKey(b) {
+		return false
+	}
+	return titles.Similarity(a, b) >= sameTitleSimilarity
+}
+
+// Заполняет пустые поля dst значениями из src
+func merge_anime(dst, src *models.Anime) {
+	if dst.Title == "" {
+		dst.Title = src.Title
+	}
+	if dst.OriginalTitle == "" {
+		dst.OriginalTitle = src.OriginalTitle
+	}
+	for _, title := range src.OtherTitles {
+		exists := false
+		for _, other := range dst.OtherTitles {
+			if other == title {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			dst.OtherTitles = append(dst.OtherTitles, title)
+		}
+	}
+	if dst.AnimegoID == "" {
+		dst.AnimegoID = src.AnimegoID
+	}
+	if dst.ShikimoriID == "" {
+		dst.ShikimoriID = src.ShikimoriID
+	}
+	if dst.KinopoiskID == "" {
+		dst.KinopoiskID = src.KinopoiskID
+	}
+	if dst.Kind == models.AnimeKindUnknown {
+		dst.Kind = src.Kind
+	}
+	if dst.Status == models.AnimeStatusUnknown {
+		dst.Status = src.Status
+	}
+	if dst.Rating == models.MPAARatingUnknown {
+		dst.Rating = src.Rating
+	}
+	if dst.Year == 0 {
+		dst.Year = src.Year
+	}
+	if dst.AiredFrom.IsZero() {
+		dst.AiredFrom = src.AiredFrom
+	}
+	if dst.AiredTo.IsZero() {
+		dst.AiredTo = src.AiredTo
+	}
+	if dst.Episodes == 0 {
+		dst.Episodes = src.Episodes
+	}
+	if dst.EpisodesAired == 0 {
+		dst.EpisodesAired = src.EpisodesAired
+	}
+	if dst.EpisodeDuration == 0 {
+		dst.EpisodeDuration = src.EpisodeDuration
+	}
+	if len(dst.Studios) == 0 {
+		dst.Studios = src.Studios
+	}
+	if len(dst.Genres) == 0 {
+		dst.Genres = src.Genres
+		dst.UnknownGenres = src.UnknownGenres
+	}
+	if dst.Description == "" {
+		dst.Description = src.Description
+	}
+	if dst.Poster == "" {
+		dst.Poster = src.Poster
+	}
+	if dst.Score == 0 {
+		dst.Score = src.Score
+	}
+}
+
+// Объединяет результаты поиска разных источников. Порядок групп - порядок первого появления
+func merge_federated(results [][]*models.Anime) []*FederatedResult {
+	merged := make([]*FederatedResult, 0)
+	for _, source_results := range results {
+		for _, anime := range source_results {
+			var group *FederatedResult
+			for _, candidate := range merged {
+				// В одной группе не может быть двух записей одного источника
+				if _, exists := candidate.Sources[anime.Source]; exists {
+					continue
+				}
+				if same_anime(candidate.Anime, anime) {
+					group = candidate
+					break
+				}
+			}
+			if group == nil {
+				base := *anime
+				base.OtherTitles = append(make([]string, 0, len(anime.OtherTitles)), anime.OtherTitles...)
+				group = &FederatedResult{
+					Anime:   &base,
+					Links:   make(map[string]string),
+					Sources: make(map[string]*models.Anime),
+				}
+				merged = append(merged, group)
+			} else {
+				merge_anime(group.Anime, anime)
+			}
+			group.Links[anime.Source] = anime.Link
+			group.Sources[anime.Source] = anime
+		}
+	}
+	return merged
+}
+
+// Ищет аниме сразу во всех источниках и объединяет результаты, относящиеся к одному аниме.
+//
+// :title: название аниме
+//
+// :sources: источники для поиска. Если не указаны, используются все зарегистрированные источники (см. SourceNames) с доменами по умолчанию
+//
+// Источники опрашиваются параллельно. Ошибка одного источника не прерывает поиск и записывается в FederatedSearchResult.Errors.
+// Записи объединяются по id shikimori, а если он неизвестен - по сходству оригинального названия и году.
+//
+// Возвращает ссылку на FederatedSearchResult
+func FederatedSearch(title string, sources ...Source) *FederatedSearchResult {
+	res := &FederatedSearchResult{
+		Results: make([]*FederatedResult, 0),
+		Errors:  make(map[string]string),
+	}
+
+	if len(sources) == 0 {
+		for _, name := range SourceNames() {
+			source, err := NewSource(name, "")
+			if err != nil {
+				res.Errors[name] = err.Error()
+				continue
+			}
+			sources = append(sources, source)
+		}
+	}
+
+	results := make([][]*models.Anime, len(sources))
+	mu := sync.Mutex{}
+	wg := &sync.WaitGroup{}
+
+	for i, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found, err := source.SearchAnime(title)
+			if err != nil {
+				log.Printf("Federated search warning : FederatedSearch : источник %s вернул ошибку: %v", source.Name(), err)
+				mu.Lock()
+				res.Errors[source.Name()] = err.Error()
+				mu.Unlock()
+				return
+			}
+			results[i] = found
+		}()
+	}
+	wg.Wait()
+
+	res.Results = merge_federated(results)
+	return res
+}
@@ -0,0 +1,64 @@
+package parsers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+)
+
+// Источник с заранее заданными результатами поиска
+type test_source struct {
+	name  string
+	found []*models.Anime
+	err   error
+}
+
+func (s *test_source) Name() string { return s.name }
+
+func (s *test_source) SearchAnime(title string) ([]*models.Anime, error) {
+	return s.found, s.err
+}
+
+func (s *test_source) Info(link string) (*models.Anime, error) {
+	return nil, errs.NewNoResultsError("test source")
+}
+
+func TestFederatedSearch(test *testing.T) {
+	_, kodik := new_test_kodik(test)
+	shikimori := &test_source{name: models.SourceShikimori, found: []*models.Anime{
+		{Source: models.SourceShikimori, Link: "https://shikimori.one/animes/20-naruto", Title: "Наруто", OriginalTitle: "Naruto", ShikimoriID: "20", Year: 2002},
+	}}
+	aniboom := &test_source{name: models.SourceAniboom, err: errs.NewServiceError("animego недоступен")}
+
+	res := FederatedSearch("naruto", shikimori, kodik, aniboom)
+	if len(res.Results) != 2 {
+		test.Fatalf("FederatedSearch вернул %d результатов, want 2", len(res.Results))
+	}
+	naruto := res.Results[0]
+	if naruto.Links[models.SourceShikimori] != "https://shikimori.one/animes/20-naruto" || naruto.Links[models.SourceKodik] != "https://kodik.info/serial/1/hash/720p" {
+		test.Errorf("Links = %v", naruto.Links)
+	}
+	if naruto.Anime.KinopoiskID != "420337" || naruto.Anime.Episodes != 220 {
+		test.Errorf("объединенная запись = %+v", naruto.Anime)
+	}
+	if movie := res.Results[1]; len(movie.Sources) != 1 || movie.Sources[models.SourceKodik] == nil {
+		test.Errorf("фильм = %+v", movie.Sources)
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		test.Fatal(err)
+	}
+	var decoded struct {
+		Errors map[string]string `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		test.Fatal(err)
+	}
+	if len(decoded.Errors) != 1 || !strings.Contains(decoded.Errors[models.SourceAniboom], "animego недоступен") {
+		test.Errorf("errors в json = %v", decoded.Errors)
+	}
+}
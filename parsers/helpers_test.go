@@ -0,0 +1,50 @@
+package parsers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Подменяет все сайты обработчиком handler (см. tools.SetTransport) и запоминает запросы к ним
+type test_sites struct {
+	mu       sync.Mutex
+	requests []string
+	handler  http.HandlerFunc
+}
+
+func use_test_sites(test *testing.T, handler http.HandlerFunc) *test_sites {
+	s := &test_sites{handler: handler}
+	t.SetTransport(s)
+	test.Cleanup(func() { t.SetTransport(nil) })
+	return s
+}
+
+func (s *test_sites) RoundTrip(r *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.Host+r.URL.Path)
+	s.mu.Unlock()
+	recorder := httptest.NewRecorder()
+	s.handler(recorder, r)
+	resp := recorder.Result()
+	resp.Request = r
+	return resp, nil
+}
+
+// Количество запросов к сайтам с последнего вызова
+func (s *test_sites) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := len(s.requests)
+	s.requests = nil
+	return count
+}
+
+func write_test_json(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,438 @@
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Quavke/AnimeParsersGo/api"
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+	"github.com/Quavke/AnimeParsersGo/titles"
+)
+
+// Источники id для IDResolver.Resolve (помимо models.SourceAniboom, models.SourceShikimori и models.SourceKodik)
+const (
+	SourceMAL       = "mal"
+	SourceKinopoisk = "kinopoisk"
+)
+
+// Минимальная уверенность сопоставления по умолчанию
+const defaultMinConfidence = 0.6
+
+// Соответствие id одного аниме на разных сайтах
+type IDMapping struct {
+	AnimegoID   string `json:"animego_id"`
+	ShikimoriID string `json:"shikimori_id"`
+	// id на MyAnimeList совпадает с id на shikimori
+	MALID       string `json:"mal_id"`
+	KinopoiskID string `json:"kinopoisk_id"`
+	// id материала kodik (прим: serial-12345)
+	KodikID string `json:"kodik_id"`
+	// Уверенность сопоставления от 0 до 1
+	Confidence float64 `json:"confidence"`
+	// Каким способом найдено соответствие (прим: "shikimori search")
+	Method    string    `json:"method"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Хранилище найденных соответствий, чтобы каждое соответствие вычислялось один раз
+type IDMappingStore interface {
+	// Возвращает соответствие по id в источнике (models.SourceAniboom, models.SourceShikimori, models.SourceKodik, SourceMAL или SourceKinopoisk)
+	Get(source, id string) (*IDMapping, bool, error)
+	// Сохраняет соответствие под всеми его id
+	Put(mapping *IDMapping) error
+}
+
+func mapping_key(source, id string) string {
+	if source == SourceMAL {
+		source = models.SourceShikimori
+	}
+	return source + ":" + id
+}
+
+func mapping_keys(mapping *IDMapping) []string {
+	keys := make([]string, 0, 4)
+	if mapping.AnimegoID != "" {
+		keys = append(keys, mapping_key(models.SourceAniboom, mapping.AnimegoID))
+	}
+	if mapping.ShikimoriID != "" {
+		keys = append(keys, mapping_key(models.SourceShikimori, mapping.ShikimoriID))
+	}
+	if mapping.KinopoiskID != "" {
+		keys = append(keys, mapping_key(SourceKinopoisk, mapping.KinopoiskID))
+	}
+	if mapping.KodikID != "" {
+		keys = append(keys, mapping_key(models.SourceKodik, mapping.KodikID))
+	}
+	return keys
+}
+
+// Хранилище соответствий в памяти
+type MemoryIDStore struct {
+	mu       sync.RWMutex
+	mappings map[string]*IDMapping
+}
+
+func NewMemoryIDStore() *MemoryIDStore {
+	return &MemoryIDStore{mappings: make(map[string]*IDMapping)}
+}
+
+func (s *MemoryIDStore) Get(source, id string) (*IDMapping, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mapping, exists := s.mappings[mapping_key(source, id)]
+	return mapping, exists, nil
+}
+
+func (s *MemoryIDStore) Put(mapping *IDMapping) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range mapping_keys(mapping) {
+		s.mappings[key] = mapping
+	}
+	return nil
+}
+
+// Хранилище соответствий в json файле. Файл перезаписывается целиком при каждом Put
+type FileIDStore struct {
+	path   string
+	memory *MemoryIDStore
+	// Put выполняются по очереди, чтобы в файл не попал устаревший снимок соответствий
+	write_mu sync.Mutex
+}
+
+// Создает хранилище в json файле. Если файл существует, соответствия загружаются из него.
+//
+// :path: путь до файла (прим: data/id_mappings.json)
+func NewFileIDStore(path string) (*FileIDStore, error) {
+	store := &FileIDStore{path: path, memory: NewMemoryIDStore()}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, errs.NewServiceError(fmt.Sprintf("ID resolver error : NewFileIDStore : не удалось прочитать файл %s. Ошибка: %v", path, err))
+	}
+	if len(data) == 0 {
+		return store, nil
+	}
+	if err := json.Unmarshal(data, &store.memory.mappings); err != nil {
+		return nil, errs.NewJsonDecodeFailureError(fmt.Sprintf("ID resolver error : NewFileIDStore : не удалось разобрать файл %s. Ошибка: %v", path, err))
+	}
+	return store, nil
+}
+
+func (s *FileIDStore) Get(source, id string) (*IDMapping, bool, error) {
+	return s.memory.Get(source, id)
+}
+
+func (s *FileIDStore) Put(mapping *IDMapping) error {
+	s.write_mu.Lock()
+	defer s.write_mu.Unlock()
+	if err := s.memory.Put(mapping); err != nil {
+		return err
+	}
+
+	s.memory.mu.RLock()
+	data, err := json.MarshalIndent(s.memory.mappings, "", "  ")
+	s.memory.mu.RUnlock()
+	if err != nil {
+		return errs.NewServiceError(fmt.Sprintf("ID resolver error : FileIDStore.Put : не удалось преобразовать соответствия в json. Ошибка: %v", err))
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errs.NewServiceError(fmt.Sprintf("ID resolver error : FileIDStore.Put : не удалось создать папку %s. Ошибка: %v", dir, err))
+	}
+	// Временный файл в той же папке, чтобы Rename заменил файл атомарно
+	file, err := os.CreateTemp(dir, filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return errs.NewServiceError(fmt.Sprintf("ID resolver error : FileIDStore.Put : не удалось создать временный файл в %s. Ошибка: %v", dir, err))
+	}
+	tmp := file.Name()
+	_, err = file.Write(data)
+	if close_err := file.Close(); err == nil {
+		err = close_err
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0o644)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return errs.NewServiceError(fmt.Sprintf("ID resolver error : FileIDStore.Put : не удалось записать файл %s. Ошибка: %v", tmp, err))
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return errs.NewServiceError(fmt.Sprintf("ID resolver error : FileIDStore.Put : не удалось переименовать %s в %s. Ошибка: %v", tmp, s.path, err))
+	}
+	return nil
+}
+
+// Находит соответствие id аниме между animego, shikimori, MyAnimeList, kinopoisk и kodik.
+//
+// animego и shikimori сопоставляются поиском на другом сайте по оригинальному названию с нечетким сравнением названий и проверкой года и типа
+// (для animego кандидатами также служат результаты поиска kodik, у которых есть shikimori_id).
+// id kinopoisk и kodik берутся из материалов kodik по shikimori_id, а по id kinopoisk или kodik находится shikimori_id
+type IDResolver struct {
+	aniboom   *AniboomParser
+	shikimori *ShikimoriParser
+	kodik     *KodikParser
+	store     IDMappingStore
+	// Соответствия с меньшей уверенностью не возвращаются и не сохраняются
+	MinConfidence float64
+}
+
+// Создает IDResolver.
+//
+// :aniboom: парсер animego (nil - парсер с доменом по умолчанию)
+//
+// :shikimori: парсер shikimori (nil - парсер с доменом по умолчанию)
+//
+// :kodik: парсер kodik (nil - парсер с токеном публичного плеера kodik)
+//
+// :store: хранилище соответствий (nil - хранилище в памяти)
+func NewIDResolver(aniboom *AniboomParser, shikimori *ShikimoriParser, kodik *KodikParser, store IDMappingStore) *IDResolver {
+	if aniboom == nil {
+		aniboom = NewAniboomParser("")
+	}
+	if shikimori == nil {
+		shikimori = NewShikimoriParser("")
+	}
+	if kodik == nil {
+		kodik = NewKodikParser("")
+	}
+	if store == nil {
+		store = NewMemoryIDStore()
+	}
+	return &IDResolver{
+		aniboom:       aniboom,
+		shikimori:     shikimori,
+		kodik:         kodik,
+		store:         store,
+		MinConfidence: defaultMinConfidence,
+	}
+}
+
+// Оценивает, насколько candidate похож на anime: сходство названия (до 0.6, см. titles.Similarity), совпадение года (0.3) и типа (0.1)
+func match_confidence(anime, candidate *models.Anime) float64 {
+	anime_titles := append([]string{anime.OriginalTitle, anime.Title}, anime.OtherTitles...)
+	candidate_titles := append([]string{candidate.OriginalTitle, candidate.Title}, candidate.OtherTitles...)
+
+	similarity := 0.0
+	for _, title := range anime_titles {
+		if strings.TrimSpace(title) == "" {
+			continue
+		}
+		similarity = max(similarity, titles.BestScore(title, candidate_titles...))
+	}
+	score := 0.6 * similarity
+	if anime.Year != 0 && anime.Year == candidate.Year {
+		score += 0.3
+	}
+	if anime.Kind != models.AnimeKindUnknown && anime.Kind == candidate.Kind {
+		score += 0.1
+	}
+	return score
+}
+
+// Возвращает лучшего кандидата и его уверенность
+func best_match(anime *models.Anime, candidates []*models.Anime) (*models.Anime, float64) {
+	var best *models.Anime
+	best_score := 0.0
+	for _, candidate := range candidates {
+		if score := match_confidence(anime, candidate); score > best_score {
+			best, best_score = candidate, score
+		}
+	}
+	return best, best_score
+}
+
+// Ищет кандидатов в источнике по всем названиям аниме, пропуская повторы
+func search_candidates(source Source, anime *models.Anime) []*models.Anime {
+	result := make([]*models.Anime, 0)
+	seen := make(map[string]bool)
+	queried := make(map[string]bool)
+	for _, title := range append([]string{anime.OriginalTitle, anime.Title}, anime.OtherTitles...) {
+		title = strings.TrimSpace(title)
+		if title == "" || queried[titles.Key(title)] {
+			continue
+		}
+		queried[titles.Key(title)] = true
+		found, err := source.SearchAnime(title)
+		if err != nil {
+			log.Printf("ID resolver warning : search_candidates : поиск %q в %s вернул ошибку: %v", title, source.Name(), err)
+			continue
+		}
+		for _, candidate := range found {
+			if !seen[candidate.Link] {
+				seen[candidate.Link] = true
+				result = append(result, candidate)
+			}
+		}
+	}
+	return result
+}
+
+// Находит id аниме на остальных сайтах.
+//
+// :source: источник id (models.SourceAniboom для id animego, models.SourceShikimori, SourceMAL, SourceKinopoisk или models.SourceKodik)
+//
+// :id: id аниме в источнике (для kodik - id материала, прим: serial-12345)
+//
+// Сначала проверяется хранилище, найденное соответствие сохраняется в него.
+// Если id kinopoisk или kodik не удалось найти (прим: kodik недоступен), соответствие возвращается без них.
+// Если уверенность ниже MinConfidence, возвращает ошибку errs.NoResults
+//
+// Возвращает ссылку на IDMapping
+func (r *IDResolver) Resolve(source, id string) (*IDMapping, error) {
+	if mapping, exists, err := r.store.Get(source, id); err != nil {
+		return nil, err
+	} else if exists {
+		return mapping, nil
+	}
+
+	var mapping *IDMapping
+	var err error
+	switch source {
+	case models.SourceAniboom:
+		mapping, err = r.resolve_animego(id)
+	case models.SourceShikimori, SourceMAL:
+		mapping, err = r.resolve_shikimori(id)
+	case SourceKinopoisk, models.SourceKodik:
+		mapping, err = r.resolve_kodik(source, id)
+	default:
+		return nil, errs.NewUnknownSourceError(fmt.Sprintf("ID resolver error : Resolve : неизвестный источник id %q", source))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Confidence < r.MinConfidence {
+		error_message := fmt.Sprintf("ID resolver error : Resolve : для %s id %s лучшее соответствие имеет уверенность %.2f меньше %.2f", source, id, mapping.Confidence, r.MinConfidence)
+		log.Println(error_message)
+		return nil, errs.NewNoResultsError(error_message)
+	}
+
+	if mapping.KodikID == "" {
+		r.add_kodik_ids(mapping)
+	}
+	mapping.MALID = mapping.ShikimoriID
+	mapping.UpdatedAt = time.Now()
+	if err := r.store.Put(mapping); err != nil {
+		log.Printf("ID resolver warning : Resolve : не удалось сохранить соответствие: %v", err)
+	}
+	return mapping, nil
+}
+
+func (r *IDResolver) resolve_animego(animego_id string) (*IDMapping, error) {
+	info, err := r.aniboom.Info(r.aniboom.anime_link(animego_id))
+	if err != nil {
+		log.Printf("ID resolver error : resolve_animego : Info вернул ошибку: %v", err)
+		return nil, err
+	}
+	candidates := search_candidates(r.shikimori, info)
+	for _, candidate := range search_candidates(r.kodik, info) {
+		if candidate.ShikimoriID != "" {
+			candidates = append(candidates, candidate)
+		}
+	}
+	best, confidence := best_match(info, candidates)
+	if best == nil {
+		return nil, errs.NewNoResultsError(fmt.Sprintf("ID resolver error : resolve_animego : на shikimori и kodik не найдено аниме для animego_id %s", animego_id))
+	}
+	return &IDMapping{
+		AnimegoID:   animego_id,
+		ShikimoriID: best.ShikimoriID,
+		KinopoiskID: best.KinopoiskID,
+		Confidence:  confidence,
+		Method:      best.Source + " search",
+	}, nil
+}
+
+func (r *IDResolver) resolve_shikimori(shikimori_id string) (*IDMapping, error) {
+	info, err := r.shikimori.Info(r.shikimori.anime_link(shikimori_id))
+	if err != nil {
+		log.Printf("ID resolver error : resolve_shikimori : Info вернул ошибку: %v", err)
+		return nil, err
+	}
+	info.ShikimoriID = shikimori_id
+	best, confidence := best_match(info, search_candidates(r.aniboom, info))
+	if best == nil {
+		return nil, errs.NewNoResultsError(fmt.Sprintf("ID resolver error : resolve_shikimori : на animego не найдено аниме для shikimori_id %s", shikimori_id))
+	}
+	return &IDMapping{
+		AnimegoID:   best.AnimegoID,
+		ShikimoriID: shikimori_id,
+		Confidence:  confidence,
+		Method:      "animego search",
+	}, nil
+}
+
+// Находит shikimori_id по id kinopoisk или id материала kodik, затем аниме на animego (см. resolve_shikimori).
+// id из kodik считаются точными (уверенность 1). AnimegoID добавляется, только если уверенность поиска на animego
+// не ниже MinConfidence, иначе (в том числе при ошибке поиска) соответствие возвращается без него
+func (r *IDResolver) resolve_kodik(source, id string) (*IDMapping, error) {
+	params := api.KodikSearchParams{KinopoiskID: id}
+	if source == models.SourceKodik {
+		params = api.KodikSearchParams{ID: id}
+	}
+	results, err := r.kodik.client.Search(params)
+	if err != nil {
+		log.Printf("ID resolver error : resolve_kodik : поиск kodik вернул ошибку: %v", err)
+		return nil, err
+	}
+	var found *api.KodikResult
+	for _, result := range results {
+		if result.ShikimoriID != "" {
+			found = result
+			break
+		}
+	}
+	if found == nil {
+		error_message := fmt.Sprintf("ID resolver error : resolve_kodik : у материалов kodik для %s id %s нет shikimori_id", source, id)
+		log.Println(error_message)
+		return nil, errs.NewNoResultsError(error_message)
+	}
+
+	mapping := &IDMapping{ShikimoriID: found.ShikimoriID, Confidence: 1, Method: "kodik ids"}
+	animego, err := r.resolve_shikimori(found.ShikimoriID)
+	switch {
+	case err != nil:
+		log.Printf("ID resolver warning : resolve_kodik : аниме на animego для shikimori_id %s не найдено: %v", found.ShikimoriID, err)
+	case animego.Confidence < r.MinConfidence:
+		log.Printf("ID resolver warning : resolve_kodik : аниме на animego для shikimori_id %s найдено с уверенностью %.2f меньше %.2f", found.ShikimoriID, animego.Confidence, r.MinConfidence)
+	default:
+		mapping.AnimegoID = animego.AnimegoID
+		mapping.Method += ", " + animego.Method
+	}
+	mapping.KinopoiskID = found.KinopoiskID
+	mapping.KodikID = found.ID
+	if source == models.SourceKodik {
+		mapping.KodikID = id
+	}
+	return mapping, nil
+}
+
+// Дополняет соответствие id kinopoisk и kodik из материалов kodik по shikimori_id. Ошибка kodik не прерывает сопоставление
+func (r *IDResolver) add_kodik_ids(mapping *IDMapping) {
+	if mapping.ShikimoriID == "" {
+		return
+	}
+	results, err := r.kodik.client.Search(api.KodikSearchParams{ShikimoriID: mapping.ShikimoriID})
+	if err != nil {
+		log.Printf("ID resolver warning : add_kodik_ids : поиск kodik по shikimori_id %s вернул ошибку: %v", mapping.ShikimoriID, err)
+		return
+	}
+	mapping.KodikID = results[0].ID
+	for _, result := range results {
+		if result.KinopoiskID != "" {
+			mapping.KinopoiskID = result.KinopoiskID
+			break
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package parsers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/Quavke/AnimeParsersGo/models"
+)
+
+const test_animego_page = `<html><body>
+<div class="anime-title"><h1>Наруто</h1></div>
+<div class="anime-synonyms"><ul><li>Naruto</li><li>ナルト</li></ul></div>
+<div class="anime-info"><dl>
+<dt>Тип</dt><dd>ТВ Сериал</dd>
+<dt>Эпизоды</dt><dd>220</dd>
+<dt>Статус</dt><dd>Вышел</dd>
+<dt>Сезон</dt><dd>Осень 2002</dd>
+</dl></div>
+<div class="description">Описание</div>
+</body></html>`
+
+const test_shikimori_search = `
+<div class="b-db_entry-variant-list_item" data-type="anime" data-url="https://shikimori.one/animes/1735-naruto-shippuuden" data-id="1735">
+<div class="info"><div class="name"><a title="Naruto: Shippuuden">Наруто: Ураганные хроники</a></div>
+<div class="line"><div class="key">Тип:</div><div class="value"><div class="b-tag">TV Сериал</div><div class="b-tag">2007 год</div><div class="b-anime_status_tag" data-text="вышло"></div></div></div></div>
+</div>
+<div class="b-db_entry-variant-list_item" data-type="anime" data-url="https://shikimori.one/animes/20-naruto" data-id="20">
+<div class="info"><div class="name"><a title="Naruto">Наруто</a></div>
+<div class="line"><div class="key">Тип:</div><div class="value"><div class="b-tag">TV Сериал</div><div class="b-tag">2002 год</div><div class="b-anime_status_tag" data-text="вышло"></div></div></div></div>
+</div>`
+
+const test_shikimori_page = `<html><body>
+<header class="head"><h1>Наруто / Naruto</h1></header>
+<div class="c-info-left"><div class="block">
+<div class="line"><div class="key">Тип:</div><div class="value">TV Сериал</div></div>
+<div class="line"><div class="key">Эпизоды:</div><div class="value">220</div></div>
+<div class="line"><div class="key">Статус:</div><div class="value"><span data-text="вышло">вышло</span> <span>с 3 окт. 2002 г. по 8 февр. 2007 г.</span></div></div>
+</div></div>
+</body></html>`
+
+// Страница Steins;Gate: на animego (test_animego_search) для него находится только Naruto
+const test_shikimori_steins_gate = `<html><body>
+<header class="head"><h1>Врата Штейна / Steins;Gate</h1></header>
+<div class="c-info-left"><div class="block">
+<div class="line"><div class="key">Тип:</div><div class="value">TV Сериал</div></div>
+<div class="line"><div class="key">Эпизоды:</div><div class="value">24</div></div>
+<div class="line"><div class="key">Статус:</div><div class="value"><span data-text="вышло">вышло</span> <span>с 6 апр. 2011 г. по 14 сент. 2011 г.</span></div></div>
+</div></div>
+</body></html>`
+
+const test_animego_search = `<div class="result-search-anime">
+<div class="result-search-item"><h5><a href="/anime/naruto-uraganny-hroniki-103">Наруто: Ураганные хроники</a></h5><span class="anime-year">2007</span><div class="text-truncate">Naruto: Shippuuden</div><a href="/anime/type/tv">ТВ Сериал</a></div>
+<div class="result-search-item"><h5><a href="/anime/naruto-102">Наруто</a></h5><span class="anime-year">2002</span><div class="text-truncate">Naruto</div><a href="/anime/type/tv">ТВ Сериал</a></div>
+</div>`
+
+// Сайты animego и shikimori для Naruto (animego_id 102, shikimori_id 20).
+// Ссылка animego без названия перенаправляется на страницу с названием, как на сайте
+func test_animego_shikimori(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Host + r.URL.Path {
+	case "animego.me/anime/102":
+		http.Redirect(w, r, "/anime/naruto-102", http.StatusMovedPermanently)
+	case "animego.me/anime/naruto-102":
+		if r.URL.Query().Get("type") == "episodeSchedule" {
+			write_test_json(w, map[string]string{"status": "success", "content": ""})
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, test_animego_page)
+	case "animego.me/anime/102/player":
+		write_test_json(w, map[string]string{"status": "success", "content": `<div id="video-dubbing"></div><div id="video-players"></div>`})
+	case "animego.me/search/all":
+		write_test_json(w, map[string]string{"status": "success", "content": test_animego_search})
+	case "shikimori.one/animes/20":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, test_shikimori_page)
+	case "shikimori.one/animes/9253":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, test_shikimori_steins_gate)
+	case "shikimori.one/animes/autocomplete/v2":
+		write_test_json(w, map[string]string{"content": test_shikimori_search})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestAnimegoIDFromLink(test *testing.T) {
+	for link, want := range map[string]string{
+		"https://animego.me/anime/naruto-102":                 "102",
+		"https://animego.me/anime/naruto-shippuuden-103?a=1":  "103",
+		"https://animego.me/anime/102":                        "102",
+		"https://animego.me/anime/102/player":                 "102",
+		"https://animego.me/anime/type/tv":                    "",
+		"https://animego.me/anime/season-2-without-id-at-end": "",
+	} {
+		if got := AnimegoIDFromLink(link); got != want {
+			test.Errorf("AnimegoIDFromLink(%q) = %q, want %q", link, got, want)
+		}
+	}
+}
+
+func TestIDResolverAnimego(test *testing.T) {
+	use_test_sites(test, test_animego_shikimori)
+	_, kodik := new_test_kodik(test)
+	store := NewMemoryIDStore()
+	resolver := NewIDResolver(NewAniboomParser("animego.me"), NewShikimoriParser("shikimori.one"), kodik, store)
+
+	mapping, err := resolver.Resolve(models.SourceAniboom, "102")
+	if err != nil {
+		test.Fatalf("Resolve вернул ошибку: %v", err)
+	}
+	if mapping.AnimegoID != "102" || mapping.ShikimoriID != "20" || mapping.MALID != "20" || mapping.Confidence < 0.9 {
+		test.Errorf("Resolve = %+v", mapping)
+	}
+	if mapping.KinopoiskID != "420337" || mapping.KodikID != "serial-1" {
+		test.Errorf("Resolve без id kodik: %+v", mapping)
+	}
+	if stored, exists, _ := store.Get(models.SourceShikimori, "20"); !exists || stored != mapping {
+		test.Errorf("соответствие не сохранено по shikimori_id: %+v", stored)
+	}
+}
+
+func TestIDResolverKinopoisk(test *testing.T) {
+	use_test_sites(test, test_animego_shikimori)
+	kodik_server, kodik := new_test_kodik(test)
+	resolver := NewIDResolver(NewAniboomParser("animego.me"), NewShikimoriParser("shikimori.one"), kodik, nil)
+
+	mapping, err := resolver.Resolve(SourceKinopoisk, "420337")
+	if err != nil {
+		test.Fatalf("Resolve вернул ошибку: %v", err)
+	}
+	if mapping.ShikimoriID != "20" || mapping.AnimegoID != "102" || mapping.KinopoiskID != "420337" || mapping.KodikID != "serial-1" ||
+		mapping.Method != "kodik ids, animego search" {
+		test.Errorf("Resolve = %+v", mapping)
+	}
+
+	// Соответствие сохранено под всеми id: повторные запросы не обращаются к сайтам
+	kodik_server.calls()
+	for source, id := range map[string]string{models.SourceKodik: "serial-1", models.SourceAniboom: "102", SourceMAL: "20"} {
+		if cached, err := resolver.Resolve(source, id); err != nil || cached != mapping {
+			test.Errorf("Resolve(%s, %s) = %+v, %v", source, id, cached, err)
+		}
+	}
+	if calls := kodik_server.calls(); len(calls) != 0 {
+		test.Errorf("запросы к kodik для сохраненного соответствия: %v", calls)
+	}
+
+	if _, err := resolver.Resolve(SourceKinopoisk, "1"); err == nil {
+		test.Error("Resolve для неизвестного id kinopoisk должен вернуть ошибку")
+	}
+}
+
+func TestIDResolverKinopoiskWeakAnimegoMatch(test *testing.T) {
+	use_test_sites(test, test_animego_shikimori)
+	_, kodik := new_test_kodik(test)
+	resolver := NewIDResolver(NewAniboomParser("animego.me"), NewShikimoriParser("shikimori.one"), kodik, nil)
+
+	// На animego находится только Naruto с уверенностью ниже MinConfidence: id из kodik сохраняются без AnimegoID
+	mapping, err := resolver.Resolve(SourceKinopoisk, "580245")
+	if err != nil {
+		test.Fatalf("Resolve вернул ошибку: %v", err)
+	}
+	if mapping.ShikimoriID != "9253" || mapping.KinopoiskID != "580245" || mapping.KodikID != "serial-4" || mapping.AnimegoID != "" ||
+		mapping.Confidence != 1 || mapping.Method != "kodik ids" {
+		test.Errorf("Resolve = %+v", mapping)
+	}
+}
+
+func TestFileIDStoreConcurrentPut(test *testing.T) {
+	path := filepath.Join(test.TempDir(), "data", "id_mappings.json")
+	store, err := NewFileIDStore(path)
+	if err != nil {
+		test.Fatalf("NewFileIDStore вернул ошибку: %v", err)
+	}
+
+	const mappings = 50
+	wg := sync.WaitGroup{}
+	for i := 1; i <= mappings; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := strconv.Itoa(i)
+			if err := store.Put(&IDMapping{AnimegoID: id, ShikimoriID: "sh" + id}); err != nil {
+				test.Errorf("Put вернул ошибку: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Последний записанный файл содержит все соответствия, временных файлов не осталось
+	loaded, err := NewFileIDStore(path)
+	if err != nil {
+		test.Fatalf("NewFileIDStore вернул ошибку: %v", err)
+	}
+	for i := 1; i <= mappings; i++ {
+		if mapping, exists, _ := loaded.Get(models.SourceShikimori, "sh"+strconv.Itoa(i)); !exists || mapping.AnimegoID != strconv.Itoa(i) {
+			test.Errorf("в файле нет соответствия %d: %+v", i, mapping)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		test.Fatal(err)
+	}
+	if len(entries) != 1 {
+		test.Errorf("файлы в папке хранилища: %v", entries)
+	}
+}
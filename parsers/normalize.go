@@ -0,0 +1,180 @@
+package parsers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// id аниме в ссылке shikimori (прим: https://shikimori.one/animes/z20-naruto > 20)
+var shikimori_id_re = regexp.MustCompile(`/animes/[a-z]*(\d+)`)
+
+// Возвращает id аниме на shikimori из ссылки или "" (прим: https://shikimori.one/animes/z20-naruto > 20)
+func ShikimoriIDFromLink(link string) string {
+	match := shikimori_id_re.FindStringSubmatch(link)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// id аниме в ссылке animego (прим: https://animego.me/anime/naruto-102 > 102, https://animego.me/anime/102 > 102)
+var animego_id_re = regexp.MustCompile(`/anime/(?:[^/?#]*-)?(\d+)(?:[/?#]|$)`)
+
+// Возвращает id аниме на animego из ссылки на его страницу или "" (прим: https://animego.me/anime/naruto-102 > 102)
+func AnimegoIDFromLink(link string) string {
+	match := animego_id_re.FindStringSubmatch(link)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// Разделяет строку студий (прим: "MAPPA, Studio VOLN") на срез
+func split_studios(raw string) []string {
+	result := make([]string, 0)
+	for _, studio := range strings.Split(raw, ",") {
+		if studio = strings.TrimSpace(studio); studio != "" {
+			result = append(result, studio)
+		}
+	}
+	return result
+}
+
+// Заполняет Episodes и EpisodesAired из строки вида "5 / 12", "12" или "? / 12"
+func set_episodes(anime *models.Anime, raw string) {
+	numbers := t.ParseNumbers(raw)
+	switch {
+	case len(numbers) >= 2:
+		anime.EpisodesAired = numbers[0]
+		anime.Episodes = numbers[len(numbers)-1]
+	case len(numbers) == 1 && strings.Contains(raw, "/"):
+		anime.Episodes = numbers[0]
+	case len(numbers) == 1:
+		anime.Episodes = numbers[0]
+		anime.EpisodesAired = numbers[0]
+	}
+}
+
+// Преобразует FastSearchResult в models.Anime
+func (r *FastSearchResult) Normalize() *models.Anime {
+	typed := r.Typed()
+	anime := &models.Anime{
+		Source:        models.SourceAniboom,
+		Link:          r.Link,
+		Title:         r.Title,
+		OriginalTitle: strings.TrimSpace(r.OtherTitle),
+		OtherTitles:   make([]string, 0),
+		AnimegoID:     r.AnimegoID,
+		Kind:          typed.Kind,
+		Year:          typed.Year,
+		Studios:       make([]string, 0),
+		Genres:        make([]models.Genre, 0),
+		UnknownGenres: make([]string, 0),
+	}
+	if anime.OriginalTitle != "" {
+		anime.OtherTitles = append(anime.OtherTitles, anime.OriginalTitle)
+	}
+	return anime
+}
+
+// Преобразует ABSearchResult в models.Anime.
+// Оригинальным названием считается первое из OtherTitle (на animego это обычно английское или ромадзи название)
+func (r *ABSearchResult) Normalize() *models.Anime {
+	typed := r.Typed()
+	anime := &models.Anime{
+		Source:        models.SourceAniboom,
+		Link:          r.Link,
+		Title:         r.Title,
+		OtherTitles:   append(make([]string, 0, len(r.OtherTitle)), r.OtherTitle...),
+		AnimegoID:     r.AnimegoID,
+		Kind:          typed.Kind,
+		Status:        typed.Status,
+		Year:          typed.Year,
+		Episodes:      typed.EpisodesTotal,
+		EpisodesAired: typed.EpisodesAired,
+		Studios:       make([]string, 0),
+		Description:   r.Description,
+		Poster:        r.PosterURL,
+	}
+	if len(r.OtherTitle) > 0 {
+		anime.OriginalTitle = r.OtherTitle[0]
+	}
+	anime.Genres, anime.UnknownGenres = models.ParseGenres(r.Genres)
+	if typed.OtherInfo != nil {
+		anime.Rating = typed.OtherInfo.MPAARating
+		anime.AiredFrom = typed.OtherInfo.ReleaseStart
+		anime.AiredTo = typed.OtherInfo.ReleaseEnd
+		anime.EpisodeDuration = typed.OtherInfo.Duration
+		anime.Studios = split_studios(r.OtherInfo.Studio)
+	}
+	return anime
+}
+
+// Преобразует SHSearchResult в models.Anime
+func (r *SHSearchResult) Normalize() *models.Anime {
+	anime := &models.Anime{
+		Source:        models.SourceShikimori,
+		Link:          r.Link,
+		Title:         strings.TrimSpace(r.Title),
+		OriginalTitle: strings.TrimSpace(r.OriginalTitle),
+		OtherTitles:   make([]string, 0),
+		ShikimoriID:   r.ShikimoriID,
+		Kind:          models.ParseAnimeKind(r.Type),
+		Status:        models.ParseAnimeStatus(r.Status),
+		Year:          t.ParseYear(r.Year),
+		Studios:       split_studios(r.Studio),
+		Poster:        r.Poster,
+	}
+	anime.Genres, anime.UnknownGenres = models.ParseGenres(r.Genres)
+	return anime
+}
+
+// Преобразует SHAnimeInfoResult в models.Anime.
+//
+// :shikimori_link: ссылка, по которой был получен результат (SHAnimeInfoResult не хранит ссылку и id)
+func (r *SHAnimeInfoResult) Normalize(shikimori_link string) *models.Anime {
+	anime := &models.Anime{
+		Source:        models.SourceShikimori,
+		Link:          shikimori_link,
+		Title:         strings.TrimSpace(r.Title),
+		OriginalTitle: strings.TrimSpace(r.OriginalTitle),
+		OtherTitles:   make([]string, 0),
+		ShikimoriID:   ShikimoriIDFromLink(shikimori_link),
+		Kind:          models.ParseAnimeKind(r.Type),
+		Status:        models.ParseAnimeStatus(r.Status),
+		Rating:        models.ParseMPAARating(r.Rating),
+		Studios:       split_studios(r.Studio),
+		Description:   strings.TrimSpace(r.Description),
+		Poster:        r.Picture,
+	}
+	if r.LicensedInRU != "" {
+		anime.OtherTitles = append(anime.OtherTitles, strings.TrimSpace(r.LicensedInRU))
+	}
+	set_episodes(anime, r.Episodes)
+	anime.EpisodeDuration, _ = t.ParseRussianDuration(r.EpisodeDuration)
+	anime.AiredFrom, anime.AiredTo, _ = t.ParseRussianDateRange(r.Dates)
+	anime.Year = t.ParseYear(r.Dates)
+	anime.Score, _ = strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(r.Score), ",", "."), 64)
+	anime.Genres, anime.UnknownGenres = models.ParseGenres(append(append(make([]string, 0), r.Genres...), r.Themes...))
+	return anime
+}
+
+// Трейлер аниме в едином виде (площадка, id, ссылки для просмотра, встраивания и превью), см. tools.ResolveVideoLink.
+// Если трейлера нет, возвращает ошибку errs.NoResults
+func (r *ABSearchResult) TrailerVideo() (*t.VideoLink, error) {
+	if r.Trailer == "" {
+		return nil, errs.NewNoResultsError(fmt.Sprintf("Aniboom parser error : TrailerVideo : у аниме %s нет трейлера", r.AnimegoID))
+	}
+	return t.ResolveVideoLink(r.Trailer)
+}
+
+// Ролик в едином виде (площадка, id, ссылки для просмотра, встраивания и превью), см. tools.ResolveVideoLink
+func (v *SHVideos) Video() (*t.VideoLink, error) {
+	return t.ResolveVideoLink(v.Link)
+}
@@ -19,22 +19,68 @@ import (
 	t "github.com/Quavke/AnimeParsersGo/tools"
 )
 
+// Количество аниме, для которых Search параллельно загружает данные, по умолчанию
+const defaultSearchWorkers = 4
+
+// Зеркала animego по умолчанию в порядке приоритета
+var aniboomMirrors = []string{"animego.me", "animego.org"}
+
 type AniboomParser struct {
-	dmn     string
-	context context.Context
+	mirrors        *t.Mirrors
+	context        context.Context
+	search_workers int
+	// Парсер kodik, которому передаются плееры kodik из плеера animego (см. ResolvePlayer)
+	kodik *KodikParser
 }
 
+// :mirror: домен animego (пустая строка - домен по умолчанию). Зеркала по умолчанию используются как запасные
 func NewAniboomParser(mirror string) *AniboomParser {
-	var dmn string
-	if mirror != "" {
-		dmn = mirror
-	} else {
-		dmn = "animego.me"
-	}
+	return NewAniboomParserWithMirrors(mirror)
+}
+
+// Создает парсер с упорядоченным списком зеркал animego (прим: "animego.me", "animego.org").
+//
+// Запросы отправляются на активное зеркало, при недоступности или блокировке - на следующие (см. tools.Mirrors).
+// Зеркала по умолчанию добавляются в конец списка как запасные
+func NewAniboomParserWithMirrors(mirrors ...string) *AniboomParser {
 	return &AniboomParser{
-		dmn:     dmn,
-		context: context.Background(),
+		mirrors:        t.NewMirrors(mirrors, aniboomMirrors...),
+		context:        context.Background(),
+		search_workers: defaultSearchWorkers,
+		kodik:          NewKodikParser(""),
+	}
+}
+
+// Активное зеркало animego
+func (ab *AniboomParser) domain() string {
+	return ab.mirrors.Active()
+}
+
+// Возвращает зеркала парсера (для проверки доступности и ручного переключения)
+func (ab *AniboomParser) Mirrors() *t.Mirrors {
+	return ab.mirrors
+}
+
+// Проверяет доступность зеркал и делает активным первое доступное (см. tools.Mirrors.Probe)
+func (ab *AniboomParser) ProbeMirrors() (map[string]error, error) {
+	return ab.mirrors.Probe(ab.context)
+}
+
+// Задает количество аниме, для которых Search и SearchIter параллельно загружают данные. Значения меньше 1 игнорируются.
+// Как и SetContext, вызывается до использования парсера: вызов одновременно с поиском не синхронизирован
+func (ab *AniboomParser) SetSearchWorkers(workers int) {
+	if workers < 1 {
+		return
+	}
+	ab.search_workers = workers
+}
+
+// Задает парсер kodik, которому ResolvePlayer передает плееры kodik (прим: с токеном API или другим клиентом)
+func (ab *AniboomParser) SetKodikParser(kp *KodikParser) {
+	if kp == nil {
+		return
 	}
+	ab.kodik = kp
 }
 
 type FastSearchResult struct {
@@ -67,8 +113,18 @@ type EpisodeInfo struct {
 }
 
 type Translation struct {
-	Name          string `json:"name"`
+	Name string `json:"name"`
+	// Озвучка или субтитры (TranslationDub или TranslationSubtitles)
+	Kind string `json:"kind"`
+	// id перевода для плеера aniboom. Пустой, если перевод доступен только у других провайдеров (см. Players)
 	TranslationID string `json:"translation_id"`
+	// Провайдер, через который воспроизводится перевод: "aniboom" - TranslationID можно передать в GetMPDPlaylist,
+	// иначе - название первого провайдера в нижнем регистре (прим: kodik), перевод воспроизводится через ResolvePlayer
+	Provider string `json:"provider"`
+	// id озвучки на animego (атрибут data-dubbing)
+	Dubbing string `json:"dubbing"`
+	// Все плееры, в которых доступен перевод (aniboom, kodik и т.д.)
+	Players []*ABPlayer `json:"players"`
 }
 
 type OtherAnimeInfo struct {
@@ -107,10 +163,10 @@ type ABSearchResult struct {
 
 :title: Название аниме
 
-Возвращает срез ссылок на FastSearchResult
+Возвращает срез ссылок на FastSearchResult, отсортированный по сходству названий с запросом
 */
 func (ab *AniboomParser) FastSearch(title string) ([]*FastSearchResult, error) {
-	domain := fmt.Sprintf("https://%s/", ab.dmn)
+	domain := fmt.Sprintf("https://%s/", ab.domain())
 	URL := fmt.Sprintf("%ssearch/all", domain)
 
 	params := models.Params{
@@ -123,11 +179,11 @@ func (ab *AniboomParser) FastSearch(title string) ([]*FastSearchResult, error) {
 		"Referer":          domain,
 	}
 
-	response, err := t.RequestWithContext(ab.context, "GET", URL, params, headers, true, &ABJsonResponse{})
+	response, err := ab.mirrors.Request(ab.context, "GET", URL, params, headers, true, &ABJsonResponse{})
 	if err != nil {
 		error_message := fmt.Sprintf("Aniboom parser error : FastSearch : RequestWithContext вернул ошибку: %v", err)
 		log.Println(error_message)
-		return nil, errs.NewServiceError(error_message)
+		return nil, t.WrapRequestError(err, error_message)
 	}
 
 	json_response, ok := response.Json.(*ABJsonResponse)
@@ -179,16 +235,11 @@ func (ab *AniboomParser) FastSearch(title string) ([]*FastSearchResult, error) {
 				rawLink = href
 			}
 		}
-		c_data.Link = fmt.Sprintf("https://%s", ab.dmn) + rawLink
-		fullLink := c_data.Link
-		lastDashIndex := strings.LastIndex(fullLink, "-")
-		if lastDashIndex != -1 && lastDashIndex < len(fullLink)-1 {
-			c_data.AnimegoID = fullLink[lastDashIndex+1:]
-		} else {
-			c_data.AnimegoID = ""
-		}
+		c_data.Link = fmt.Sprintf("https://%s", ab.domain()) + rawLink
+		c_data.AnimegoID = AnimegoIDFromLink(c_data.Link)
 		res = append(res, &c_data)
 	})
+	rank_fast_search(title, res)
 	return res, nil
 }
 
@@ -200,7 +251,7 @@ func (ab *AniboomParser) FastSearch(title string) ([]*FastSearchResult, error) {
 func (ab *AniboomParser) EpisodesInfo(link string) ([]*EpisodeInfo, error) {
 	episodes_info := make([]*EpisodeInfo, 0)
 
-	referer := fmt.Sprintf("https://%s/search/all?q=anime", ab.dmn)
+	referer := fmt.Sprintf("https://%s/search/all?q=anime", ab.domain())
 
 	params := models.Params{
 		"type":          "episodeSchedule",
@@ -213,11 +264,11 @@ func (ab *AniboomParser) EpisodesInfo(link string) ([]*EpisodeInfo, error) {
 		"X-Requested-With": "XMLHttpRequest",
 	}
 
-	response, err := t.RequestWithContext(ab.context, "GET", link, params, headers, true, &ABJsonResponse{})
+	response, err := ab.mirrors.Request(ab.context, "GET", link, params, headers, true, &ABJsonResponse{})
 	if err != nil {
 		error_message := fmt.Sprintf("Aniboom parser error : FastSearch : RequestWithContext вернул ошибку: %v", err)
 		log.Println(error_message)
-		return nil, errs.NewServiceError(error_message)
+		return nil, t.WrapRequestError(err, error_message)
 	}
 
 	json_response, ok := response.Json.(*ABJsonResponse)
@@ -302,31 +353,87 @@ func (ab *AniboomParser) EpisodesInfo(link string) ([]*EpisodeInfo, error) {
 	return episodes_info, nil
 }
 
+// Ошибка загрузки данных одного аниме в Search
+type ABSearchError struct {
+	// Позиция аниме в результатах FastSearch
+	Index int    `json:"index"`
+	Title string `json:"title"`
+	Link  string `json:"link"`
+	Err   error  `json:"-"`
+}
+
+func (e *ABSearchError) Error() string {
+	return fmt.Sprintf("Aniboom parser error : Search : AnimeInfo не смог найти данные для %s по ссылке %s. Ошибка: %v", e.Title, e.Link, e.Err)
+}
+
+func (e *ABSearchError) Unwrap() error {
+	return e.Err
+}
+
 // Расширенный поиск через animego.me. Собирает дополнительные данные об аниме.
 //
 // :title: Название
 //
+// Данные загружаются параллельно (см. SetSearchWorkers), порядок результатов совпадает с FastSearch.
+// Если данные некоторых аниме загрузить не удалось, возвращает остальные результаты вместе с ошибкой,
+// объединяющей *ABSearchError по каждому такому аниме (errors.Join). Список ошибок можно получить через SearchWithErrors
+//
 // Возвращает срез ссылок на ABSearchResult
 func (ab *AniboomParser) Search(title string) ([]*ABSearchResult, error) {
+	res, failed, err := ab.SearchWithErrors(title)
+	if err != nil {
+		return nil, err
+	}
+	if len(failed) == 0 {
+		return res, nil
+	}
+	failed_errors := make([]error, 0, len(failed))
+	for _, item_error := range failed {
+		failed_errors = append(failed_errors, item_error)
+	}
+	return res, errors.Join(failed_errors...)
+}
+
+// То же, что Search, но ошибки загрузки отдельных аниме возвращаются списком (в порядке FastSearch).
+//
+// :title: Название
+//
+// Ошибка (третье значение) возвращается, только если не удался сам FastSearch
+func (ab *AniboomParser) SearchWithErrors(title string) ([]*ABSearchResult, []*ABSearchError, error) {
 	elements, err := ab.FastSearch(title)
 	if err != nil {
 		error_message := fmt.Sprintf("Aniboom parser error : search : FastSearch не смог найти данные для title %s. Ошибка: %v", title, err)
 		log.Println(error_message)
-		return nil, err
+		return nil, nil, err
 	}
-	res := make([]*ABSearchResult, 0)
-	for _, anime := range elements {
-		anime_indirect := *anime
-		c_data, err := ab.AnimeInfo(anime_indirect.Link)
-		if err != nil {
-			error_message := fmt.Sprintf("Aniboom parser error : search : AnimeInfo не смог найти данные для %s по ссылке %s. Ошибка: %v", anime.Title, anime.Link, err)
-			log.Println(error_message)
+
+	results := make([]*ABSearchResult, len(elements))
+	item_errors := make([]*ABSearchError, len(elements))
+
+	done := make(chan struct{})
+	defer close(done)
+	for item := range enrich_each(elements, ab.search_workers, func(anime *FastSearchResult) (*ABSearchResult, error) {
+		return ab.AnimeInfo(anime.Link)
+	}, done) {
+		if item.err != nil {
+			anime := elements[item.index]
+			item_errors[item.index] = &ABSearchError{Index: item.index, Title: anime.Title, Link: anime.Link, Err: item.err}
+			log.Println(item_errors[item.index].Error())
 			continue
 		}
-		res = append(res, c_data)
+		results[item.index] = item.result
 	}
 
-	return res, nil
+	res := make([]*ABSearchResult, 0, len(elements))
+	failed := make([]*ABSearchError, 0)
+	for index := range elements {
+		if item_errors[index] != nil {
+			failed = append(failed, item_errors[index])
+			continue
+		}
+		res = append(res, results[index])
+	}
+	return res, failed, nil
 }
 
 /*
@@ -339,17 +446,17 @@ func (ab *AniboomParser) Search(title string) ([]*ABSearchResult, error) {
 func (ab *AniboomParser) AnimeInfo(link string) (*ABSearchResult, error) {
 	var c_data ABSearchResult
 
-	URL := fmt.Sprintf("https://%s/search/all?q=anime", ab.dmn)
+	URL := fmt.Sprintf("https://%s/search/all?q=anime", ab.domain())
 
 	headers := models.Headers{
 		"Referer": URL,
 	}
 
-	response, err := t.RequestWithContext(ab.context, "GET", link, nil, headers, false, nil)
+	response, err := ab.mirrors.Request(ab.context, "GET", link, nil, headers, false, nil)
 	if err != nil {
 		error_message := fmt.Sprintf("Aniboom parser error : FastSearch : RequestWithContext вернул ошибку: %v", err)
 		log.Println(error_message)
-		return nil, errs.NewServiceError(error_message)
+		return nil, t.WrapRequestError(err, error_message)
 	}
 
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(response.Data)))
@@ -358,15 +465,21 @@ func (ab *AniboomParser) AnimeInfo(link string) (*ABSearchResult, error) {
 		log.Println(error_message)
 		return nil, errs.NewServiceError(error_message)
 	}
-	c_data.Link = link
-	fullLink := c_data.Link
-	lastDashIndex := strings.LastIndex(fullLink, "-")
-	if lastDashIndex != -1 && lastDashIndex < len(fullLink)-1 {
-		c_data.AnimegoID = fullLink[lastDashIndex+1:]
-	} else {
-		c_data.AnimegoID = ""
+	c_data.Link = ab.mirrors.Rewrite(link)
+	c_data.AnimegoID = AnimegoIDFromLink(c_data.Link)
+	if response.Response != nil && response.Response.Request != nil && response.Response.Request.URL != nil {
+		// Ссылка без названия (прим: https://animego.me/anime/102) перенаправляется на страницу с названием
+		if final_link := ab.mirrors.Rewrite(response.Response.Request.URL.String()); AnimegoIDFromLink(final_link) != "" {
+			c_data.Link = final_link
+			c_data.AnimegoID = AnimegoIDFromLink(final_link)
+		}
 	}
-	dmn := fmt.Sprintf("https://%s", ab.dmn)
+	if c_data.AnimegoID == "" {
+		error_message := fmt.Sprintf("Aniboom parser error : AnimeInfo : не удалось найти id аниме в ссылке %s", link)
+		log.Println(error_message)
+		return nil, errs.NewPostArgumentsError(error_message)
+	}
+	dmn := fmt.Sprintf("https://%s", ab.domain())
 	c_data.Title = strings.TrimSpace(doc.Find("div.anime-title h1").Text())
 
 	other_titles := make([]string, 0)
@@ -503,7 +616,7 @@ func (ab *AniboomParser) AnimeInfo(link string) (*ABSearchResult, error) {
 			c_data.Trailer = strings.TrimSpace(href)
 		}
 	}
-	result, err := ab.EpisodesInfo(link)
+	result, err := ab.EpisodesInfo(c_data.Link)
 	if err != nil {
 		error_message := fmt.Sprintf("Aniboom parser error : AnimeInfo : EpisodesInfo вернул неожиданную ошибку: %v", err)
 		log.Println(error_message)
@@ -514,9 +627,14 @@ func (ab *AniboomParser) AnimeInfo(link string) (*ABSearchResult, error) {
 
 	translations_info, err := ab.GetTranslationsInfo(c_data.AnimegoID)
 	var contentBlocked *errs.ContentBlocked
+	var ageRestricted *errs.AgeRestricted
 	if errors.As(err, &contentBlocked) {
 		log.Println("Aniboom parser warning : AnimeInfo : GetTranslationsInfo вернул ошибку ContentBlocked")
 		c_data.Translations = []*Translation{}
+	} else if errors.As(err, &ageRestricted) {
+		// Данные страницы доступны, ограничен только плеер
+		log.Println("Aniboom parser warning : AnimeInfo : GetTranslationsInfo вернул ошибку AgeRestricted")
+		c_data.Translations = []*Translation{}
 	} else if err != nil {
 		error_message := fmt.Sprintf("Aniboom parser error : AnimeInfo : GetTranslationsInfo вернул неожиданную ошибку: %v", err)
 		log.Println(error_message)
@@ -529,52 +647,79 @@ func (ab *AniboomParser) AnimeInfo(link string) (*ABSearchResult, error) {
 	return &c_data, nil
 }
 
-// Получает информацию о переводах и их id для плеера aniboom
+// Загружает страницу плеера animego для указанного аниме.
 //
 // :animego_id: id аниме на animego.me
 //
-// Возвращает срез ссылок на Translation:
-func (ab *AniboomParser) GetTranslationsInfo(animego_id string) ([]*Translation, error) {
+// Возвращает html страницы плеера в виде goquery документа. Используется в GetTranslationsInfo, get_embed_link и ABSession.
+// Если плеер закрыт возрастным ограничением, возвращает ошибку errs.AgeRestricted (нужна сессия с cookies входа, см. SetCookieSession)
+func (ab *AniboomParser) get_player_doc(animego_id string) (*goquery.Document, error) {
 	params := models.Params{
 		"_allow": "true",
 	}
 
-	referer := fmt.Sprintf("https://%s/search/all?q=anime", ab.dmn)
+	referer := fmt.Sprintf("https://%s/search/all?q=anime", ab.domain())
 	headers := models.Headers{
 		"X-Requested-With": "XMLHttpRequest",
 		"Referer":          referer,
 	}
 
-	URL := fmt.Sprintf("https://%s/anime/%s/player?", ab.dmn, animego_id)
+	URL := fmt.Sprintf("https://%s/anime/%s/player", ab.domain(), animego_id)
 
-	response, err := t.RequestWithContext(ab.context, "GET", URL, params, headers, true, &ABJsonResponse{})
+	response, err := ab.mirrors.Request(ab.context, "GET", URL, params, headers, true, &ABJsonResponse{})
 	if err != nil {
-		error_message := fmt.Sprintf("Aniboom parser error : FastSearch : RequestWithContext вернул ошибку: %v", err)
+		error_message := fmt.Sprintf("Aniboom parser error : get_player_doc : RequestWithContext вернул ошибку: %v", err)
 		log.Println(error_message)
-		return nil, errs.NewServiceError(error_message)
+		return nil, t.WrapRequestError(err, error_message)
 	}
 
 	json_response, ok := response.Json.(*ABJsonResponse)
 	if !ok {
-		error_message := "Aniboom parser error : FastSearch : не смог привести result.Json к *ABJsonResponse"
+		error_message := "Aniboom parser error : get_player_doc : не смог привести result.Json к *ABJsonResponse"
 		log.Println(error_message)
 		return nil, errs.NewServiceError(error_message)
 	}
 
 	if json_response.Status != "success" {
+		if is_age_gate_text(json_response.Message) {
+			return nil, age_restricted_error("Aniboom", "get_player_doc", URL, ab.authenticated())
+		}
 		return nil, errs.NewServiceError(fmt.Sprintf(
-			"Aniboom parser error : FastSearch : сервер вернул статус отличный от success: %q, сообщение: %q для animegoID: %q",
+			"Aniboom parser error : get_player_doc : сервер вернул статус отличный от success: %q, сообщение: %q для animegoID: %q",
 			json_response.Status, json_response.Message, animego_id,
 		))
 	}
 	htmlContent := html.UnescapeString(json_response.Content)
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
 	if err != nil {
-		error_message := fmt.Sprintf("Aniboom parser error : GetTranslationsInfo : goquery не смог преобразовать ответ в документ. Ошибка: %v", err)
+		error_message := fmt.Sprintf("Aniboom parser error : get_player_doc : goquery не смог преобразовать ответ в документ. Ошибка: %v", err)
 		log.Println(error_message)
 		return nil, errs.NewServiceError(error_message)
 	}
+	// Плеер аниме 18+ без входа заблокирован с причиной о возрастном ограничении
+	if blocked := doc.Find("div.player-blocked"); blocked.Length() > 0 && is_age_gate_text(blocked.Text()) {
+		return nil, age_restricted_error("Aniboom", "get_player_doc", URL, ab.authenticated())
+	}
+	return doc, nil
+}
+
+// Получает информацию о переводах, их id для плеера aniboom и плеерах остальных провайдеров
+//
+// :animego_id: id аниме на animego.me
+//
+// Возвращает срез ссылок на Translation. Переводы, недоступные в aniboom, возвращаются с пустым TranslationID
+// и Provider другого провайдера (прим: kodik). Их нельзя передавать в GetMPDPlaylist, они воспроизводятся через ResolvePlayer:
+func (ab *AniboomParser) GetTranslationsInfo(animego_id string) ([]*Translation, error) {
+	doc, err := ab.get_player_doc(animego_id)
+	if err != nil {
+		log.Printf("Aniboom parser error : GetTranslationsInfo : get_player_doc вернул ошибку: %v", err)
+		return nil, err
+	}
+	return parse_translations(doc, animego_id)
+}
 
+// Разбирает переводы из страницы плеера (можно получить из get_player_doc)
+func parse_translations(doc *goquery.Document, animego_id string) ([]*Translation, error) {
 	if doc.Find("div.player-blocked").Length() > 0 {
 		reason_elem := doc.Find("div.h5")
 		var reason string
@@ -585,68 +730,27 @@ func (ab *AniboomParser) GetTranslationsInfo(animego_id string) ([]*Translation,
 		log.Println(error_message)
 		return nil, errs.NewContentBlockedError(error_message)
 	}
-	translations_container := doc.Find("#video-dubbing").Find("span.video-player-toggle-item")
-	players_container := doc.Find("#video-players").Find("span.video-player-toggle-item")
-	translation := make(map[string]*Translation)
-
-	if translations_container.Length() == 0 {
+	if doc.Find("#video-dubbing").Find("span.video-player-toggle-item").Length() == 0 {
 		log.Printf("Aniboom parser warning : GetTranslationsInfo : ни одного translations контейнера не было найдено для animego_id %s", animego_id)
 	}
-	if players_container.Length() == 0 {
+	if doc.Find("#video-players").Find("span.video-player-toggle-item").Length() == 0 {
 		log.Printf("Aniboom parser warning : GetTranslationsInfo : ни одного players контейнера не было найдено для animego_id %s", animego_id)
 	}
 
-	translations_container.Each(func(i int, s *goquery.Selection) {
-		dubbing, exists := s.Attr("data-dubbing")
-		if !exists || dubbing == "" {
-			return
-		}
-
-		name := strings.TrimSpace(s.Text())
-		if name == "" {
-			return
-		}
-
-		if _, exists := translation[dubbing]; !exists {
-			translation[dubbing] = &Translation{}
-		}
-
-		translation[dubbing].Name = name
-
-	})
-
-	players_container.Each(func(i int, s *goquery.Selection) {
-		provider, exists := s.Attr("data-provider")
-		if !exists || provider != "24" {
-			return
-		}
-
-		dubbing, exists := s.Attr("data-provide-dubbing")
-		if !exists {
-			return
-		}
-
-		if _, exists := translation[dubbing]; !exists {
-			translation[dubbing] = &Translation{}
-		}
-
-		translationID, exists := s.Attr("data-player")
-		if !exists {
-			return
-		}
-
-		lastIndex := strings.LastIndex(translationID, "=")
-		if lastIndex != -1 && lastIndex < len(translationID)-1 {
-			translationID = translationID[lastIndex+1:]
-		}
-
-		translation[dubbing].TranslationID = translationID
-	})
 	result := make([]*Translation, 0)
-	for _, translation_info := range translation {
-		if len(translation_info.Name) > 0 && len(translation_info.TranslationID) > 0 {
-			result = append(result, translation_info)
+	for _, translation_info := range parse_dubbing_players(doc) {
+		translation := &Translation{
+			Name:          translation_info.Name,
+			Kind:          translation_info.Kind,
+			TranslationID: translation_info.TranslationID,
+			Dubbing:       translation_info.Dubbing,
+			Players:       translation_info.Players,
+			Provider:      "aniboom",
+		}
+		if translation.TranslationID == "" {
+			translation.Provider = translation.Players[0].key()
 		}
+		result = append(result, translation)
 	}
 
 	return result, nil
@@ -659,46 +763,16 @@ func (ab *AniboomParser) GetTranslationsInfo(animego_id string) ([]*Translation,
 // Возвращает ссылку в виде: https://aniboom.one/embed/yxVdenrqNar
 // Если ссылка не найдена, возвращает ошибку errs.NoResultsError
 func (ab *AniboomParser) get_embed_link(animego_id string) (string, error) {
-	params := models.Params{
-		"_allow": "true",
-	}
-
-	headers := models.Headers{
-		"X-Requested-With": "XMLHttpRequest",
-	}
-
-	URL := fmt.Sprintf("https://%s/anime/%s/player", ab.dmn, animego_id)
-
-	response, err := t.RequestWithContext(ab.context, "GET", URL, params, headers, true, &ABJsonResponse{})
-	if err != nil {
-		error_message := fmt.Sprintf("Aniboom parser error : FastSearch : RequestWithContext вернул ошибку: %v", err)
-		log.Println(error_message)
-		return "", errs.NewServiceError(error_message)
-	}
-
-	json_response, ok := response.Json.(*ABJsonResponse)
-	if !ok {
-		error_message := "Aniboom parser error : FastSearch : не смог привести result.Json к *ABJsonResponse"
-		log.Println(error_message)
-		return "", errs.NewServiceError(error_message)
-	}
-
-	if json_response.Status != "success" {
-		return "", errs.NewServiceError(fmt.Sprintf(
-			"Aniboom parser error : FastSearch : сервер вернул статус отличный от success: %q, сообщение: %q для animegoID: %q",
-			json_response.Status, json_response.Message, animego_id,
-		))
-	}
-
-	htmlContent := html.UnescapeString(json_response.Content)
-
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	doc, err := ab.get_player_doc(animego_id)
 	if err != nil {
-		error_message := fmt.Sprintf("Aniboom parser error : get_embed_link : goquery не смог преобразовать ответ в документ. Ошибка: %v", err)
-		log.Println(error_message)
-		return "", errs.NewServiceError(error_message)
+		log.Printf("Aniboom parser error : get_embed_link : get_player_doc вернул ошибку: %v", err)
+		return "", err
 	}
+	return parse_embed_link(doc, animego_id)
+}
 
+// Разбирает ссылку на embed aniboom из страницы плеера (можно получить из get_player_doc)
+func parse_embed_link(doc *goquery.Document, animego_id string) (string, error) {
 	items := doc.Find("div.player-blocked").First()
 
 	if items.Length() > 0 {
@@ -748,16 +822,16 @@ func (ab *AniboomParser) get_embed(embed_link, translation string, episode int)
 		params["episode"] = fmt.Sprintf("%d", episode)
 	}
 
-	referer := fmt.Sprintf("https://%s/", ab.dmn)
+	referer := fmt.Sprintf("https://%s/", ab.domain())
 	headers := models.Headers{
 		"Referer": referer,
 	}
 
-	response, err := t.RequestWithContext(ab.context, "GET", embed_link, params, headers, false, nil)
+	response, err := ab.mirrors.Request(ab.context, "GET", embed_link, params, headers, false, nil)
 	if err != nil {
 		error_message := fmt.Sprintf("Aniboom parser error : FastSearch : RequestWithContext вернул ошибку: %v", err)
 		log.Println(error_message)
-		return "", errs.NewServiceError(error_message)
+		return "", t.WrapRequestError(err, error_message)
 	}
 
 	bodyText := string(response.Data)
@@ -920,9 +994,12 @@ func (ab *AniboomParser) get_mpd_playlist(embed_link, translation string, episod
 		"Referer": referer,
 	}
 
-	response, err := t.RequestWithContext(ab.context, "GET", media_src, nil, headers, false, nil)
-
-	fmt.Println(html.UnescapeString(string(response.Data)))
+	response, err := ab.mirrors.Request(ab.context, "GET", media_src, nil, headers, false, nil)
+	if err != nil {
+		error_message := fmt.Sprintf("Aniboom parser error : get_mpd_playlist : RequestWithContext вернул ошибку: %v", err)
+		log.Println(error_message)
+		return "", t.WrapRequestError(err, error_message)
+	}
 
 	str_playlist := string(response.Data)
 	if strings.Contains(str_playlist, "<MPD") {
@@ -946,6 +1023,16 @@ func (ab *AniboomParser) get_mpd_playlist(embed_link, translation string, episod
 	return str_playlist, nil
 }
 
+// Проверяет, что передан id перевода для плеера aniboom (у переводов других провайдеров TranslationID пустой)
+func check_aniboom_translation(method, translation_id string) error {
+	if strings.TrimSpace(translation_id) != "" {
+		return nil
+	}
+	error_message := fmt.Sprintf("Aniboom parser error : %s : не указан id перевода aniboom. Переводы других провайдеров (Translation.Provider) воспроизводятся через ResolvePlayer", method)
+	log.Println(error_message)
+	return errs.NewPostArgumentsError(error_message)
+}
+
 // Возвращает mpd файл строкой (содержимое файла)
 //
 // :animego_id: id аниме на animego.me (может быть найдена из FastSearch по в поле AnimegoID для нужного аниме или из Search по тому же полю для нужного аниме) (из ссылки на страницу аниме https://animego.me/anime/volchica-i-pryanosti-torgovec-vstrechaet-mudruyu-volchicu-2546 > 2546)
@@ -959,6 +1046,9 @@ func (ab *AniboomParser) get_mpd_playlist(embed_link, translation string, episod
 // Также в файле содержится сразу несколько "качеств" видео (от 480 до 1080 в большинстве случаев).
 // Если вам нужен mp4 файл воспользуйтесь ffmpeg или другими конвертерами
 func (ab *AniboomParser) GetMPDPlaylist(animego_id, translation_id string, episode int) (string, error) {
+	if err := check_aniboom_translation("GetMPDPlaylist", translation_id); err != nil {
+		return "", err
+	}
 	embed_link, err := ab.get_embed_link(animego_id)
 	if err != nil {
 		log.Printf("Aniboom parser error : GetMPDPlaylist : get_embed_link вернул ошибку. Ошибка: %v", err)
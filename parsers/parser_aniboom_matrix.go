@@ -0,0 +1,290 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// id провайдера aniboom в плеере animego (атрибут data-provider)
+const aniboomProviderID = "24"
+
+// Плеер (провайдер) перевода в плеере animego
+type ABPlayer struct {
+	// Значение атрибута data-provider (прим: 24 для aniboom)
+	ProviderID string `json:"provider_id"`
+	// Название провайдера как оно указано в плеере (прим: AniBoom, Kodik)
+	Provider string `json:"provider"`
+	// Ссылка на плеер (атрибут data-player), всегда с https:
+	URL string `json:"url"`
+}
+
+// Вид перевода (Translation.Kind)
+const (
+	TranslationDub       = "dub"
+	TranslationSubtitles = "subtitles"
+)
+
+// Перевод, доступный для конкретного эпизода
+type ABEpisodeTranslation struct {
+	Name string `json:"name"`
+	// Озвучка или субтитры (TranslationDub или TranslationSubtitles)
+	Kind string `json:"kind"`
+	// id озвучки на animego (атрибут data-dubbing)
+	Dubbing string `json:"dubbing"`
+	// id перевода для плеера aniboom. Пустой, если перевод для эпизода не доступен в aniboom
+	TranslationID string      `json:"translation_id"`
+	Players       []*ABPlayer `json:"players"`
+}
+
+// Эпизод из карусели плеера animego
+type ab_player_episode struct {
+	num int
+	id  string
+}
+
+// Возвращает id перевода aniboom из ссылки на плеер (прим: //aniboom.one/embed/yxVdenrqNar?episode=1&translation=2 > 2)
+func aniboom_translation_id(player_link string) string {
+	lastIndex := strings.LastIndex(player_link, "=")
+	if lastIndex != -1 && lastIndex < len(player_link)-1 {
+		return player_link[lastIndex+1:]
+	}
+	return player_link
+}
+
+// Определяет вид перевода по его названию в плеере animego (прим: "Субтитры", "AniLibria (субтитры)", "Crunchyroll [SUB]")
+func translation_kind(name string) string {
+	lower := strings.ToLower(name)
+	if strings.Contains(lower, "субтитр") || strings.Contains(lower, "[sub]") || strings.Contains(lower, "(sub)") || strings.HasSuffix(lower, " sub") {
+		return TranslationSubtitles
+	}
+	return TranslationDub
+}
+
+// Разбирает блоки #video-dubbing и #video-players из html плеера animego.
+//
+// Возвращает переводы по id озвучки (data-dubbing) в порядке их появления в плеере
+func parse_dubbing_players(doc *goquery.Document) []*ABEpisodeTranslation {
+	by_dubbing := make(map[string]*ABEpisodeTranslation)
+	order := make([]string, 0)
+
+	get := func(dubbing string) *ABEpisodeTranslation {
+		if _, exists := by_dubbing[dubbing]; !exists {
+			by_dubbing[dubbing] = &ABEpisodeTranslation{Dubbing: dubbing, Players: make([]*ABPlayer, 0)}
+			order = append(order, dubbing)
+		}
+		return by_dubbing[dubbing]
+	}
+
+	doc.Find("#video-dubbing").Find("span.video-player-toggle-item").Each(func(i int, s *goquery.Selection) {
+		dubbing, exists := s.Attr("data-dubbing")
+		if !exists || dubbing == "" {
+			return
+		}
+		name := strings.TrimSpace(s.Text())
+		if name == "" {
+			return
+		}
+		translation := get(dubbing)
+		translation.Name = name
+		translation.Kind = translation_kind(name)
+	})
+
+	doc.Find("#video-players").Find("span.video-player-toggle-item").Each(func(i int, s *goquery.Selection) {
+		dubbing, exists := s.Attr("data-provide-dubbing")
+		if !exists || dubbing == "" {
+			return
+		}
+		player_link, exists := s.Attr("data-player")
+		if !exists || player_link == "" {
+			return
+		}
+		provider_id, _ := s.Attr("data-provider")
+
+		player := &ABPlayer{
+			ProviderID: provider_id,
+			Provider:   strings.TrimSpace(s.Text()),
+			URL:        player_link,
+		}
+		if strings.HasPrefix(player.URL, "//") {
+			player.URL = "https:" + player.URL
+		}
+
+		translation := get(dubbing)
+		translation.Players = append(translation.Players, player)
+		if provider_id == aniboomProviderID {
+			translation.TranslationID = aniboom_translation_id(player_link)
+		}
+	})
+
+	result := make([]*ABEpisodeTranslation, 0, len(order))
+	for _, dubbing := range order {
+		translation := by_dubbing[dubbing]
+		if translation.Name != "" && len(translation.Players) > 0 {
+			result = append(result, translation)
+		}
+	}
+	return result
+}
+
+// Разбирает список вышедших эпизодов из карусели плеера animego (элементы с атрибутами data-episode и data-id)
+func parse_player_episodes(doc *goquery.Document) []*ab_player_episode {
+	result := make([]*ab_player_episode, 0)
+	seen := make(map[int]bool)
+	doc.Find("[data-episode][data-id]").Each(func(i int, s *goquery.Selection) {
+		episode, _ := s.Attr("data-episode")
+		num, err := strconv.Atoi(strings.TrimSpace(episode))
+		if err != nil || seen[num] {
+			return
+		}
+		id, _ := s.Attr("data-id")
+		if id == "" {
+			return
+		}
+		seen[num] = true
+		result = append(result, &ab_player_episode{num: num, id: id})
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].num < result[j].num
+	})
+	return result
+}
+
+// Загружает html плеера animego для конкретного эпизода.
+//
+// :episode_id: id эпизода на animego (атрибут data-id в карусели плеера)
+func (ab *AniboomParser) get_episode_player_doc(episode_id string) (*goquery.Document, error) {
+	params := models.Params{
+		"id": episode_id,
+	}
+
+	referer := fmt.Sprintf("https://%s/search/all?q=anime", ab.domain())
+	headers := models.Headers{
+		"X-Requested-With": "XMLHttpRequest",
+		"Referer":          referer,
+	}
+
+	URL := fmt.Sprintf("https://%s/anime/series", ab.domain())
+
+	response, err := ab.mirrors.Request(ab.context, "GET", URL, params, headers, true, &ABJsonResponse{})
+	if err != nil {
+		error_message := fmt.Sprintf("Aniboom parser error : get_episode_player_doc : RequestWithContext вернул ошибку: %v", err)
+		log.Println(error_message)
+		return nil, t.WrapRequestError(err, error_message)
+	}
+
+	json_response, ok := response.Json.(*ABJsonResponse)
+	if !ok {
+		error_message := "Aniboom parser error : get_episode_player_doc : не смог привести result.Json к *ABJsonResponse"
+		log.Println(error_message)
+		return nil, errs.NewServiceError(error_message)
+	}
+
+	if json_response.Status != "success" {
+		return nil, errs.NewServiceError(fmt.Sprintf(
+			"Aniboom parser error : get_episode_player_doc : сервер вернул статус отличный от success: %q, сообщение: %q для id эпизода: %q",
+			json_response.Status, json_response.Message, episode_id,
+		))
+	}
+
+	htmlContent := html.UnescapeString(json_response.Content)
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		error_message := fmt.Sprintf("Aniboom parser error : get_episode_player_doc : goquery не смог преобразовать ответ в документ. Ошибка: %v", err)
+		log.Println(error_message)
+		return nil, errs.NewServiceError(error_message)
+	}
+	return doc, nil
+}
+
+// Возвращает доступные переводы для каждого вышедшего эпизода.
+//
+// Список эпизодов берется из карусели плеера, после чего для каждого эпизода параллельно запрашивается его плеер.
+// Если у аниме нет карусели эпизодов (фильм), результат содержит единственный ключ 0 с переводами из страницы плеера.
+//
+// Если часть эпизодов загрузить не удалось, возвращаются данные по остальным эпизодам вместе с объединенной ошибкой (errors.Join)
+//
+// Возвращает словарь номер эпизода > срез ссылок на ABEpisodeTranslation
+func (s *ABSession) TranslationsMatrix() (map[int][]*ABEpisodeTranslation, error) {
+	doc, err := s.PlayerDoc()
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.Find("div.player-blocked").Length() > 0 {
+		reason := strings.TrimSpace(doc.Find("div.h5").First().Text())
+		error_message := fmt.Sprintf("Aniboom parser error : TranslationsMatrix : Контент по id %s заблокирован. Причина блокировки: \"%s\"", s.animegoID, reason)
+		log.Println(error_message)
+		return nil, errs.NewContentBlockedError(error_message)
+	}
+
+	matrix := make(map[int][]*ABEpisodeTranslation)
+
+	episodes := parse_player_episodes(doc)
+	if len(episodes) == 0 {
+		matrix[0] = parse_dubbing_players(doc)
+		return matrix, nil
+	}
+
+	s.mu.Lock()
+	workers := s.workers
+	s.mu.Unlock()
+
+	jobs := make(chan *ab_player_episode)
+	mu := sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	failures := make([]error, 0)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for episode := range jobs {
+				episode_doc, err := s.requests().get_episode_player_doc(episode.id)
+				mu.Lock()
+				if err != nil {
+					failures = append(failures, fmt.Errorf("эпизод %d: %w", episode.num, err))
+				} else {
+					matrix[episode.num] = parse_dubbing_players(episode_doc)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, episode := range episodes {
+		jobs <- episode
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(failures) == len(episodes) {
+		error_message := fmt.Sprintf("Aniboom parser error : TranslationsMatrix : не удалось загрузить плеер ни для одного эпизода animego_id %s. Ошибка: %v", s.animegoID, errors.Join(failures...))
+		log.Println(error_message)
+		return nil, errs.NewServiceError(error_message)
+	}
+	if len(failures) > 0 {
+		log.Printf("Aniboom parser warning : TranslationsMatrix : не удалось загрузить плеер для %d из %d эпизодов animego_id %s", len(failures), len(episodes), s.animegoID)
+		return matrix, errors.Join(failures...)
+	}
+	return matrix, nil
+}
+
+// Возвращает доступные переводы для каждого вышедшего эпизода (см. ABSession.TranslationsMatrix).
+//
+// :animego_id: id аниме на animego.me
+//
+// Возвращает словарь номер эпизода > срез ссылок на ABEpisodeTranslation
+func (ab *AniboomParser) GetEpisodesTranslations(animego_id string) (map[int][]*ABEpisodeTranslation, error) {
+	return ab.NewSession(animego_id).TranslationsMatrix()
+}
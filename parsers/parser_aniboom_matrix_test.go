@@ -0,0 +1,196 @@
+package parsers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+// Плеер animego с каруселью эпизодов: эпизоды не по порядку, повтор эпизода 2 и элементы без номера или id пропускаются
+const test_animego_player_episodes = `<div class="player-video-bar">
+<div data-episode="3" data-id="e3">3 серия</div>
+<div data-episode="1" data-id="e1">1 серия</div>
+<div data-episode="2" data-id="e2">2 серия</div>
+<div data-episode="2" data-id="e2-copy">2 серия</div>
+<div data-episode="трейлер" data-id="t1">Трейлер</div>
+<div data-episode="4" data-id="">4 серия</div>
+</div>` + test_animego_player
+
+// Плеер эпизода 1: AniLibria в aniboom и kodik, субтитры без плееров и плеер без озвучки не попадают в результат
+const test_animego_episode_1 = `<div id="video-dubbing">
+<span class="video-player-toggle-item" data-dubbing="1">
+	AniLibria
+</span>
+<span class="video-player-toggle-item" data-dubbing="2">Субтитры</span>
+<span class="video-player-toggle-item" data-dubbing="">Без id</span>
+</div>
+<div id="video-players">
+<span class="video-player-toggle-item" data-provide-dubbing="1" data-provider="24" data-player="//aniboom.one/embed/yxVdenrqNar?episode=1&amp;translation=2">AniBoom</span>
+<span class="video-player-toggle-item" data-provide-dubbing="1" data-provider="29" data-player="https://kodik.info/serial/1/hash/720p">Kodik</span>
+<span class="video-player-toggle-item" data-provide-dubbing="3" data-provider="29" data-player="//kodik.info/serial/3/hash/720p">Kodik</span>
+<span class="video-player-toggle-item" data-provide-dubbing="1" data-provider="31">Без ссылки</span>
+</div>`
+
+// Плеер эпизода 2: только субтитры в kodik
+const test_animego_episode_2 = `<div id="video-dubbing">
+<span class="video-player-toggle-item" data-dubbing="5">Crunchyroll [SUB]</span>
+</div>
+<div id="video-players">
+<span class="video-player-toggle-item" data-provide-dubbing="5" data-provider="29" data-player="//kodik.info/serial/5/hash/720p">Kodik</span>
+</div>`
+
+func test_goquery(test *testing.T, content string) *goquery.Document {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		test.Fatal(err)
+	}
+	return doc
+}
+
+func TestTranslationKind(test *testing.T) {
+	tests := map[string]string{
+		"AniLibria":             TranslationDub,
+		"Субтитры":              TranslationSubtitles,
+		"AniLibria (субтитры)":  TranslationSubtitles,
+		"Crunchyroll [SUB]":     TranslationSubtitles,
+		"Wakanim (sub)":         TranslationSubtitles,
+		"Crunchyroll Sub":       TranslationSubtitles,
+		"SubStudio":             TranslationDub,
+		"Студийная Банда":       TranslationDub,
+		"Dream Cast (Субтитры)": TranslationSubtitles,
+	}
+	for name, want := range tests {
+		if got := translation_kind(name); got != want {
+			test.Errorf("translation_kind(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestParseDubbingPlayers(test *testing.T) {
+	translations := parse_dubbing_players(test_goquery(test, test_animego_episode_1))
+	if len(translations) != 1 {
+		test.Fatalf("parse_dubbing_players вернул %d переводов, want 1: %+v", len(translations), translations)
+	}
+	translation := translations[0]
+	if translation.Name != "AniLibria" || translation.Kind != TranslationDub || translation.Dubbing != "1" || translation.TranslationID != "2" {
+		test.Errorf("parse_dubbing_players[0] = %+v", translation)
+	}
+	want := []ABPlayer{
+		{ProviderID: "24", Provider: "AniBoom", URL: "https://aniboom.one/embed/yxVdenrqNar?episode=1&translation=2"},
+		{ProviderID: "29", Provider: "Kodik", URL: "https://kodik.info/serial/1/hash/720p"},
+	}
+	if len(translation.Players) != len(want) {
+		test.Fatalf("плееров %d, want %d", len(translation.Players), len(want))
+	}
+	for i, player := range translation.Players {
+		if *player != want[i] {
+			test.Errorf("Players[%d] = %+v, want %+v", i, *player, want[i])
+		}
+	}
+
+	// Перевод без плеера aniboom: пустой TranslationID
+	translations = parse_dubbing_players(test_goquery(test, test_animego_episode_2))
+	if len(translations) != 1 || translations[0].TranslationID != "" || translations[0].Kind != TranslationSubtitles {
+		test.Errorf("parse_dubbing_players для субтитров kodik = %+v", translations)
+	}
+}
+
+func TestParsePlayerEpisodes(test *testing.T) {
+	episodes := parse_player_episodes(test_goquery(test, test_animego_player_episodes))
+	want := []ab_player_episode{{num: 1, id: "e1"}, {num: 2, id: "e2"}, {num: 3, id: "e3"}}
+	if len(episodes) != len(want) {
+		test.Fatalf("parse_player_episodes вернул %d эпизодов, want %d", len(episodes), len(want))
+	}
+	for i, episode := range episodes {
+		if *episode != want[i] {
+			test.Errorf("episodes[%d] = %+v, want %+v", i, *episode, want[i])
+		}
+	}
+
+	// Фильм без карусели эпизодов
+	if episodes := parse_player_episodes(test_goquery(test, test_animego_player)); len(episodes) != 0 {
+		test.Errorf("parse_player_episodes без карусели вернул %d эпизодов", len(episodes))
+	}
+}
+
+// Плеер animego и плееры эпизодов, failed - id эпизодов, для которых сервер отвечает ошибкой
+func test_animego_matrix_sites(player string, failed ...string) http.HandlerFunc {
+	episodes := map[string]string{"e1": test_animego_episode_1, "e2": test_animego_episode_2, "e3": test_animego_episode_2}
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Host + r.URL.Path {
+		case "animego.me/anime/102/player":
+			write_test_json(w, map[string]string{"status": "success", "content": player})
+		case "animego.me/anime/series":
+			id := r.URL.Query().Get("id")
+			for _, failed_id := range failed {
+				if id == failed_id {
+					http.Error(w, "internal error", http.StatusInternalServerError)
+					return
+				}
+			}
+			write_test_json(w, map[string]string{"status": "success", "content": episodes[id]})
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func TestTranslationsMatrix(test *testing.T) {
+	use_test_sites(test, test_animego_matrix_sites(test_animego_player_episodes))
+	ab := NewAniboomParser("animego.me")
+
+	matrix, err := ab.GetEpisodesTranslations("102")
+	if err != nil {
+		test.Fatalf("GetEpisodesTranslations вернул ошибку: %v", err)
+	}
+	if len(matrix) != 3 {
+		test.Fatalf("GetEpisodesTranslations вернул %d эпизодов, want 3", len(matrix))
+	}
+	if first := matrix[1]; len(first) != 1 || first[0].Name != "AniLibria" || first[0].TranslationID != "2" {
+		test.Errorf("эпизод 1: %+v", first)
+	}
+	for _, num := range []int{2, 3} {
+		if translations := matrix[num]; len(translations) != 1 || translations[0].Name != "Crunchyroll [SUB]" {
+			test.Errorf("эпизод %d: %+v", num, translations)
+		}
+	}
+
+	// Фильм: переводы со страницы плеера под ключом 0
+	use_test_sites(test, test_animego_matrix_sites(test_animego_player))
+	matrix, err = ab.GetEpisodesTranslations("102")
+	if err != nil || len(matrix) != 1 || len(matrix[0]) != 2 {
+		test.Errorf("GetEpisodesTranslations для фильма = %v, %v", matrix, err)
+	}
+}
+
+func TestTranslationsMatrixFailures(test *testing.T) {
+	// Часть эпизодов не загрузилась: остальные эпизоды возвращаются вместе с ошибкой
+	use_test_sites(test, test_animego_matrix_sites(test_animego_player_episodes, "e3"))
+	ab := NewAniboomParser("animego.me")
+	matrix, err := ab.GetEpisodesTranslations("102")
+	if err == nil || !strings.Contains(err.Error(), "эпизод 3") {
+		test.Errorf("GetEpisodesTranslations вернул ошибку %v, want ошибку для эпизода 3", err)
+	}
+	if len(matrix) != 2 || matrix[1] == nil || matrix[2] == nil {
+		test.Errorf("GetEpisodesTranslations при ошибке эпизода 3 = %v, want эпизоды 1 и 2", matrix)
+	}
+
+	// Ни один эпизод не загрузился
+	use_test_sites(test, test_animego_matrix_sites(test_animego_player_episodes, "e1", "e2", "e3"))
+	matrix, err = ab.GetEpisodesTranslations("102")
+	var service *errs.ServiceError
+	if matrix != nil || !errors.As(err, &service) {
+		test.Errorf("GetEpisodesTranslations без эпизодов = %v, %T: %v, want *errs.ServiceError", matrix, err, err)
+	}
+
+	// Заблокированный контент
+	use_test_sites(test, test_animego_matrix_sites(`<div class="player-blocked"><div class="h5">Заблокировано правообладателем</div></div>`))
+	var blocked *errs.ContentBlocked
+	if _, err := ab.GetEpisodesTranslations("102"); !errors.As(err, &blocked) || !strings.Contains(err.Error(), "Заблокировано правообладателем") {
+		test.Errorf("GetEpisodesTranslations для заблокированного контента вернул %T: %v, want *errs.ContentBlocked", err, err)
+	}
+}
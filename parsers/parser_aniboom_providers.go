@@ -0,0 +1,157 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+// Вид результата ABStream
+const (
+	// Content содержит mpd (или m3u8) плейлист
+	ABStreamPlaylist = "playlist"
+	// URL содержит ссылку на embed плеер провайдера, которую можно открыть в iframe
+	ABStreamEmbed = "embed"
+)
+
+// Результат обработки плеера провайдера
+type ABStream struct {
+	Provider string `json:"provider"`
+	Kind     string `json:"kind"`
+	URL      string `json:"url"`
+	Content  string `json:"content,omitempty"`
+}
+
+// Обработчик плеера провайдера.
+//
+// :player: плеер перевода (можно получить из Translation.Players)
+//
+// :episode: Номер эпизода (вышедшего) (Если фильм - 0)
+type ABProviderHandler func(ab *AniboomParser, player *ABPlayer, episode int) (*ABStream, error)
+
+var (
+	provider_handlers_mu sync.RWMutex
+	provider_handlers    = map[string]ABProviderHandler{
+		"aniboom": aniboom_provider_handler,
+		"kodik":   kodik_provider_handler,
+	}
+)
+
+// Регистрирует обработчик для провайдера плеера animego. Существующий обработчик с тем же названием заменяется.
+//
+// :provider: название провайдера как в плеере animego без учета регистра (прим: kodik, cvh)
+func RegisterProviderHandler(provider string, handler ABProviderHandler) {
+	provider_handlers_mu.Lock()
+	defer provider_handlers_mu.Unlock()
+	provider_handlers[provider_key(provider)] = handler
+}
+
+func provider_key(provider string) string {
+	return strings.ToLower(strings.TrimSpace(provider))
+}
+
+// Возвращает название провайдера плеера, по которому ищется обработчик
+func (p *ABPlayer) key() string {
+	if p.ProviderID == aniboomProviderID {
+		return "aniboom"
+	}
+	return provider_key(p.Provider)
+}
+
+// Плеер aniboom: получает mpd плейлист через embed ссылку из плеера
+func aniboom_provider_handler(ab *AniboomParser, player *ABPlayer, episode int) (*ABStream, error) {
+	embed_link := player.URL
+	if index := strings.Index(embed_link, "?"); index != -1 {
+		embed_link = embed_link[:index]
+	}
+	playlist, err := ab.get_mpd_playlist(embed_link, aniboom_translation_id(player.URL), episode)
+	if err != nil {
+		return nil, err
+	}
+	return &ABStream{
+		Provider: player.Provider,
+		Kind:     ABStreamPlaylist,
+		URL:      embed_link,
+		Content:  playlist,
+	}, nil
+}
+
+// Плеер kodik: передается KodikParser (см. SetKodikParser), который проверяет плеер в API kodik
+// и возвращает embed ссылку с выбранным эпизодом
+func kodik_provider_handler(ab *AniboomParser, player *ABPlayer, episode int) (*ABStream, error) {
+	stream, err := ab.kodik.PlayerStream(player.URL, episode)
+	if err != nil {
+		return nil, err
+	}
+	return &ABStream{
+		Provider: player.Provider,
+		Kind:     stream.Kind,
+		URL:      stream.URL,
+	}, nil
+}
+
+// Остальные провайдеры: ссылка на плеер возвращается без изменений
+func embed_provider_handler(ab *AniboomParser, player *ABPlayer, episode int) (*ABStream, error) {
+	return &ABStream{
+		Provider: player.Provider,
+		Kind:     ABStreamEmbed,
+		URL:      player.URL,
+	}, nil
+}
+
+// Передает плеер перевода обработчику его провайдера (aniboom, kodik или зарегистрированному через RegisterProviderHandler).
+//
+// :translation: перевод (можно получить из GetTranslationsInfo)
+//
+// :episode: Номер эпизода (вышедшего) (Если фильм - 0)
+//
+// :providers: порядок провайдеров без учета регистра (прим: "aniboom", "kodik"). Если не указан - в порядке плеера animego
+//
+// Если обработчик провайдера вернул ошибку (прим: плеер aniboom заблокирован), пробуется следующий провайдер.
+//
+// Возвращает ссылку на ABStream
+func (ab *AniboomParser) ResolvePlayer(translation *Translation, episode int, providers ...string) (*ABStream, error) {
+	players := make([]*ABPlayer, 0, len(translation.Players))
+	if len(providers) == 0 {
+		players = append(players, translation.Players...)
+	} else {
+		for _, provider := range providers {
+			for _, player := range translation.Players {
+				if player.key() == provider_key(provider) {
+					players = append(players, player)
+				}
+			}
+		}
+	}
+
+	if len(players) == 0 {
+		error_message := fmt.Sprintf("Aniboom parser error : ResolvePlayer : для перевода %q не найдено плееров провайдеров %v", translation.Name, providers)
+		log.Println(error_message)
+		return nil, errs.NewNoResultsError(error_message)
+	}
+
+	failures := make([]error, 0, len(players))
+	for _, player := range players {
+		provider_handlers_mu.RLock()
+		handler, exists := provider_handlers[player.key()]
+		provider_handlers_mu.RUnlock()
+		if !exists {
+			handler = embed_provider_handler
+		}
+
+		stream, err := handler(ab, player, episode)
+		if err == nil {
+			return stream, nil
+		}
+		log.Printf("Aniboom parser warning : ResolvePlayer : провайдер %s вернул ошибку для перевода %q: %v", player.Provider, translation.Name, err)
+		failures = append(failures, fmt.Errorf("%s: %w", player.Provider, err))
+	}
+
+	error_message := fmt.Sprintf("Aniboom parser error : ResolvePlayer : ни один провайдер не смог обработать перевод %q. Ошибки: %v", translation.Name, errors.Join(failures...))
+	log.Println(error_message)
+	return nil, errs.NewServiceError(error_message)
+}
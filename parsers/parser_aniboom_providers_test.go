@@ -0,0 +1,41 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestResolvePlayerKodik(test *testing.T) {
+	server, kodik := new_test_kodik(test)
+	ab := NewAniboomParser("animego.me")
+	ab.SetKodikParser(kodik)
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(test_animego_player))
+	if err != nil {
+		test.Fatal(err)
+	}
+	translations, err := parse_translations(doc, "102")
+	if err != nil {
+		test.Fatalf("parse_translations вернул ошибку: %v", err)
+	}
+	subtitles := translations[1]
+
+	// Плеер kodik передается KodikParser: плеер ищется в API kodik, эпизод добавляется к ссылке
+	stream, err := ab.ResolvePlayer(subtitles, 5)
+	if err != nil {
+		test.Fatalf("ResolvePlayer вернул ошибку: %v", err)
+	}
+	if stream.Provider != "Kodik" || stream.Kind != ABStreamEmbed || stream.URL != "https://kodik.info/serial/2/hash/720p?episode=5&translations=false" {
+		test.Errorf("ResolvePlayer = %+v", stream)
+	}
+	if calls := server.calls(); len(calls) != 1 || calls[0] != "player_link=%2F%2Fkodik.info%2Fserial%2F2%2Fhash%2F720p&types=anime%2Canime-serial" {
+		test.Errorf("запросы к kodik: %v", calls)
+	}
+
+	// Эпизод, которого нет в плеере kodik
+	if _, err := ab.ResolvePlayer(subtitles, 13, "kodik"); err == nil {
+		test.Error("ResolvePlayer для невышедшего эпизода должен вернуть ошибку")
+	}
+}
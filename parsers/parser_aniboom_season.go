@@ -0,0 +1,187 @@
+package parsers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+// Настройки для DownloadSeason
+type ABSeasonPreferences struct {
+	// Названия переводов в порядке приоритета (прим: []string{"AniLibria", "StudioBand"}). Сравнение без учета регистра
+	Translations []string `json:"translations"`
+	// Если ни один перевод из Translations не найден, будет выбран любой доступный перевод
+	AnyTranslation bool `json:"any_translation"`
+	// Первый эпизод диапазона (0 - с первого вышедшего)
+	FromEpisode int `json:"from_episode"`
+	// Последний эпизод диапазона (0 - до последнего вышедшего)
+	ToEpisode int `json:"to_episode"`
+	// Папка для сохранения mpd файлов. Если пустая, файлы не сохраняются и плейлисты возвращаются только в ABEpisodeDownload.Playlist
+	OutputDir string `json:"output_dir"`
+}
+
+// Результат загрузки одного эпизода
+type ABEpisodeDownload struct {
+	Episode  int    `json:"episode"`
+	Playlist string `json:"playlist,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Err      error  `json:"-"`
+}
+
+// Итог загрузки сезона
+type ABSeasonDownload struct {
+	AnimegoID   string               `json:"animego_id"`
+	Translation *Translation         `json:"translation"`
+	Episodes    []*ABEpisodeDownload `json:"episodes"`
+	Succeeded   int                  `json:"succeeded"`
+	Failed      int                  `json:"failed"`
+}
+
+// Возвращает ссылку на страницу аниме по его id на animego.me (прим: 2546 > https://animego.me/anime/2546)
+func (ab *AniboomParser) anime_link(animego_id string) string {
+	return fmt.Sprintf("https://%s/anime/%s", ab.domain(), animego_id)
+}
+
+// Выбирает перевод согласно порядку preferences.Translations.
+// Переводы без id для плеера aniboom пропускаются.
+//
+// Если ничего не найдено и preferences.AnyTranslation == true, возвращает первый по алфавиту перевод
+func pick_translation(translations []*Translation, preferences *ABSeasonPreferences) *Translation {
+	available := make([]*Translation, 0, len(translations))
+	for _, translation := range translations {
+		if translation.TranslationID != "" {
+			available = append(available, translation)
+		}
+	}
+
+	for _, name := range preferences.Translations {
+		name = strings.TrimSpace(name)
+		for _, translation := range available {
+			if strings.EqualFold(strings.TrimSpace(translation.Name), name) {
+				return translation
+			}
+		}
+	}
+
+	if !preferences.AnyTranslation || len(available) == 0 {
+		return nil
+	}
+	sort.Slice(available, func(i, j int) bool {
+		return available[i].Name < available[j].Name
+	})
+	return available[0]
+}
+
+// Возвращает отсортированные номера вышедших эпизодов в диапазоне [from, to] (0 - без ограничения).
+// Эпизоды с нечисловым номером пропускаются
+func released_episodes(episodes_info []*EpisodeInfo, from, to int) []int {
+	result := make([]int, 0, len(episodes_info))
+	for _, episode := range episodes_info {
+		if episode.Status != "вышел" {
+			continue
+		}
+		num, err := strconv.Atoi(episode.Num)
+		if err != nil {
+			continue
+		}
+		if from > 0 && num < from {
+			continue
+		}
+		if to > 0 && num > to {
+			continue
+		}
+		result = append(result, num)
+	}
+	sort.Ints(result)
+	return result
+}
+
+// Загружает mpd плейлисты для всех вышедших эпизодов аниме с выбранным переводом.
+//
+// :animego_id: id аниме на animego.me (прим: https://animego.me/anime/volchica-i-pryanosti-torgovec-vstrechaet-mudruyu-volchicu-2546 > 2546)
+//
+// :preferences: настройки загрузки (порядок переводов, диапазон эпизодов, папка для сохранения). Если nil - любой перевод, все вышедшие эпизоды, без сохранения
+//
+// Эпизоды загружаются параллельно через ABSession, страница плеера запрашивается один раз.
+// Ошибка отдельного эпизода не прерывает загрузку, а записывается в ABEpisodeDownload.Err.
+// Если у аниме нет расписания эпизодов (фильм), загружается эпизод 0.
+//
+// Возвращает ссылку на ABSeasonDownload
+func (ab *AniboomParser) DownloadSeason(animego_id string, preferences *ABSeasonPreferences) (*ABSeasonDownload, error) {
+	if preferences == nil {
+		preferences = &ABSeasonPreferences{AnyTranslation: true}
+	}
+
+	session := ab.NewSession(animego_id)
+
+	translations, err := session.Translations()
+	if err != nil {
+		log.Printf("Aniboom parser error : DownloadSeason : Translations вернул ошибку: %v", err)
+		return nil, err
+	}
+
+	translation := pick_translation(translations, preferences)
+	if translation == nil {
+		error_message := fmt.Sprintf("Aniboom parser error : DownloadSeason : для animego_id %s не найдено ни одного подходящего перевода из %v", animego_id, preferences.Translations)
+		log.Println(error_message)
+		return nil, errs.NewNoResultsError(error_message)
+	}
+
+	episodes_info, err := session.Episodes()
+	if err != nil {
+		log.Printf("Aniboom parser error : DownloadSeason : Episodes вернул ошибку: %v", err)
+		return nil, err
+	}
+
+	var episodes []int
+	if len(episodes_info) == 0 {
+		episodes = []int{0}
+	} else {
+		episodes = released_episodes(episodes_info, preferences.FromEpisode, preferences.ToEpisode)
+	}
+
+	if preferences.OutputDir != "" {
+		if err := os.MkdirAll(preferences.OutputDir, 0o755); err != nil {
+			error_message := fmt.Sprintf("Aniboom parser error : DownloadSeason : не удалось создать папку %s. Ошибка: %v", preferences.OutputDir, err)
+			log.Println(error_message)
+			return nil, errs.NewServiceError(error_message)
+		}
+	}
+
+	result := &ABSeasonDownload{
+		AnimegoID:   animego_id,
+		Translation: translation,
+		Episodes:    make([]*ABEpisodeDownload, 0, len(episodes)),
+	}
+
+	failed := session.Prefetch(translation.TranslationID, episodes)
+
+	for _, episode := range episodes {
+		download := &ABEpisodeDownload{Episode: episode}
+		if download.Err = failed[episode]; download.Err == nil {
+			download.Playlist, download.Err = session.Playlist(episode, translation.TranslationID)
+		}
+		if download.Err == nil && preferences.OutputDir != "" {
+			download.Filename = filepath.Join(preferences.OutputDir, fmt.Sprintf("%s_%d.mpd", animego_id, episode))
+			download.Err = os.WriteFile(download.Filename, []byte(download.Playlist), 0o644)
+		}
+		if download.Err != nil {
+			log.Printf("Aniboom parser error : DownloadSeason : не удалось загрузить эпизод %d для animego_id %s. Ошибка: %v", episode, animego_id, download.Err)
+			download.Error = download.Err.Error()
+			download.Filename = ""
+			result.Failed++
+		} else {
+			result.Succeeded++
+		}
+		result.Episodes = append(result.Episodes, download)
+	}
+
+	return result, nil
+}
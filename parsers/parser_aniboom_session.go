@@ -0,0 +1,247 @@
+package parsers
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Количество эпизодов, загружаемых параллельно в ABSession.Prefetch по умолчанию
+const defaultSessionWorkers = 4
+
+// Сессия для работы с одним аниме на animego.me.
+//
+// Страница плеера (а вместе с ней embed ссылка и список переводов), список эпизодов и полученные плейлисты
+// загружаются один раз и переиспользуются между вызовами. В отличие от GetMPDPlaylist, который на каждый эпизод
+// заново запрашивает страницу плеера, сессия делает один запрос плеера на все эпизоды.
+//
+// Эпизоды загружаются параллельно (см. Prefetch), поэтому каждый запрос сессии отправляется на сервер один раз,
+// а не сразу несколькими воркерами (см. tools.WithRequestWorkers). Плейлист сезона из n эпизодов стоит 1 + 2n запросов
+// (страница плеера, затем embed и плейлист на эпизод) вместо 3n запросов GetMPDPlaylist, каждый из которых отправляется до 3 раз.
+//
+// Методы сессии безопасны для вызова из нескольких горутин
+type ABSession struct {
+	parser    *AniboomParser
+	animegoID string
+	workers   int
+
+	mu           sync.Mutex
+	player_doc   *goquery.Document
+	embed_link   string
+	translations []*Translation
+	episodes     []*EpisodeInfo
+	playlists    map[string]string
+}
+
+// Создает сессию для аниме.
+//
+// :animego_id: id аниме на animego.me (прим: https://animego.me/anime/volchica-i-pryanosti-torgovec-vstrechaet-mudruyu-volchicu-2546 > 2546)
+//
+// Запросы не выполняются до первого вызова методов сессии
+func (ab *AniboomParser) NewSession(animego_id string) *ABSession {
+	return &ABSession{
+		parser:    ab,
+		animegoID: animego_id,
+		workers:   defaultSessionWorkers,
+		playlists: make(map[string]string),
+	}
+}
+
+// Задает количество эпизодов, загружаемых параллельно в Prefetch. Значения меньше 1 игнорируются
+func (s *ABSession) SetWorkers(workers int) {
+	if workers < 1 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workers = workers
+}
+
+// Парсер для запросов сессии: тот же парсер, но каждый запрос отправляется одним воркером
+func (s *ABSession) requests() *AniboomParser {
+	parser := *s.parser
+	parser.context = t.WithRequestWorkers(s.parser.context, 1)
+	return &parser
+}
+
+// Возвращает id аниме на animego.me, для которого создана сессия
+func (s *ABSession) AnimegoID() string {
+	return s.animegoID
+}
+
+// Загружает страницу плеера, если она еще не загружена. Вызывающий должен держать s.mu
+func (s *ABSession) load_player() (*goquery.Document, error) {
+	if s.player_doc != nil {
+		return s.player_doc, nil
+	}
+	doc, err := s.requests().get_player_doc(s.animegoID)
+	if err != nil {
+		return nil, err
+	}
+	s.player_doc = doc
+	return doc, nil
+}
+
+// Возвращает html страницы плеера animego в виде goquery документа
+func (s *ABSession) PlayerDoc() (*goquery.Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, err := s.load_player()
+	if err != nil {
+		log.Printf("Aniboom parser error : ABSession.PlayerDoc : get_player_doc вернул ошибку: %v", err)
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Возвращает ссылку на embed от aniboom (см. get_embed_link)
+func (s *ABSession) EmbedLink() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.embed_link != "" {
+		return s.embed_link, nil
+	}
+	doc, err := s.load_player()
+	if err != nil {
+		log.Printf("Aniboom parser error : ABSession.EmbedLink : get_player_doc вернул ошибку: %v", err)
+		return "", err
+	}
+	embed_link, err := parse_embed_link(doc, s.animegoID)
+	if err != nil {
+		log.Printf("Aniboom parser error : ABSession.EmbedLink : parse_embed_link вернул ошибку: %v", err)
+		return "", err
+	}
+	s.embed_link = embed_link
+	return embed_link, nil
+}
+
+// Возвращает переводы всех провайдеров (см. GetTranslationsInfo). В Playlist передаются только переводы с Provider "aniboom"
+func (s *ABSession) Translations() ([]*Translation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.translations != nil {
+		return s.translations, nil
+	}
+	doc, err := s.load_player()
+	if err != nil {
+		log.Printf("Aniboom parser error : ABSession.Translations : get_player_doc вернул ошибку: %v", err)
+		return nil, err
+	}
+	translations, err := parse_translations(doc, s.animegoID)
+	if err != nil {
+		return nil, err
+	}
+	s.translations = translations
+	return translations, nil
+}
+
+// Возвращает данные по эпизодам (см. EpisodesInfo)
+func (s *ABSession) Episodes() ([]*EpisodeInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.episodes != nil {
+		return s.episodes, nil
+	}
+	episodes, err := s.requests().EpisodesInfo(s.parser.anime_link(s.animegoID))
+	if err != nil {
+		log.Printf("Aniboom parser error : ABSession.Episodes : EpisodesInfo вернул ошибку: %v", err)
+		return nil, err
+	}
+	s.episodes = episodes
+	return episodes, nil
+}
+
+func playlist_key(translation_id string, episode int) string {
+	return fmt.Sprintf("%s:%d", translation_id, episode)
+}
+
+// Возвращает mpd файл строкой (см. GetMPDPlaylist). Полученный плейлист запоминается в сессии.
+//
+// :episode: Номер эпизода (вышедшего) (Если фильм - 0)
+//
+// :translation_id: id перевода (который именно для aniboom плеера) (можно получить из Translations)
+func (s *ABSession) Playlist(episode int, translation_id string) (string, error) {
+	if err := check_aniboom_translation("ABSession.Playlist", translation_id); err != nil {
+		return "", err
+	}
+	key := playlist_key(translation_id, episode)
+
+	s.mu.Lock()
+	if playlist, exists := s.playlists[key]; exists {
+		s.mu.Unlock()
+		return playlist, nil
+	}
+	s.mu.Unlock()
+
+	embed_link, err := s.EmbedLink()
+	if err != nil {
+		return "", err
+	}
+
+	playlist, err := s.requests().get_mpd_playlist(embed_link, translation_id, episode)
+	if err != nil {
+		log.Printf("Aniboom parser error : ABSession.Playlist : get_mpd_playlist вернул ошибку для эпизода %d. Ошибка: %v", episode, err)
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.playlists[key] = playlist
+	s.mu.Unlock()
+	return playlist, nil
+}
+
+// Параллельно загружает плейлисты для нескольких эпизодов. Загруженные плейлисты затем отдаются из Playlist без запросов.
+//
+// :translation_id: id перевода (который именно для aniboom плеера) (можно получить из Translations)
+//
+// :episodes: номера эпизодов
+//
+// Возвращает ошибки по номерам эпизодов. Эпизоды, загруженные успешно, в результат не попадают
+func (s *ABSession) Prefetch(translation_id string, episodes []int) map[int]error {
+	failed := make(map[int]error)
+
+	// Embed ссылка загружается заранее, чтобы воркеры не ждали друг друга на первом запросе
+	if _, err := s.EmbedLink(); err != nil {
+		for _, episode := range episodes {
+			failed[episode] = err
+		}
+		return failed
+	}
+
+	s.mu.Lock()
+	workers := s.workers
+	s.mu.Unlock()
+
+	jobs := make(chan int)
+	failed_mu := sync.Mutex{}
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for episode := range jobs {
+				if _, err := s.Playlist(episode, translation_id); err != nil {
+					failed_mu.Lock()
+					failed[episode] = err
+					failed_mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, episode := range episodes {
+		jobs <- episode
+	}
+	close(jobs)
+	wg.Wait()
+
+	return failed
+}
@@ -0,0 +1,63 @@
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Плеер animego, embed aniboom и mpd плейлисты эпизодов (animego_id 102, перевод 2).
+// Ответы задерживаются, чтобы все воркеры запроса успели отправить его на сервер
+func test_aniboom_sites(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(20 * time.Millisecond)
+	switch {
+	case r.URL.Host+r.URL.Path == "animego.me/anime/102/player":
+		write_test_json(w, map[string]string{"status": "success", "content": test_animego_player})
+	case r.URL.Host+r.URL.Path == "aniboom.one/embed/yxVdenrqNar":
+		dash, _ := json.Marshal(map[string]string{"src": fmt.Sprintf("https://cdn.test/%s-%s/video.mpd", r.URL.Query().Get("translation"), r.URL.Query().Get("episode"))})
+		parameters, _ := json.Marshal(map[string]string{"dash": string(dash)})
+		fmt.Fprintf(w, `<html><body><div id="video" data-parameters="%s"></div></body></html>`, html.EscapeString(string(parameters)))
+	case r.URL.Host == "cdn.test":
+		fmt.Fprint(w, `<MPD><BaseURL>video.mp4</BaseURL></MPD>`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestABSessionPlaylistRequests(test *testing.T) {
+	sites := use_test_sites(test, test_aniboom_sites)
+	ab := NewAniboomParser("animego.me")
+	episodes := []int{1, 2, 3, 4}
+
+	for _, episode := range episodes {
+		if _, err := ab.GetMPDPlaylist("102", "2", episode); err != nil {
+			test.Fatalf("GetMPDPlaylist вернул ошибку для эпизода %d: %v", episode, err)
+		}
+	}
+	single := sites.count()
+
+	session := ab.NewSession("102")
+	if failed := session.Prefetch("2", episodes); len(failed) != 0 {
+		test.Fatalf("Prefetch вернул ошибки: %v", failed)
+	}
+	playlist, err := session.Playlist(3, "2")
+	if err != nil {
+		test.Fatalf("Playlist вернул ошибку: %v", err)
+	}
+	if playlist != "<MPD><BaseURL>https://cdn.test/2-3/video.mp4</BaseURL></MPD>" {
+		test.Errorf("Playlist = %q", playlist)
+	}
+	prefetched := sites.count()
+
+	// Страница плеера один раз, затем embed и плейлист на эпизод, каждый запрос - один раз
+	if prefetched != 1+2*len(episodes) {
+		test.Errorf("сессия выполнила %d запросов для %d эпизодов, want %d", prefetched, len(episodes), 1+2*len(episodes))
+	}
+	if prefetched*2 >= single {
+		test.Errorf("сессия выполнила %d запросов, GetMPDPlaylist - %d: want меньше половины", prefetched, single)
+	}
+	test.Logf("запросов для %d эпизодов: GetMPDPlaylist %d, ABSession %d", len(episodes), single, prefetched)
+}
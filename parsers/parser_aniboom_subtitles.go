@@ -0,0 +1,341 @@
+package parsers
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Файл субтитров, найденный в плеере aniboom или в его плейлисте
+type ABSubtitle struct {
+	// Язык дорожки (прим: ru), если указан
+	Language string `json:"language"`
+	// Название дорожки, если указано
+	Label string `json:"label"`
+	// Формат файла: vtt, ass или srt
+	Format string `json:"format"`
+	URL    string `json:"url"`
+}
+
+// Возвращает формат субтитров по расширению файла в ссылке или "" если расширение не известно
+func subtitle_format(link string) string {
+	if parsed, err := url.Parse(link); err == nil {
+		link = parsed.Path
+	}
+	switch strings.ToLower(path.Ext(link)) {
+	case ".vtt", ".webvtt":
+		return t.SubtitleVTT
+	case ".ass", ".ssa":
+		return t.SubtitleASS
+	case ".srt":
+		return t.SubtitleSRT
+	}
+	return ""
+}
+
+// Приводит ссылку к абсолютной относительно base
+func resolve_link(base, link string) string {
+	if strings.HasPrefix(link, "//") {
+		return "https:" + link
+	}
+	base_url, err := url.Parse(base)
+	if err != nil {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return base_url.ResolveReference(ref).String()
+}
+
+// Возвращает json из атрибута data-parameters div#video плеера aniboom.
+//
+// :embed_link: ссылка на embed (можно получить из get_embed_link)
+//
+// :translation: id перевода (который именно для aniboom плеера) (можно получить из GetTranslationsInfo)
+//
+// :episode: Номер эпизода (вышедшего) (Если фильм - 0)
+func (ab *AniboomParser) get_embed_parameters(embed_link, translation string, episode int) (map[string]interface{}, error) {
+	embed, err := ab.get_embed(embed_link, translation, episode)
+	if err != nil {
+		error_message := fmt.Sprintf("Aniboom parser error : get_embed_parameters : get_embed вернул ошибку. Ошибка: %v", err)
+		return nil, errs.NewServiceError(error_message)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(embed))
+	if err != nil {
+		error_message := fmt.Sprintf("Aniboom parser error : get_embed_parameters : goquery не смог преобразовать ответ в документ. Ошибка: %v", err)
+		return nil, errs.NewServiceError(error_message)
+	}
+	jsonData, exists := doc.Find("div#video").First().Attr("data-parameters")
+	if !exists || jsonData == "" {
+		return nil, errs.NewServiceError(fmt.Sprintf("Aniboom parser error : get_embed_parameters : для указанного embed_link \"%s\" в div#video не найден атрибут data-parameters", embed_link))
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonData), &data); err != nil {
+		error_message := fmt.Sprintf("Aniboom parser error : get_embed_parameters : не удалось преобразовать jsonData. Ошибка: %v\njsonData: %s", err, jsonData)
+		return nil, errs.NewServiceError(error_message)
+	}
+	return data, nil
+}
+
+// Возвращает ссылку на плейлист из data-parameters (сначала dash, затем hls)
+func embed_media_src(data map[string]interface{}) string {
+	for _, key := range []string{"dash", "hls"} {
+		raw, ok := data[key].(string)
+		if !ok || raw == "" {
+			continue
+		}
+		var media map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &media); err != nil {
+			continue
+		}
+		if src, ok := media["src"].(string); ok && src != "" {
+			return src
+		}
+	}
+	return ""
+}
+
+// Ключи параметров плеера, которые не подходят как название дорожки
+var subtitle_generic_keys = map[string]bool{
+	"src": true, "url": true, "file": true, "link": true, "subtitles": true, "subtitle": true,
+	"subs": true, "tracks": true, "captions": true, "vtt": true, "ass": true, "srt": true,
+}
+
+// Рекурсивно ищет ссылки на файлы субтитров в data-parameters плеера.
+// Вложенные json строки (как dash и hls) тоже разбираются
+//
+// :label: ключ, под которым найдено значение. Используется как название дорожки, если у нее нет поля label или name (прим: {"Русские": "/ru.vtt"})
+func collect_parameter_subtitles(value interface{}, label string, found map[string]*ABSubtitle) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		language, _ := v["lang"].(string)
+		if language == "" {
+			language, _ = v["srclang"].(string)
+		}
+		name, _ := v["label"].(string)
+		if name == "" {
+			name, _ = v["name"].(string)
+		}
+		if name == "" && !subtitle_generic_keys[strings.ToLower(label)] {
+			name = label
+		}
+		for key, item := range v {
+			if str, ok := item.(string); ok && subtitle_format(str) != "" {
+				item_label := name
+				if item_label == "" && !subtitle_generic_keys[strings.ToLower(key)] {
+					item_label = key
+				}
+				if _, exists := found[str]; !exists {
+					found[str] = &ABSubtitle{Language: language, Label: item_label, Format: subtitle_format(str), URL: str}
+				}
+				continue
+			}
+			collect_parameter_subtitles(item, key, found)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collect_parameter_subtitles(item, label, found)
+		}
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			var nested interface{}
+			if err := json.Unmarshal([]byte(trimmed), &nested); err == nil {
+				collect_parameter_subtitles(nested, label, found)
+			}
+		}
+	}
+}
+
+type mpd_manifest struct {
+	Periods []struct {
+		BaseURL        string `xml:"BaseURL"`
+		AdaptationSets []struct {
+			MimeType        string `xml:"mimeType,attr"`
+			ContentType     string `xml:"contentType,attr"`
+			Lang            string `xml:"lang,attr"`
+			Label           string `xml:"label,attr"`
+			LabelElement    string `xml:"Label"`
+			BaseURL         string `xml:"BaseURL"`
+			Representations []struct {
+				MimeType string `xml:"mimeType,attr"`
+				BaseURL  string `xml:"BaseURL"`
+			} `xml:"Representation"`
+		} `xml:"AdaptationSet"`
+	} `xml:"Period"`
+}
+
+// Разбирает текстовые дорожки из mpd плейлиста (AdaptationSet с contentType="text" или mimeType text/vtt и т.п.)
+func parse_mpd_subtitles(manifest, base string) []*ABSubtitle {
+	var mpd mpd_manifest
+	if err := xml.Unmarshal([]byte(manifest), &mpd); err != nil {
+		return nil
+	}
+	result := make([]*ABSubtitle, 0)
+	for _, period := range mpd.Periods {
+		period_base := base
+		if period.BaseURL != "" {
+			period_base = resolve_link(base, strings.TrimSpace(period.BaseURL))
+		}
+		for _, set := range period.AdaptationSets {
+			if set.ContentType != "text" && !strings.HasPrefix(set.MimeType, "text/") && !strings.Contains(set.MimeType, "ttml") {
+				continue
+			}
+			label := set.Label
+			if label == "" {
+				label = set.LabelElement
+			}
+			links := make([]string, 0)
+			if set.BaseURL != "" {
+				links = append(links, strings.TrimSpace(set.BaseURL))
+			}
+			for _, representation := range set.Representations {
+				if representation.BaseURL != "" {
+					links = append(links, strings.TrimSpace(representation.BaseURL))
+				}
+			}
+			for _, link := range links {
+				absolute := resolve_link(period_base, link)
+				format := subtitle_format(absolute)
+				if format == "" && strings.Contains(set.MimeType, "vtt") {
+					format = t.SubtitleVTT
+				}
+				result = append(result, &ABSubtitle{Language: set.Lang, Label: label, Format: format, URL: absolute})
+			}
+		}
+	}
+	return result
+}
+
+// Разбирает дорожки субтитров из m3u8 плейлиста (#EXT-X-MEDIA:TYPE=SUBTITLES)
+func parse_hls_subtitles(manifest, base string) []*ABSubtitle {
+	result := make([]*ABSubtitle, 0)
+	scanner := bufio.NewScanner(strings.NewReader(manifest))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "#EXT-X-MEDIA:") || !strings.Contains(line, "TYPE=SUBTITLES") {
+			continue
+		}
+		attrs := t.ParseM3U8Attributes(strings.TrimPrefix(line, "#EXT-X-MEDIA:"))
+		if attrs["URI"] == "" {
+			continue
+		}
+		absolute := resolve_link(base, attrs["URI"])
+		format := subtitle_format(absolute)
+		if format == "" {
+			// Субтитры в HLS - это плейлист из сегментов WebVTT
+			format = t.SubtitleVTT
+		}
+		result = append(result, &ABSubtitle{Language: attrs["LANGUAGE"], Label: attrs["NAME"], Format: format, URL: absolute})
+	}
+	return result
+}
+
+// Возвращает субтитры для эпизода: ссылки из параметров плеера aniboom и текстовые дорожки из его mpd/m3u8 плейлиста.
+//
+// :episode: Номер эпизода (вышедшего) (Если фильм - 0)
+//
+// :translation_id: id перевода (который именно для aniboom плеера) (можно получить из Translations)
+//
+// Возвращает срез ссылок на ABSubtitle: сначала дорожки из параметров плеера, отсортированные по ссылке,
+// затем дорожки плейлиста в порядке плейлиста. Если субтитров нет, возвращает ошибку errs.NoResults
+func (s *ABSession) Subtitles(episode int, translation_id string) ([]*ABSubtitle, error) {
+	if err := check_aniboom_translation("ABSession.Subtitles", translation_id); err != nil {
+		return nil, err
+	}
+	embed_link, err := s.EmbedLink()
+	if err != nil {
+		return nil, err
+	}
+
+	parser := s.requests()
+	data, err := parser.get_embed_parameters(embed_link, translation_id, episode)
+	if err != nil {
+		log.Printf("Aniboom parser error : Subtitles : get_embed_parameters вернул ошибку: %v", err)
+		return nil, err
+	}
+
+	found := make(map[string]*ABSubtitle)
+	collect_parameter_subtitles(data, "", found)
+	result := make([]*ABSubtitle, 0, len(found))
+	for _, subtitle := range found {
+		subtitle.URL = resolve_link(embed_link, subtitle.URL)
+		result = append(result, subtitle)
+	}
+	// Параметры плеера разбираются из map, поэтому порядок обхода случайный
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].URL < result[j].URL
+	})
+
+	if media_src := embed_media_src(data); media_src != "" {
+		headers := models.Headers{
+			"Origin":  "https://aniboom.one",
+			"Referer": "https://aniboom.one/",
+		}
+		response, err := parser.mirrors.Request(parser.context, "GET", media_src, nil, headers, false, nil)
+		if err != nil {
+			log.Printf("Aniboom parser warning : Subtitles : не удалось загрузить плейлист %s. Ошибка: %v", media_src, err)
+		} else {
+			manifest := string(response.Data)
+			if strings.Contains(manifest, "<MPD") {
+				result = append(result, parse_mpd_subtitles(manifest, media_src)...)
+			} else {
+				result = append(result, parse_hls_subtitles(manifest, media_src)...)
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, errs.NewNoResultsError(fmt.Sprintf("Aniboom parser error : Subtitles : для animego_id %s, перевода %s и эпизода %d субтитры не найдены", s.animegoID, translation_id, episode))
+	}
+	return result, nil
+}
+
+// Возвращает субтитры для эпизода (см. ABSession.Subtitles)
+//
+// :animego_id: id аниме на animego.me
+//
+// :translation_id: id перевода (который именно для aniboom плеера) (можно получить из GetTranslationsInfo)
+//
+// :episode: Номер эпизода (вышедшего) (Если фильм - 0)
+func (ab *AniboomParser) GetSubtitles(animego_id, translation_id string, episode int) ([]*ABSubtitle, error) {
+	return ab.NewSession(animego_id).Subtitles(episode, translation_id)
+}
+
+// Загружает файл субтитров и при необходимости конвертирует его.
+//
+// :subtitle: субтитры (можно получить из GetSubtitles)
+//
+// :format: формат результата (tools.SubtitleVTT или tools.SubtitleASS). Если пустой - файл возвращается как есть
+//
+// Возвращает содержимое файла субтитров
+func (ab *AniboomParser) DownloadSubtitle(subtitle *ABSubtitle, format string) (string, error) {
+	headers := models.Headers{
+		"Origin":  "https://aniboom.one",
+		"Referer": "https://aniboom.one/",
+	}
+	response, err := ab.mirrors.Request(ab.context, "GET", subtitle.URL, nil, headers, false, nil)
+	if err != nil {
+		error_message := fmt.Sprintf("Aniboom parser error : DownloadSubtitle : RequestWithContext вернул ошибку: %v", err)
+		log.Println(error_message)
+		return "", t.WrapRequestError(err, error_message)
+	}
+	content := string(response.Data)
+	if format == "" || format == subtitle.Format {
+		return content, nil
+	}
+	return t.ConvertSubtitles(content, subtitle.Format, format)
+}
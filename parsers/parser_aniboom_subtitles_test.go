@@ -0,0 +1,149 @@
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"testing"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+func TestCollectParameterSubtitles(test *testing.T) {
+	raw := `{
+		"dash": "{\"src\":\"https://aniboom.one/v.mpd\",\"tracks\":[{\"file\":\"/s/ru.vtt\",\"srclang\":\"ru\",\"label\":\"Русские\"}]}",
+		"subtitles": {"Английские": "/s/en.ass", "src": "/s/default.srt"},
+		"captions": [{"url": "/s/ja.vtt", "lang": "ja"}]
+	}`
+	var data interface{}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		test.Fatal(err)
+	}
+	found := make(map[string]*ABSubtitle)
+	collect_parameter_subtitles(data, "", found)
+
+	want := map[string]ABSubtitle{
+		"/s/ru.vtt":      {Language: "ru", Label: "Русские", Format: "vtt", URL: "/s/ru.vtt"},
+		"/s/en.ass":      {Label: "Английские", Format: "ass", URL: "/s/en.ass"},
+		"/s/default.srt": {Format: "srt", URL: "/s/default.srt"},
+		"/s/ja.vtt":      {Language: "ja", Format: "vtt", URL: "/s/ja.vtt"},
+	}
+	if len(found) != len(want) {
+		test.Fatalf("найдено %d субтитров, want %d: %v", len(found), len(want), found)
+	}
+	for link, subtitle := range want {
+		if got := found[link]; got == nil || *got != subtitle {
+			test.Errorf("субтитры %s = %+v, want %+v", link, got, subtitle)
+		}
+	}
+}
+
+const test_mpd_subtitles = `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011">
+<Period>
+<AdaptationSet contentType="video" mimeType="video/mp4"><Representation mimeType="video/mp4"><BaseURL>video.mp4</BaseURL></Representation></AdaptationSet>
+<AdaptationSet contentType="text" lang="ru" label="Русские"><BaseURL>subs/ru.vtt</BaseURL></AdaptationSet>
+<AdaptationSet mimeType="text/vtt" lang="en"><Label>English</Label><Representation mimeType="text/vtt"><BaseURL>https://cdn.test/subs/en</BaseURL></Representation></AdaptationSet>
+</Period>
+</MPD>`
+
+func TestParseMPDSubtitles(test *testing.T) {
+	got := parse_mpd_subtitles(test_mpd_subtitles, "https://cdn.test/2-1/video.mpd")
+	want := []ABSubtitle{
+		{Language: "ru", Label: "Русские", Format: "vtt", URL: "https://cdn.test/2-1/subs/ru.vtt"},
+		{Language: "en", Label: "English", Format: "vtt", URL: "https://cdn.test/subs/en"},
+	}
+	if len(got) != len(want) {
+		test.Fatalf("parse_mpd_subtitles = %v, want %d дорожки", got, len(want))
+	}
+	for i := range want {
+		if *got[i] != want[i] {
+			test.Errorf("дорожка %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if got := parse_mpd_subtitles("не xml", ""); len(got) != 0 {
+		test.Errorf("parse_mpd_subtitles для неверного xml = %v", got)
+	}
+}
+
+func TestParseHLSSubtitles(test *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="Русский",URI="audio/ru.m3u8"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",LANGUAGE="ru",NAME="Русские",URI="subs/ru.m3u8"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",LANGUAGE="en",NAME="English, full",URI="https://cdn.test/subs/en.srt"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",LANGUAGE="ja",NAME="日本語"
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,SUBTITLES="subs"
+media_720.m3u8`
+	got := parse_hls_subtitles(manifest, "https://cdn.test/2-1/master_device.m3u8")
+	want := []ABSubtitle{
+		{Language: "ru", Label: "Русские", Format: "vtt", URL: "https://cdn.test/2-1/subs/ru.m3u8"},
+		{Language: "en", Label: "English, full", Format: "srt", URL: "https://cdn.test/subs/en.srt"},
+	}
+	if len(got) != len(want) {
+		test.Fatalf("parse_hls_subtitles = %v, want %d дорожки", got, len(want))
+	}
+	for i := range want {
+		if *got[i] != want[i] {
+			test.Errorf("дорожка %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// Плеер animego и embed aniboom с субтитрами в параметрах плеера и в mpd плейлисте
+func test_aniboom_subtitles_sites(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Host + r.URL.Path {
+	case "animego.me/anime/102/player":
+		write_test_json(w, map[string]string{"status": "success", "content": test_animego_player})
+	case "aniboom.one/embed/yxVdenrqNar":
+		dash, _ := json.Marshal(map[string]string{"src": "https://cdn.test/2-1/video.mpd"})
+		parameters, _ := json.Marshal(map[string]any{
+			"dash":      string(dash),
+			"subtitles": map[string]string{"Русские": "/s/ru.vtt", "English": "/s/en.ass", "Deutsch": "/s/de.srt"},
+		})
+		fmt.Fprintf(w, `<html><body><div id="video" data-parameters="%s"></div></body></html>`, html.EscapeString(string(parameters)))
+	case "cdn.test/2-1/video.mpd":
+		fmt.Fprint(w, test_mpd_subtitles)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestABSessionSubtitles(test *testing.T) {
+	sites := use_test_sites(test, test_aniboom_subtitles_sites)
+	ab := NewAniboomParser("animego.me")
+
+	want := []ABSubtitle{
+		{Label: "Deutsch", Format: "srt", URL: "https://aniboom.one/s/de.srt"},
+		{Label: "English", Format: "ass", URL: "https://aniboom.one/s/en.ass"},
+		{Label: "Русские", Format: "vtt", URL: "https://aniboom.one/s/ru.vtt"},
+		{Language: "ru", Label: "Русские", Format: "vtt", URL: "https://cdn.test/2-1/subs/ru.vtt"},
+		{Language: "en", Label: "English", Format: "vtt", URL: "https://cdn.test/subs/en"},
+	}
+	// Порядок дорожек одинаковый при каждом вызове
+	for call := 0; call < 5; call++ {
+		got, err := ab.NewSession("102").Subtitles(1, "2")
+		if err != nil {
+			test.Fatalf("Subtitles вернул ошибку: %v", err)
+		}
+		if len(got) != len(want) {
+			test.Fatalf("Subtitles = %v, want %d дорожек", got, len(want))
+		}
+		for i := range want {
+			if *got[i] != want[i] {
+				test.Errorf("вызов %d: дорожка %d = %+v, want %+v", call, i, got[i], want[i])
+			}
+		}
+	}
+
+	// Перевод только kodik (пустой TranslationID) отклоняется без запросов к сайтам
+	sites.count()
+	if _, err := ab.NewSession("102").Subtitles(1, ""); err == nil {
+		test.Error("Subtitles без id перевода aniboom должен вернуть ошибку")
+	} else if _, ok := err.(*errs.PostArgumentsError); !ok {
+		test.Errorf("Subtitles без id перевода aniboom вернул %T, want *errs.PostArgumentsError", err)
+	}
+	if count := sites.count(); count != 0 {
+		test.Errorf("Subtitles без id перевода aniboom выполнил %d запросов", count)
+	}
+}
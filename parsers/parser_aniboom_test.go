@@ -0,0 +1,71 @@
+package parsers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+// Плеер animego: перевод AniLibria есть в aniboom и kodik, субтитры - только в kodik
+const test_animego_player = `<div id="video-dubbing">
+<span class="video-player-toggle-item" data-dubbing="1">AniLibria</span>
+<span class="video-player-toggle-item" data-dubbing="2">Субтитры</span>
+</div>
+<div id="video-players">
+<span class="video-player-toggle-item" data-provide-dubbing="1" data-provider="24" data-player="//aniboom.one/embed/yxVdenrqNar?episode=&amp;translation=2">AniBoom</span>
+<span class="video-player-toggle-item" data-provide-dubbing="1" data-provider="29" data-player="//kodik.info/serial/1/hash/720p?translations=false">Kodik</span>
+<span class="video-player-toggle-item" data-provide-dubbing="2" data-provider="29" data-player="//kodik.info/serial/2/hash/720p?translations=false">Kodik</span>
+</div>`
+
+func TestParseTranslationsProvider(test *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(test_animego_player))
+	if err != nil {
+		test.Fatal(err)
+	}
+	translations, err := parse_translations(doc, "102")
+	if err != nil {
+		test.Fatalf("parse_translations вернул ошибку: %v", err)
+	}
+	if len(translations) != 2 {
+		test.Fatalf("parse_translations вернул %d переводов, want 2", len(translations))
+	}
+	if dub := translations[0]; dub.Provider != "aniboom" || dub.TranslationID != "2" || len(dub.Players) != 2 {
+		test.Errorf("translations[0] = %+v", dub)
+	}
+	if sub := translations[1]; sub.Provider != "kodik" || sub.TranslationID != "" || sub.Kind != TranslationSubtitles {
+		test.Errorf("translations[1] = %+v", sub)
+	}
+}
+
+func TestAniboomTranslationsOnlyAniboom(test *testing.T) {
+	sites := use_test_sites(test, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host+r.URL.Path == "animego.me/anime/102/player" {
+			write_test_json(w, map[string]string{"status": "success", "content": test_animego_player})
+			return
+		}
+		http.NotFound(w, r)
+	})
+	ab := NewAniboomParser("animego.me")
+
+	translations, err := ab.Translations("102")
+	if err != nil {
+		test.Fatalf("Translations вернул ошибку: %v", err)
+	}
+	if len(translations) != 1 || translations[0].ID != "2" || translations[0].Name != "AniLibria" {
+		test.Errorf("Translations = %+v", translations)
+	}
+
+	// Перевод только kodik (пустой TranslationID) отклоняется без запросов к сайтам
+	sites.count()
+	if _, err := ab.GetMPDPlaylist("102", "", 1); err == nil {
+		test.Error("GetMPDPlaylist без id перевода aniboom должен вернуть ошибку")
+	} else if _, ok := err.(*errs.PostArgumentsError); !ok {
+		test.Errorf("GetMPDPlaylist без id перевода aniboom вернул %T, want *errs.PostArgumentsError", err)
+	}
+	if count := sites.count(); count != 0 {
+		test.Errorf("GetMPDPlaylist без id перевода aniboom выполнил %d запросов", count)
+	}
+}
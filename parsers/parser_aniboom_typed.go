@@ -0,0 +1,125 @@
+package parsers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Quavke/AnimeParsersGo/models"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Типизированная версия FastSearchResult. Исходные строки доступны в Raw
+type TypedFastSearchResult struct {
+	Raw       *FastSearchResult `json:"raw"`
+	Year      int               `json:"year"`
+	Kind      models.AnimeKind  `json:"kind"`
+	AnimegoID int               `json:"animego_id"`
+}
+
+// Типизированная версия EpisodeInfo. Исходные строки доступны в Raw
+type TypedEpisodeInfo struct {
+	Raw *EpisodeInfo `json:"raw"`
+	// 0, если номер эпизода не числовой
+	Num int `json:"num"`
+	// Нулевое время, если дата не указана или не распознана
+	Date   time.Time            `json:"date"`
+	Status models.EpisodeStatus `json:"status"`
+}
+
+// Типизированная версия OtherAnimeInfo. Исходные строки доступны в Raw
+type TypedOtherAnimeInfo struct {
+	Raw *OtherAnimeInfo `json:"raw"`
+	// Минимальный возраст (прим: "16+" > 16)
+	AgeRestriction int `json:"age_restriction"`
+	// Начало и конец выпуска. Нулевое время, если не указано
+	ReleaseStart time.Time `json:"release_start"`
+	ReleaseEnd   time.Time `json:"release_end"`
+	// Длительность одного эпизода
+	Duration   time.Duration     `json:"duration"`
+	MPAARating models.MPAARating `json:"mpaa_rating"`
+	SeasonYear int               `json:"season_year"`
+}
+
+// Типизированная версия ABSearchResult. Исходные строки доступны в Raw
+type TypedABSearchResult struct {
+	Raw    *ABSearchResult    `json:"raw"`
+	Status models.AnimeStatus `json:"status"`
+	Kind   models.AnimeKind   `json:"kind"`
+	// Вышедшие и всего эпизодов (прим: "5 / 12" > 5 и 12, "12" > 12 и 12). 0, если не указано
+	EpisodesAired int                  `json:"episodes_aired"`
+	EpisodesTotal int                  `json:"episodes_total"`
+	Year          int                  `json:"year"`
+	AnimegoID     int                  `json:"animego_id"`
+	EpisodesInfo  []*TypedEpisodeInfo  `json:"episodes_info"`
+	OtherInfo     *TypedOtherAnimeInfo `json:"other_info"`
+}
+
+// Преобразует FastSearchResult в TypedFastSearchResult
+func (r *FastSearchResult) Typed() *TypedFastSearchResult {
+	animego_id, _ := strconv.Atoi(r.AnimegoID)
+	return &TypedFastSearchResult{
+		Raw:       r,
+		Year:      t.ParseYear(r.Year),
+		Kind:      models.ParseAnimeKind(r.Type),
+		AnimegoID: animego_id,
+	}
+}
+
+// Преобразует EpisodeInfo в TypedEpisodeInfo
+func (e *EpisodeInfo) Typed() *TypedEpisodeInfo {
+	num, _ := strconv.Atoi(strings.TrimSpace(e.Num))
+	date, _ := t.ParseRussianDate(e.Date)
+	return &TypedEpisodeInfo{
+		Raw:    e,
+		Num:    num,
+		Date:   date,
+		Status: models.ParseEpisodeStatus(e.Status),
+	}
+}
+
+// Преобразует OtherAnimeInfo в TypedOtherAnimeInfo
+func (o *OtherAnimeInfo) Typed() *TypedOtherAnimeInfo {
+	res := &TypedOtherAnimeInfo{
+		Raw:        o,
+		MPAARating: models.ParseMPAARating(o.MPAARating),
+		SeasonYear: t.ParseYear(o.Season),
+	}
+	if numbers := t.ParseNumbers(o.AgeRests); len(numbers) > 0 {
+		res.AgeRestriction = numbers[0]
+	}
+	res.ReleaseStart, res.ReleaseEnd, _ = t.ParseRussianDateRange(o.ReleaseDate)
+	res.Duration, _ = t.ParseRussianDuration(o.Duration)
+	return res
+}
+
+// Преобразует ABSearchResult в TypedABSearchResult
+func (r *ABSearchResult) Typed() *TypedABSearchResult {
+	animego_id, _ := strconv.Atoi(r.AnimegoID)
+	res := &TypedABSearchResult{
+		Raw:          r,
+		Status:       models.ParseAnimeStatus(r.Status),
+		Kind:         models.ParseAnimeKind(r.Type),
+		AnimegoID:    animego_id,
+		EpisodesInfo: make([]*TypedEpisodeInfo, 0, len(r.EpisodesInfo)),
+	}
+
+	if numbers := t.ParseNumbers(r.Episodes); len(numbers) > 0 {
+		res.EpisodesAired = numbers[0]
+		res.EpisodesTotal = numbers[len(numbers)-1]
+	}
+
+	for _, episode := range r.EpisodesInfo {
+		res.EpisodesInfo = append(res.EpisodesInfo, episode.Typed())
+	}
+
+	if r.OtherInfo != nil {
+		res.OtherInfo = r.OtherInfo.Typed()
+		if !res.OtherInfo.ReleaseStart.IsZero() {
+			res.Year = res.OtherInfo.ReleaseStart.Year()
+		} else {
+			res.Year = res.OtherInfo.SeasonYear
+		}
+	}
+	return res
+}
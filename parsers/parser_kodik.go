@@ -1 +1,304 @@
 package parsers
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Quavke/AnimeParsersGo/api"
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+	"github.com/Quavke/AnimeParsersGo/titles"
+)
+
+// Типы сериалов kodik с длиной серии (прим: tv_13, tv_24) приводятся к tv
+var kodik_tv_kind_re = regexp.MustCompile(`^tv_\d+$`)
+
+// Парсер kodik. Данные берутся из API kodik (см. api.KodikClient), видео отдается embed ссылкой на плеер kodik
+type KodikParser struct {
+	client *api.KodikClient
+}
+
+// :token: токен API kodik (пустая строка - токен публичного плеера kodik)
+func NewKodikParser(token string) *KodikParser {
+	return NewKodikParserWithClient(api.NewKodikClient(token))
+}
+
+// Создает парсер с готовым клиентом API (прим: с другим BaseURL или HTTPClient)
+func NewKodikParserWithClient(client *api.KodikClient) *KodikParser {
+	return &KodikParser{client: client}
+}
+
+// Возвращает клиент API парсера
+func (kp *KodikParser) Client() *api.KodikClient {
+	return kp.client
+}
+
+// Ссылка на плеер kodik с протоколом (API возвращает ссылки вида //kodik.info/...)
+func kodik_link(link string) string {
+	link = strings.TrimSpace(link)
+	if strings.HasPrefix(link, "//") {
+		return "https:" + link
+	}
+	return link
+}
+
+// Ключ аниме для группировки материалов kodik: shikimori_id, иначе kinopoisk_id, иначе оригинальное название и год
+func kodik_key(result *api.KodikResult) string {
+	switch {
+	case result.ShikimoriID != "":
+		return "shikimori:" + result.ShikimoriID
+	case result.KinopoiskID != "":
+		return "kinopoisk:" + result.KinopoiskID
+	}
+	title := result.TitleOrig
+	if title == "" {
+		title = result.Title
+	}
+	return fmt.Sprintf("title:%s:%d", titles.Key(title), result.Year)
+}
+
+// Группирует материалы kodik по аниме (каждый перевод аниме в kodik - отдельный материал), сохраняя порядок выдачи
+func kodik_group(results []*api.KodikResult) [][]*api.KodikResult {
+	groups := make([][]*api.KodikResult, 0)
+	index := make(map[string]int)
+	for _, result := range results {
+		key := kodik_key(result)
+		if i, exists := index[key]; exists {
+			groups[i] = append(groups[i], result)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, []*api.KodikResult{result})
+	}
+	return groups
+}
+
+func kodik_kind(result *api.KodikResult) models.AnimeKind {
+	if result.MaterialData != nil && result.MaterialData.AnimeKind != "" {
+		kind := strings.ToLower(result.MaterialData.AnimeKind)
+		if kodik_tv_kind_re.MatchString(kind) {
+			return models.AnimeKindTV
+		}
+		return models.ParseAnimeKind(kind)
+	}
+	switch result.Type {
+	case "anime":
+		return models.AnimeKindMovie
+	case "anime-serial":
+		return models.AnimeKindTV
+	}
+	return models.AnimeKindUnknown
+}
+
+func append_title(other_titles []string, seen map[string]bool, title string) []string {
+	title = strings.TrimSpace(title)
+	if title == "" || seen[title] {
+		return other_titles
+	}
+	seen[title] = true
+	return append(other_titles, title)
+}
+
+// Преобразует материалы одного аниме (переводы) в models.Anime. Ссылка - плеер первого материала
+func kodik_anime(group []*api.KodikResult) *models.Anime {
+	first := group[0]
+	anime := &models.Anime{
+		Source:        models.SourceKodik,
+		Link:          kodik_link(first.Link),
+		Title:         strings.TrimSpace(first.Title),
+		OriginalTitle: strings.TrimSpace(first.TitleOrig),
+		OtherTitles:   make([]string, 0),
+		Kind:          kodik_kind(first),
+		Year:          first.Year,
+		Studios:       make([]string, 0),
+		Genres:        make([]models.Genre, 0),
+		UnknownGenres: make([]string, 0),
+	}
+	seen := map[string]bool{anime.Title: true, anime.OriginalTitle: true}
+	for _, result := range group {
+		if anime.ShikimoriID == "" {
+			anime.ShikimoriID = result.ShikimoriID
+		}
+		if anime.KinopoiskID == "" {
+			anime.KinopoiskID = result.KinopoiskID
+		}
+		anime.EpisodesAired = max(anime.EpisodesAired, result.LastEpisode)
+		for _, title := range strings.Split(result.OtherTitle, " / ") {
+			anime.OtherTitles = append_title(anime.OtherTitles, seen, title)
+		}
+	}
+
+	data := first.MaterialData
+	if data == nil {
+		return anime
+	}
+	for _, title := range append(append(append([]string{data.TitleEn}, data.OtherTitlesEn...), data.OtherTitlesJp...), data.OtherTitles...) {
+		anime.OtherTitles = append_title(anime.OtherTitles, seen, title)
+	}
+	anime.Status = models.ParseAnimeStatus(data.AnimeStatus)
+	anime.Rating = models.ParseMPAARating(data.RatingMPAA)
+	if data.Year != 0 {
+		anime.Year = data.Year
+	}
+	if data.EpisodesTotal != 0 {
+		anime.Episodes = data.EpisodesTotal
+	}
+	if data.EpisodesAired != 0 {
+		anime.EpisodesAired = data.EpisodesAired
+	}
+	anime.EpisodeDuration = time.Duration(data.Duration) * time.Minute
+	anime.Studios = append(anime.Studios, data.AnimeStudios...)
+	anime.Genres, anime.UnknownGenres = models.ParseGenres(data.AnimeGenres)
+	anime.Description = strings.TrimSpace(data.AnimeDescription)
+	if anime.Description == "" {
+		anime.Description = strings.TrimSpace(data.Description)
+	}
+	anime.Poster = data.AnimePosterURL
+	if anime.Poster == "" {
+		anime.Poster = data.PosterURL
+	}
+	anime.Score = data.ShikimoriRating
+	return anime
+}
+
+func (kp *KodikParser) Name() string {
+	return models.SourceKodik
+}
+
+// Поиск аниме по названию. Переводы одного аниме объединяются в один результат
+func (kp *KodikParser) SearchAnime(title string) ([]*models.Anime, error) {
+	results, err := kp.client.Search(api.KodikSearchParams{Title: title, WithMaterialData: true})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.Anime, 0)
+	for _, group := range kodik_group(results) {
+		res = append(res, kodik_anime(group))
+	}
+	return res, nil
+}
+
+// Данные об аниме по ссылке на плеер kodik (прим: //kodik.info/serial/12345/hash/720p)
+func (kp *KodikParser) Info(link string) (*models.Anime, error) {
+	results, err := kp.client.Search(api.KodikSearchParams{PlayerLink: player_base_link(link), WithMaterialData: true})
+	if err != nil {
+		return nil, err
+	}
+	anime := kodik_anime(kodik_group(results)[0])
+	anime.Link = kodik_link(link)
+	return anime, nil
+}
+
+// Материалы аниме по id на shikimori
+func (kp *KodikParser) by_shikimori_id(method, shikimori_id string, translation_id int) ([]*api.KodikResult, error) {
+	if strings.TrimSpace(shikimori_id) == "" {
+		error_message := fmt.Sprintf("Kodik parser error : %s : не указан shikimori_id", method)
+		log.Println(error_message)
+		return nil, errs.NewPostArgumentsError(error_message)
+	}
+	return kp.client.Search(api.KodikSearchParams{ShikimoriID: shikimori_id, TranslationID: translation_id})
+}
+
+// Переводы аниме. ID - id перевода kodik, который передается в Stream
+//
+// :shikimori_id: id аниме на shikimori (kodik ищет аниме по нему)
+func (kp *KodikParser) Translations(shikimori_id string) ([]*models.Translation, error) {
+	results, err := kp.by_shikimori_id("Translations", shikimori_id, 0)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.Translation, 0, len(results))
+	seen := make(map[int]bool)
+	for _, result := range results {
+		if seen[result.Translation.ID] {
+			continue
+		}
+		seen[result.Translation.ID] = true
+		kind := TranslationDub
+		if result.Translation.Type == api.KodikSubtitles {
+			kind = TranslationSubtitles
+		}
+		res = append(res, &models.Translation{
+			ID:   strconv.Itoa(result.Translation.ID),
+			Name: strings.TrimSpace(result.Translation.Title),
+			Kind: kind,
+		})
+	}
+	return res, nil
+}
+
+// Embed ссылка на плеер kodik с выбранным эпизодом и переводом.
+//
+// :shikimori_id: id аниме на shikimori
+//
+// :translation_id: id перевода kodik (models.Translation.ID из Translations)
+//
+// :episode: Номер эпизода (вышедшего) (Если фильм - 0)
+func (kp *KodikParser) Stream(shikimori_id, translation_id string, episode int) (*models.Stream, error) {
+	id, err := strconv.Atoi(strings.TrimSpace(translation_id))
+	if err != nil {
+		error_message := fmt.Sprintf("Kodik parser error : Stream : неверный id перевода %q", translation_id)
+		log.Println(error_message)
+		return nil, errs.NewPostArgumentsError(error_message)
+	}
+	results, err := kp.by_shikimori_id("Stream", shikimori_id, id)
+	if err != nil {
+		return nil, err
+	}
+	return kodik_stream("Stream", results[0], results[0].Link, episode)
+}
+
+// Embed ссылка на плеер kodik (прим: из плеера animego) с выбранным эпизодом.
+// Плеер ищется в API kodik, чтобы проверить, что он существует и эпизод в нем уже вышел.
+// Параметры ссылки (прим: only_translations) сохраняются
+//
+// :link: ссылка на плеер kodik (прим: //kodik.info/serial/12345/hash/720p?translations=false)
+//
+// :episode: Номер эпизода (вышедшего) (Если фильм - 0)
+func (kp *KodikParser) PlayerStream(link string, episode int) (*models.Stream, error) {
+	results, err := kp.client.Search(api.KodikSearchParams{PlayerLink: player_base_link(link)})
+	if err != nil {
+		return nil, err
+	}
+	return kodik_stream("PlayerStream", results[0], link, episode)
+}
+
+// Ссылка на плеер без параметров и протокола (в таком виде ее принимает поиск kodik)
+func player_base_link(link string) string {
+	link = strings.TrimSpace(link)
+	if index := strings.IndexAny(link, "?#"); index != -1 {
+		link = link[:index]
+	}
+	if index := strings.Index(link, "//"); index != -1 {
+		link = link[index:]
+	}
+	return link
+}
+
+func kodik_stream(method string, result *api.KodikResult, link string, episode int) (*models.Stream, error) {
+	if episode != 0 && result.LastEpisode != 0 && episode > result.LastEpisode {
+		error_message := fmt.Sprintf("Kodik parser error : %s : в плеере %s вышло %d эпизодов, эпизод %d не найден", method, result.Link, result.LastEpisode, episode)
+		log.Println(error_message)
+		return nil, errs.NewNoResultsError(error_message)
+	}
+	parsed, err := url.Parse(kodik_link(link))
+	if err != nil {
+		error_message := fmt.Sprintf("Kodik parser error : %s : не удалось разобрать ссылку на плеер %s. Ошибка: %v", method, link, err)
+		log.Println(error_message)
+		return nil, errs.NewServiceError(error_message)
+	}
+	if episode != 0 {
+		query := parsed.Query()
+		query.Set("episode", strconv.Itoa(episode))
+		parsed.RawQuery = query.Encode()
+	}
+	return &models.Stream{
+		Kind: ABStreamEmbed,
+		URL:  parsed.String(),
+	}, nil
+}
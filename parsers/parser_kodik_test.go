@@ -0,0 +1,201 @@
+package parsers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Quavke/AnimeParsersGo/api"
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+)
+
+// Материалы тестового сервера kodik: два перевода Naruto, фильм без shikimori_id и Steins;Gate, которого нет на animego
+var test_kodik_materials = []map[string]any{
+	{
+		"id": "serial-1", "type": "anime-serial", "link": "//kodik.info/serial/1/hash/720p",
+		"title": "Наруто", "title_orig": "Naruto", "other_title": "ナルト / Naruto TV",
+		"translation": map[string]any{"id": 610, "title": "AniLibria.TV", "type": "voice"},
+		"year":        2002, "last_episode": 220, "episodes_count": 220, "shikimori_id": "20", "kinopoisk_id": "420337",
+		"material_data": map[string]any{
+			"anime_kind": "tv_24", "anime_status": "released", "title_en": "Naruto", "year": 2002,
+			"episodes_total": 220, "episodes_aired": 220, "duration": 23, "shikimori_rating": 8.0, "rating_mpaa": "PG-13",
+			"anime_genres": []string{"Экшен"}, "anime_studios": []string{"Studio Pierrot"}, "anime_description": "Описание",
+			"anime_poster_url": "https://shikimori.one/naruto.jpg",
+		},
+	},
+	{
+		"id": "serial-2", "type": "anime-serial", "link": "//kodik.info/serial/2/hash/720p",
+		"title": "Наруто", "title_orig": "Naruto",
+		"translation": map[string]any{"id": 869, "title": "Субтитры", "type": "subtitles"},
+		"year":        2002, "last_episode": 12, "shikimori_id": "20",
+	},
+	{
+		"id": "movie-3", "type": "anime", "link": "//kodik.info/video/3/hash/720p",
+		"title": "Наруто: Фильм", "title_orig": "Naruto the Movie",
+		"translation": map[string]any{"id": 610, "title": "AniLibria.TV", "type": "voice"},
+		"year":        2004,
+	},
+	{
+		"id": "serial-4", "type": "anime-serial", "link": "//kodik.info/serial/4/hash/720p",
+		"title": "Врата Штейна", "title_orig": "Steins;Gate",
+		"translation": map[string]any{"id": 610, "title": "AniLibria.TV", "type": "voice"},
+		"year":        2011, "last_episode": 24, "shikimori_id": "9253", "kinopoisk_id": "580245",
+	},
+}
+
+// Тестовый сервер kodik: фильтрует test_kodik_materials по параметрам поиска
+type test_kodik struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func new_test_kodik(test *testing.T) (*test_kodik, *KodikParser) {
+	s := &test_kodik{}
+	server := httptest.NewServer(s)
+	test.Cleanup(server.Close)
+
+	client := api.NewKodikClient("test-token")
+	client.BaseURL = server.URL
+	client.HTTPClient = server.Client()
+	return s, NewKodikParserWithClient(client)
+}
+
+// Параметры запросов к серверу с последнего вызова
+func (s *test_kodik) calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := s.requests
+	s.requests = nil
+	return calls
+}
+
+func (s *test_kodik) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	query.Del("token")
+	s.mu.Lock()
+	s.requests = append(s.requests, query.Encode())
+	s.mu.Unlock()
+
+	results := make([]map[string]any, 0)
+	for _, material := range test_kodik_materials {
+		translation := material["translation"].(map[string]any)
+		switch {
+		case query.Get("title") != "" && !strings.Contains(strings.ToLower(material["title_orig"].(string)), strings.ToLower(query.Get("title"))):
+		case query.Get("shikimori_id") != "" && material["shikimori_id"] != query.Get("shikimori_id"):
+		case query.Get("kinopoisk_id") != "" && material["kinopoisk_id"] != query.Get("kinopoisk_id"):
+		case query.Get("player_link") != "" && material["link"] != query.Get("player_link"):
+		case query.Get("translation_id") != "" && strconv.Itoa(translation["id"].(int)) != query.Get("translation_id"):
+		default:
+			result := make(map[string]any)
+			for key, value := range material {
+				if key != "material_data" || query.Get("with_material_data") == "true" {
+					result[key] = value
+				}
+			}
+			results = append(results, result)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"total": len(results), "results": results})
+}
+
+func TestKodikSearchAnime(test *testing.T) {
+	_, kp := new_test_kodik(test)
+	found, err := kp.SearchAnime("naruto")
+	if err != nil {
+		test.Fatalf("SearchAnime вернул ошибку: %v", err)
+	}
+	if len(found) != 2 {
+		test.Fatalf("SearchAnime вернул %d аниме, want 2 (переводы одного аниме объединяются)", len(found))
+	}
+
+	naruto := found[0]
+	if naruto.Source != models.SourceKodik || naruto.Link != "https://kodik.info/serial/1/hash/720p" || naruto.Title != "Наруто" ||
+		naruto.OriginalTitle != "Naruto" || naruto.ShikimoriID != "20" || naruto.KinopoiskID != "420337" {
+		test.Errorf("SearchAnime[0] = %+v", naruto)
+	}
+	if naruto.Kind != models.AnimeKindTV || naruto.Status != models.AnimeStatusReleased || naruto.Rating != models.MPAARatingPG13 ||
+		naruto.Episodes != 220 || naruto.EpisodeDuration.Minutes() != 23 || naruto.Score != 8 || naruto.Poster == "" {
+		test.Errorf("SearchAnime[0] material_data = %+v", naruto)
+	}
+	if strings.Join(naruto.OtherTitles, "|") != "ナルト|Naruto TV" {
+		test.Errorf("OtherTitles = %q", naruto.OtherTitles)
+	}
+	if movie := found[1]; movie.Kind != models.AnimeKindMovie || movie.ShikimoriID != "" || movie.Year != 2004 {
+		test.Errorf("SearchAnime[1] = %+v", movie)
+	}
+}
+
+func TestKodikInfo(test *testing.T) {
+	_, kp := new_test_kodik(test)
+	anime, err := kp.Info("https://kodik.info/serial/2/hash/720p?translations=false")
+	if err != nil {
+		test.Fatalf("Info вернул ошибку: %v", err)
+	}
+	if anime.ShikimoriID != "20" || anime.Link != "https://kodik.info/serial/2/hash/720p?translations=false" {
+		test.Errorf("Info = %+v", anime)
+	}
+}
+
+func TestKodikTranslationsAndStream(test *testing.T) {
+	server, kp := new_test_kodik(test)
+	translations, err := kp.Translations("20")
+	if err != nil {
+		test.Fatalf("Translations вернул ошибку: %v", err)
+	}
+	if len(translations) != 2 || *translations[0] != (models.Translation{ID: "610", Name: "AniLibria.TV", Kind: TranslationDub}) ||
+		*translations[1] != (models.Translation{ID: "869", Name: "Субтитры", Kind: TranslationSubtitles}) {
+		test.Errorf("Translations = %+v, %+v", translations[0], translations[1])
+	}
+
+	server.calls()
+	stream, err := kp.Stream("20", "869", 5)
+	if err != nil {
+		test.Fatalf("Stream вернул ошибку: %v", err)
+	}
+	if stream.Kind != ABStreamEmbed || stream.URL != "https://kodik.info/serial/2/hash/720p?episode=5" {
+		test.Errorf("Stream = %+v", stream)
+	}
+	if calls := server.calls(); len(calls) != 1 || calls[0] != "shikimori_id=20&translation_id=869&types=anime%2Canime-serial" {
+		test.Errorf("Stream запросы: %v", calls)
+	}
+
+	if _, err := kp.Stream("20", "869", 13); err == nil {
+		test.Error("Stream для невышедшего эпизода должен вернуть ошибку")
+	} else if _, ok := err.(*errs.NoResults); !ok {
+		test.Errorf("Stream для невышедшего эпизода вернул %T, want *errs.NoResults", err)
+	}
+	if _, err := kp.Stream("20", "anilibria", 1); err == nil {
+		test.Error("Stream с неверным id перевода должен вернуть ошибку")
+	}
+}
+
+func TestKodikPlayerStream(test *testing.T) {
+	_, kp := new_test_kodik(test)
+	stream, err := kp.PlayerStream("//kodik.info/serial/1/hash/720p?translations=false", 3)
+	if err != nil {
+		test.Fatalf("PlayerStream вернул ошибку: %v", err)
+	}
+	if stream.URL != "https://kodik.info/serial/1/hash/720p?episode=3&translations=false" {
+		test.Errorf("PlayerStream = %+v", stream)
+	}
+	if _, err := kp.PlayerStream("//kodik.info/serial/404/hash/720p", 1); err == nil {
+		test.Error("PlayerStream для неизвестного плеера должен вернуть ошибку")
+	}
+}
+
+func TestKodikSourceRegistered(test *testing.T) {
+	source, err := NewSource("Kodik", "kodik.example")
+	if err != nil {
+		test.Fatalf("NewSource вернул ошибку: %v", err)
+	}
+	kp, ok := source.(*KodikParser)
+	if !ok || kp.Client().BaseURL != "https://kodik.example" {
+		test.Errorf("NewSource(kodik) = %#v", source)
+	}
+}
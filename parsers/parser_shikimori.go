@@ -18,35 +18,80 @@ import (
 
 var genres_list = []string{"1-Action", "2-Adventure", "3-Racing", "4-Comedy", "5-Avant-Garde", "6-Mythology", "7-Mystery", "8-Drama", "9-Ecchi", "10-Fantasy", "11-Strategy-Game", "13-Historical", "14-Horror", "15-Kids", "17-Martial-Arts", "18-Mecha", "19-Music", "20-Parody", "21-Samurai", "22-Romance", "23-School", "24-Sci-Fi", "25-Shoujo", "27-Shounen", "29-Space", "30-Sports", "31-Super-Power", "32-Vampire", "35-Harem", "36-Slice-of-Life", "37-Supernatural", "38-Military", "39-Detective", "40-Psychological", "42-Seinen", "43-Josei", "102-Team-Sports", "103-Video-Game", "104-Adult-Cast", "105-Gore", "106-Reincarnation", "107-Love-Polygon", "108-Visual-Arts", "111-Time-Travel", "112-Gag-Humor", "114-Award-Winning", "117-Suspense", "118-Combat-Sports", "119-CGDCT", "124-Mahou-Shoujo", "125-Reverse-Harem", "130-Isekai", "131-Delinquents", "134-Childcare", "135-Magical-Sex-Shift", "136-Showbiz", "137-Otaku-Culture", "138-Organized-Crime", "139-Workplace", "140-Iyashikei", "141-Survival", "142-Performing-Arts", "143-Anthropomorphic", "144-Crossdressing", "145-Idols-(Female)", "146-High-Stakes-Game", "147-Medical", "148-Pets", "149-Educational", "150-Idols-(Male)", "151-Romantic-Subtext", "543-Gourmet"}
 
+// Зеркала shikimori по умолчанию в порядке приоритета
+var shikimoriMirrors = []string{"shikimori.one", "shikimori.me"}
+
 type ShikimoriParser struct {
-	dmn     string
-	context context.Context
+	mirrors        *t.Mirrors
+	context        context.Context
+	search_workers int
+	// Интервал между запросами к api (см. api_get)
+	api_pacer *sh_api_pacer
 }
 
+// :mirror: домен shikimori (пустая строка - домен по умолчанию). Зеркала по умолчанию используются как запасные
 func NewShikimoriParser(mirror string) *ShikimoriParser {
-	var dmn string
-	if mirror != "" {
-		dmn = mirror
-	} else {
-		dmn = "shikimori.one"
-	}
+	return NewShikimoriParserWithMirrors(mirror)
+}
+
+// Создает парсер с упорядоченным списком зеркал shikimori (прим: "shikimori.one", "shikimori.me").
+//
+// Запросы отправляются на активное зеркало, при недоступности или блокировке - на следующие (см. tools.Mirrors).
+// Зеркала по умолчанию добавляются в конец списка как запасные
+func NewShikimoriParserWithMirrors(mirrors ...string) *ShikimoriParser {
 	return &ShikimoriParser{
-		dmn:     dmn,
-		context: context.Background(),
+		mirrors:        t.NewMirrors(mirrors, shikimoriMirrors...),
+		context:        context.Background(),
+		search_workers: defaultSearchWorkers,
+		api_pacer:      &sh_api_pacer{interval: shikimoriAPIInterval},
 	}
 }
 
+// Активное зеркало shikimori
+func (sh *ShikimoriParser) domain() string {
+	return sh.mirrors.Active()
+}
+
+// Возвращает зеркала парсера (для проверки доступности и ручного переключения)
+func (sh *ShikimoriParser) Mirrors() *t.Mirrors {
+	return sh.mirrors
+}
+
+// Проверяет доступность зеркал и делает активным первое доступное (см. tools.Mirrors.Probe)
+func (sh *ShikimoriParser) ProbeMirrors() (map[string]error, error) {
+	return sh.mirrors.Probe(sh.context)
+}
+
+// Задает количество аниме, для которых SearchIter параллельно загружает данные. Значения меньше 1 игнорируются.
+// Как и SetContext, вызывается до использования парсера: вызов одновременно с поиском не синхронизирован
+func (sh *ShikimoriParser) SetSearchWorkers(workers int) {
+	if workers < 1 {
+		return
+	}
+	sh.search_workers = workers
+}
+
+// Возвращает ссылку на страницу аниме по его id на shikimori (прим: 20 > https://shikimori.one/animes/20)
+func (sh *ShikimoriParser) anime_link(shikimori_id string) string {
+	return fmt.Sprintf("https://%s/animes/%s", sh.domain(), shikimori_id)
+}
+
 type SHSearchResult struct {
 	Genres        []string `json:"genres"`
 	Link          string   `json:"link"`
 	OriginalTitle string   `json:"original_title"`
 	Poster        string   `json:"poster"`
-	ShikimoriID   string   `json:"shikimori_parser"`
-	Status        string   `json:"status"`
-	Studio        string   `json:"studio"`
-	Title         string   `json:"title"`
-	Type          string   `json:"type"`
-	Year          string   `json:"year"`
+	// Все варианты постера в формате srcset: 1x из img src и варианты из img srcset (см. tools.SrcsetVariant)
+	PosterSrcset string `json:"poster_srcset"`
+	ShikimoriID  string `json:"shikimori_parser"`
+	Status       string `json:"status"`
+	Studio       string `json:"studio"`
+	Title        string `json:"title"`
+	Type         string `json:"type"`
+	Year         string `json:"year"`
+	// Оценка и количество эпизодов (заполняются в SearchCatalog)
+	Score    string `json:"score"`
+	Episodes string `json:"episodes"`
 }
 
 type SHAnimeInfoResult struct {
@@ -60,14 +105,16 @@ type SHAnimeInfoResult struct {
 	NextEpisode     string   `json:"next_episode"`
 	OriginalTitle   string   `json:"original_title"`
 	Picture         string   `json:"picture"`
-	PremiereInRU    string   `json:"premiere_in_ru"`
-	Rating          string   `json:"rating"`
-	Score           string   `json:"score"`
-	Status          string   `json:"status"`
-	Studio          string   `json:"studio"`
-	Themes          []string `json:"themes"`
-	Title           string   `json:"title"`
-	Type            string   `json:"type"`
+	// Все варианты картинки в формате srcset: 1x из img src и варианты из img srcset (см. tools.SrcsetVariant)
+	PictureSrcset string   `json:"picture_srcset"`
+	PremiereInRU  string   `json:"premiere_in_ru"`
+	Rating        string   `json:"rating"`
+	Score         string   `json:"score"`
+	Status        string   `json:"status"`
+	Studio        string   `json:"studio"`
+	Themes        []string `json:"themes"`
+	Title         string   `json:"title"`
+	Type          string   `json:"type"`
 }
 
 type SHRelated struct {
@@ -108,6 +155,13 @@ type SHAdditionalAnimeInfo struct {
 	Screenshots    []string            `json:"screenshots"`
 	Videos         []*SHVideos         `json:"videos"`
 	Similar        []*SHSimilar        `json:"similar"`
+	// Главные и второстепенные персонажи с ролью и сэйю (страница /characters, см. ExtendedAnimeInfo)
+	Characters    []*SHCharacter    `json:"characters"`
+	ExternalLinks []*SHExternalLink `json:"external_links"`
+	// Хронология франшизы (страница /chronology, см. ExtendedAnimeInfo)
+	Chronology []*SHChronology `json:"chronology"`
+	// Распределение оценок пользователей от 10 до 1 (главная страница, см. ExtendedAnimeInfo)
+	ScoreDistribution []*SHScoreCount `json:"score_distribution"`
 }
 
 type SHJsonResponse struct {
@@ -125,7 +179,7 @@ func (jr *SHJsonResponse) Decode(r io.Reader) error {
 //
 // :title: название аниме
 //
-// Возвращает список ссылок на SHSearchResult
+// Возвращает список ссылок на SHSearchResult, отсортированный по сходству названий с запросом
 func (sh *ShikimoriParser) Search(title string) ([]*SHSearchResult, error) {
 	headers := models.Headers{
 		"User-Agent":       "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0",
@@ -137,13 +191,13 @@ func (sh *ShikimoriParser) Search(title string) ([]*SHSearchResult, error) {
 		"search": title,
 	}
 
-	URL := fmt.Sprintf("https://%s/animes/autocomplete/v2", sh.dmn)
+	URL := fmt.Sprintf("https://%s/animes/autocomplete/v2", sh.domain())
 
-	response, err := t.RequestWithContext(sh.context, "GET", URL, params, headers, true, &SHJsonResponse{})
+	response, err := sh.mirrors.Request(sh.context, "GET", URL, params, headers, true, &SHJsonResponse{})
 	if err != nil {
 		error_message := fmt.Sprintf("Shikimori parser error : Search : RequestWithContext вернул ошибку: %v", err)
 		log.Println(error_message)
-		return nil, errs.NewServiceError(error_message)
+		return nil, t.WrapRequestError(err, error_message)
 	}
 
 	json_response, ok := response.Json.(*SHJsonResponse)
@@ -180,7 +234,7 @@ func (sh *ShikimoriParser) Search(title string) ([]*SHSearchResult, error) {
 			log.Println("Shikimori parser error : Search : goquery не смог найти атрибут data-url в контейнере с классом b-db_entry-variant-list_item")
 			return
 		}
-		c_data.Link = link
+		c_data.Link = sh.mirrors.Rewrite(link)
 
 		sh_id, exists := s.Attr("data-id")
 		if !exists || sh_id == "" {
@@ -191,12 +245,13 @@ func (sh *ShikimoriParser) Search(title string) ([]*SHSearchResult, error) {
 
 		image := s.Find("div.image").First()
 		if image.Length() != 0 {
-			poster, exists := image.Find("picture").First().Find("img").First().Attr("srcset")
-			if !exists || poster == "" {
-				log.Println("Shikimori parser error : Search : goquery не смог найти атрибут srcset в контейнере с классом b-db_entry-variant-list_item в div.image")
+			poster := img_srcset(image.Find("picture").First().Find("img").First())
+			if poster == "" {
+				log.Println("Shikimori parser error : Search : goquery не смог найти атрибуты src и srcset в контейнере с классом b-db_entry-variant-list_item в div.image")
 				return
 			}
-			c_data.Poster = strings.Replace(poster, " 2x", "", 1)
+			c_data.PosterSrcset = poster
+			c_data.Poster = t.SrcsetVariant(poster, 1)
 		}
 
 		info := s.Find("div.info").First()
@@ -255,13 +310,41 @@ func (sh *ShikimoriParser) Search(title string) ([]*SHSearchResult, error) {
 		res = append(res, c_data)
 	})
 
+	rank_sh_search(title, res)
 	return res, nil
 }
 
+// Ошибка загрузки данных одного аниме в SearchIter у ShikimoriParser
+type SHSearchError struct {
+	// Позиция аниме в результатах Search
+	Index int    `json:"index"`
+	Title string `json:"title"`
+	Link  string `json:"link"`
+	Err   error  `json:"-"`
+}
+
+func (e *SHSearchError) Error() string {
+	return fmt.Sprintf("Shikimori parser error : SearchIter : AnimeInfo не смог найти данные для %s по ссылке %s. Ошибка: %v", e.Title, e.Link, e.Err)
+}
+
+func (e *SHSearchError) Unwrap() error {
+	return e.Err
+}
+
+// Результат SearchIter: данные аниме вместе с результатом Search, для которого они загружены
+type SHSearchItem struct {
+	// Позиция аниме в результатах Search
+	Index  int                `json:"index"`
+	Search *SHSearchResult    `json:"search"`
+	Info   *SHAnimeInfoResult `json:"info"`
+}
+
 // Получение данных по аниме парсингом.
 //
 // :shikimori_link: ссылка на страницу шикимори с информацией (прим: https://shikimori.one/animes/z20-naruto)
 //
+// Для аниме 18+ без входа (см. SetCookieSession и Login) возвращает ошибку errs.AgeRestricted
+//
 // Возвращает ссылку на SHAnimeInfoResult:
 func (sh *ShikimoriParser) AnimeInfo(shikimori_link string) (*SHAnimeInfoResult, error) {
 	result := &SHAnimeInfoResult{
@@ -273,11 +356,11 @@ func (sh *ShikimoriParser) AnimeInfo(shikimori_link string) (*SHAnimeInfoResult,
 		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0",
 	}
 
-	resp, err := t.RequestWithContext(sh.context, "GET", shikimori_link, nil, headers, false, nil)
+	resp, err := sh.mirrors.Request(sh.context, "GET", shikimori_link, nil, headers, false, nil)
 	if err != nil {
 		error_message := fmt.Sprintf("Shikimori parser error : AnimeInfo : RequestWithContext вернул ошибку: %v", err)
 		log.Println(error_message)
-		return nil, errs.NewServiceError(error_message)
+		return nil, t.WrapRequestError(err, error_message)
 	}
 
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Data))
@@ -286,19 +369,23 @@ func (sh *ShikimoriParser) AnimeInfo(shikimori_link string) (*SHAnimeInfoResult,
 		log.Println(error_message)
 		return nil, errs.NewServiceError(error_message)
 	}
+	if shikimori_age_gate(doc) {
+		return nil, age_restricted_error("Shikimori", "AnimeInfo", shikimori_link, sh.authenticated())
+	}
 	title := strings.Split(doc.Find("header.head").First().Find("h1").First().Text(), " / ")
 	result.Title = title[0]
 	result.OriginalTitle = title[1]
 
 	picture := doc.Find("picture").First()
 	if picture.Length() > 0 {
-		srcset, exists := picture.Find("img").First().Attr("srcset")
-		if !exists || srcset == "" {
-			error_message := "Shikimori parser error : AnimeInfo : в picture:img не было найдено атрибута srcset"
+		srcset := img_srcset(picture.Find("img").First())
+		if srcset == "" {
+			error_message := "Shikimori parser error : AnimeInfo : в picture:img не было найдено атрибутов src и srcset"
 			log.Println(error_message)
 			return nil, errs.NewServiceError(error_message)
 		}
-		result.Picture = strings.Replace(srcset, " 2x", "", 1)
+		result.PictureSrcset = srcset
+		result.Picture = t.SrcsetVariant(srcset, 1)
 	}
 
 	info := doc.Find("div.c-info-left").First().Find("div.block").First()
@@ -360,7 +447,9 @@ func (sh *ShikimoriParser) AnimeInfo(shikimori_link string) (*SHAnimeInfoResult,
 }
 
 // Получение дополнительных данных об аниме.
-// Получаемые данные: связанные аниме (продолжение, предыстория, альтернативное и т.п.), Авторы (автор манги, режиссер), Главные герои, Скриншоты, Ролики, Похожее
+// Получаемые данные: связанные аниме (продолжение, предыстория, альтернативное и т.п.), Авторы (автор манги, режиссер), Главные герои, Скриншоты, Ролики, Похожее.
+// Делает один запрос (страница /resources). Персонажи с ролями и сэйю, внешние ссылки, хронология и распределение оценок
+// заполняются только если есть на этой странице, для их загрузки с других страниц используйте ExtendedAnimeInfo
 //
 // :shikimori_link: ссылка на страницу шикимори с информацией (прим: https://shikimori.one/animes/z20-naruto)
 //
@@ -378,22 +467,34 @@ func (sh *ShikimoriParser) AdditionalAnimeInfo(shikimori_link string) (*SHAdditi
 		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0",
 	}
 
-	resp, err := t.RequestWithContext(sh.context, "GET", link, nil, headers, false, nil)
+	resp, err := sh.mirrors.Request(sh.context, "GET", link, nil, headers, false, nil)
 	if err != nil {
 		error_message := fmt.Sprintf("Shikimori parser error : AdditionalAnimeInfo : RequestWithContext вернул ошибку: %v", err)
 		log.Println(error_message)
-		return nil, errs.NewServiceError(error_message)
+		return nil, t.WrapRequestError(err, error_message)
 	}
 
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Data))
+	if err != nil {
+		error_message := fmt.Sprintf("Shikimori parser error : AdditionalAnimeInfo : goquery не смог преобразовать ответ в документ. Ошибка: %v", err)
+		log.Println(error_message)
+		return nil, errs.NewServiceError(error_message)
+	}
+	if shikimori_age_gate(doc) {
+		return nil, age_restricted_error("Shikimori", "AdditionalAnimeInfo", link, sh.authenticated())
+	}
 
 	res := &SHAdditionalAnimeInfo{
-		Related:        make([]*SHRelated, 0),
-		Staff:          make([]*SHStaff, 0),
-		MainCharacters: make([]*SHMainCharacters, 0),
-		Screenshots:    make([]string, 0),
-		Videos:         make([]*SHVideos, 0),
-		Similar:        make([]*SHSimilar, 0),
+		Related:           make([]*SHRelated, 0),
+		Staff:             make([]*SHStaff, 0),
+		MainCharacters:    make([]*SHMainCharacters, 0),
+		Screenshots:       make([]string, 0),
+		Videos:            make([]*SHVideos, 0),
+		Similar:           make([]*SHSimilar, 0),
+		Characters:        make([]*SHCharacter, 0),
+		ExternalLinks:     make([]*SHExternalLink, 0),
+		Chronology:        make([]*SHChronology, 0),
+		ScoreDistribution: make([]*SHScoreCount, 0),
 	}
 
 	r1 := doc.Find("div.cc-related-authors").First()
@@ -411,12 +512,12 @@ func (sh *ShikimoriParser) AdditionalAnimeInfo(shikimori_link string) (*SHAdditi
 				c_data.Url = url
 
 				if entry.Find("picture").First().Length() > 0 {
-					picture, exists := entry.Find("picture").First().Find("img").First().Attr("srcset")
-					if !exists || picture == "" {
-						log.Println("Shikimori parser error : AdditionalAnimeInfo : goquery не смог найти атрибут srcset в div.cc-related-authors:div.c-column:div.subheadline:div.b-db_entry-variant-list_item:picture:img")
+					picture := img_srcset(entry.Find("picture").First().Find("img").First())
+					if picture == "" {
+						log.Println("Shikimori parser error : AdditionalAnimeInfo : goquery не смог найти атрибуты src и srcset в div.cc-related-authors:div.c-column:div.subheadline:div.b-db_entry-variant-list_item:picture:img")
 						continue
 					}
-					c_data.Picture = strings.Replace(picture, " 2x", "", 1)
+					c_data.Picture = t.SrcsetVariant(picture, 1)
 				}
 				div_name := entry.Find("div.name").First()
 				if div_name.Length() == 0 {
@@ -468,7 +569,7 @@ func (sh *ShikimoriParser) AdditionalAnimeInfo(shikimori_link string) (*SHAdditi
 					log.Println("Shikimori parser error : AdditionalAnimeInfo : goquery не смог найти атрибут data-url в div.cc-related-authors:div.c-column:div.subheadline:div.b-db_entry-variant-list_item")
 					continue
 				}
-				c_data.Link = link
+				c_data.Link = sh.mirrors.Rewrite(link)
 
 				name, exists := entry.Attr("data-text")
 				if !exists || name == "" {
@@ -482,6 +583,8 @@ func (sh *ShikimoriParser) AdditionalAnimeInfo(shikimori_link string) (*SHAdditi
 				}
 				res.Staff = append(res.Staff, c_data)
 			}
+		default:
+			sh.parse_additional_column(col_type, s, res)
 		}
 
 	})
@@ -553,7 +656,7 @@ func (sh *ShikimoriParser) AdditionalAnimeInfo(shikimori_link string) (*SHAdditi
 					log.Println("Shikimori parser error : AdditionalAnimeInfo : goquery не смог найти атрибут data-href в div.block:article:div")
 					return
 				}
-				c_data.Link = link
+				c_data.Link = sh.mirrors.Rewrite(link)
 				res.Similar = append(res.Similar, c_data)
 			})
 		}
@@ -0,0 +1,362 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Роли персонажей в SHCharacter.Role
+const (
+	CharacterRoleMain       = "main"
+	CharacterRoleSupporting = "supporting"
+)
+
+// Виды внешних ссылок в SHExternalLink.Kind (совпадают с классами b-external_link на shikimori)
+const (
+	ExternalLinkOfficialSite = "official_site"
+	ExternalLinkMyAnimeList  = "myanimelist"
+	ExternalLinkAniDB        = "anime_db"
+	ExternalLinkWikipedia    = "wikipedia"
+)
+
+type SHSeiyu struct {
+	Name    string `json:"name"`
+	Link    string `json:"link"`
+	Picture string `json:"picture"`
+}
+
+type SHCharacter struct {
+	Name         string `json:"name"`
+	OriginalName string `json:"original_name"`
+	Link         string `json:"link"`
+	Picture      string `json:"picture"`
+	// CharacterRoleMain или CharacterRoleSupporting
+	Role  string     `json:"role"`
+	Seiyu []*SHSeiyu `json:"seiyu"`
+}
+
+type SHExternalLink struct {
+	// Вид ссылки (прим: ExternalLinkMyAnimeList). Для неизвестных сайтов - класс ссылки или пустая строка
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Link string `json:"link"`
+}
+
+type SHChronology struct {
+	ShikimoriID string `json:"shikimori_id"`
+	Name        string `json:"name"`
+	Link        string `json:"link"`
+	Picture     string `json:"picture"`
+	Type        string `json:"type"`
+	Date        string `json:"date"`
+}
+
+type SHScoreCount struct {
+	Score int `json:"score"`
+	Count int `json:"count"`
+}
+
+// Разбор колонок с заголовком div.subheadline, которых нет в основном switch AdditionalAnimeInfo.
+// Используется и для дополнительных страниц (главная страница аниме, /characters, /chronology)
+func (sh *ShikimoriParser) parse_additional_column(col_type string, s *goquery.Selection, res *SHAdditionalAnimeInfo) {
+	col_type = strings.ToLower(strings.TrimSpace(col_type))
+	switch {
+	case strings.HasPrefix(col_type, "главные"):
+		res.Characters = merge_characters(res.Characters, sh.parse_characters(s, CharacterRoleMain))
+	case strings.HasPrefix(col_type, "второстепенные"):
+		res.Characters = merge_characters(res.Characters, sh.parse_characters(s, CharacterRoleSupporting))
+	case strings.HasPrefix(col_type, "на других сайтах"), strings.HasPrefix(col_type, "ссылки"):
+		for _, link := range parse_external_links(s) {
+			if !slices.ContainsFunc(res.ExternalLinks, func(existing *SHExternalLink) bool { return existing.Link == link.Link }) {
+				res.ExternalLinks = append(res.ExternalLinks, link)
+			}
+		}
+	case strings.HasPrefix(col_type, "хронология"):
+		res.Chronology = append(res.Chronology, sh.parse_chronology(s)...)
+	}
+}
+
+// Проходит по всем блокам страницы с заголовком div.subheadline и передает их в parse_additional_column
+func (sh *ShikimoriParser) parse_additional_page(doc *goquery.Document, res *SHAdditionalAnimeInfo) {
+	doc.Find("div.subheadline").Each(func(i int, s *goquery.Selection) {
+		sh.parse_additional_column(s.Text(), s.Parent(), res)
+	})
+}
+
+// Разбирает персонажей блока (article или div.b-db_entry-variant-list_item) вместе с их сэйю
+func (sh *ShikimoriParser) parse_characters(s *goquery.Selection, role string) []*SHCharacter {
+	res := make([]*SHCharacter, 0)
+	s.Find("article, div.b-db_entry-variant-list_item").Each(func(i int, entry *goquery.Selection) {
+		if !in_section(entry, s) {
+			return
+		}
+		c_data := &SHCharacter{Role: role, Seiyu: make([]*SHSeiyu, 0)}
+
+		link := entry.Find("a[href*=\"/characters/\"]").First()
+		if href, exists := link.Attr("href"); exists && href != "" {
+			c_data.Link = sh.site_link(href)
+		} else if url, exists := entry.Attr("data-url"); exists && strings.Contains(url, "/characters/") {
+			c_data.Link = sh.site_link(url)
+		} else {
+			log.Println("Shikimori parser error : AdditionalAnimeInfo : goquery не смог найти ссылку на персонажа в article:a[href*=\"/characters/\"]")
+			return
+		}
+
+		c_data.Name = strings.TrimSpace(entry.Find("span.name-ru").First().Text())
+		c_data.OriginalName = strings.TrimSpace(entry.Find("span.name-en").First().Text())
+		if c_data.Name == "" {
+			c_data.Name = strings.TrimSpace(link.Text())
+		}
+		c_data.Picture = entry_picture(entry)
+
+		entry.Find("a[href*=\"/people/\"], a[href*=\"/seyu/\"]").Each(func(i int, person *goquery.Selection) {
+			href, _ := person.Attr("href")
+			seiyu := &SHSeiyu{Link: sh.site_link(href), Picture: entry_picture(person)}
+			if title, exists := person.Attr("title"); exists && title != "" {
+				seiyu.Name = title
+			} else {
+				seiyu.Name = strings.TrimSpace(person.Text())
+			}
+			c_data.Seiyu = append(c_data.Seiyu, seiyu)
+		})
+		res = append(res, c_data)
+	})
+	return res
+}
+
+// Проверяет, что ближайший к entry блок с заголовком div.subheadline - section (а не вложенный в него блок)
+func in_section(entry, section *goquery.Selection) bool {
+	for _, parent := range entry.Parents().EachIter() {
+		if parent.ChildrenFiltered("div.subheadline").Length() > 0 {
+			return parent.IsSelection(section)
+		}
+	}
+	return false
+}
+
+// Добавляет персонажей в срез без повторов (персонаж может быть и на главной странице, и на /characters).
+// У повторов сохраняется первая роль, сэйю дополняются
+func merge_characters(res []*SHCharacter, characters []*SHCharacter) []*SHCharacter {
+	for _, character := range characters {
+		var found *SHCharacter
+		for _, existing := range res {
+			if existing.Link == character.Link {
+				found = existing
+				break
+			}
+		}
+		if found == nil {
+			res = append(res, character)
+			continue
+		}
+		for _, seiyu := range character.Seiyu {
+			if !slices.ContainsFunc(found.Seiyu, func(existing *SHSeiyu) bool { return existing.Link == seiyu.Link }) {
+				found.Seiyu = append(found.Seiyu, seiyu)
+			}
+		}
+		if found.OriginalName == "" {
+			found.OriginalName = character.OriginalName
+		}
+		if found.Picture == "" {
+			found.Picture = character.Picture
+		}
+	}
+	return res
+}
+
+// Разбирает внешние ссылки (div.b-external_link). Вид ссылки берется из классов элемента
+func parse_external_links(s *goquery.Selection) []*SHExternalLink {
+	res := make([]*SHExternalLink, 0)
+	s.Find(".b-external_link").Each(func(i int, entry *goquery.Selection) {
+		c_data := &SHExternalLink{}
+		link := entry.Find("a").First()
+		if href, exists := link.Attr("href"); exists && href != "" {
+			c_data.Link = href
+		} else if href, exists := entry.Find("[data-href]").First().Attr("data-href"); exists && href != "" {
+			c_data.Link = href
+		} else {
+			log.Println("Shikimori parser error : AdditionalAnimeInfo : goquery не смог найти ссылку в .b-external_link")
+			return
+		}
+		c_data.Name = strings.TrimSpace(entry.Text())
+
+		cls, _ := entry.Attr("class")
+		for _, class := range strings.Fields(cls) {
+			if class != "b-external_link" && class != "b-menu-line" {
+				c_data.Kind = class
+				break
+			}
+		}
+		res = append(res, c_data)
+	})
+	return res
+}
+
+// Разбирает элементы хронологии франшизы (div.b-db_entry-variant-list_item)
+func (sh *ShikimoriParser) parse_chronology(s *goquery.Selection) []*SHChronology {
+	res := make([]*SHChronology, 0)
+	s.Find("div.b-db_entry-variant-list_item").Each(func(i int, entry *goquery.Selection) {
+		url, exists := entry.Attr("data-url")
+		if !exists || url == "" {
+			log.Println("Shikimori parser error : AdditionalAnimeInfo : goquery не смог найти атрибут data-url в div.b-db_entry-variant-list_item")
+			return
+		}
+		c_data := &SHChronology{
+			ShikimoriID: ShikimoriIDFromLink(url),
+			Link:        sh.site_link(url),
+			Picture:     entry_picture(entry),
+		}
+		div_name := entry.Find("div.name").First()
+		if name := strings.TrimSpace(div_name.Find("span.name-ru").First().Text()); name != "" {
+			c_data.Name = name
+		} else if name := strings.TrimSpace(div_name.Find("span.name-en").First().Text()); name != "" {
+			c_data.Name = name
+		} else {
+			c_data.Name = strings.TrimSpace(div_name.Text())
+		}
+		for _, other := range entry.Find("div.line").First().Find("div.linkeable").EachIter() {
+			href, _ := other.Attr("data-href")
+			if strings.Contains(href, "/kind/") {
+				c_data.Type = strings.TrimSpace(other.Text())
+			} else if strings.Contains(href, "/season/") {
+				c_data.Date = strings.TrimSpace(other.Text())
+			}
+		}
+		res = append(res, c_data)
+	})
+	return res
+}
+
+// Разбирает распределение оценок пользователей (атрибут data-stats в #rates_scores_stats).
+// Поддерживаются форматы [["10",123],...] и [{"key":"10","value":123},...]
+func parse_score_distribution(doc *goquery.Document) []*SHScoreCount {
+	res := make([]*SHScoreCount, 0)
+	raw, exists := doc.Find("#rates_scores_stats").First().Attr("data-stats")
+	if !exists || raw == "" {
+		return res
+	}
+
+	pairs := make([][]json.RawMessage, 0)
+	if err := json.Unmarshal([]byte(raw), &pairs); err != nil {
+		objects := make([]struct {
+			Key   json.RawMessage `json:"key"`
+			Value json.RawMessage `json:"value"`
+		}, 0)
+		if err := json.Unmarshal([]byte(raw), &objects); err != nil {
+			log.Printf("Shikimori parser error : AdditionalAnimeInfo : не удалось разобрать data-stats в #rates_scores_stats: %v", err)
+			return res
+		}
+		for _, object := range objects {
+			pairs = append(pairs, []json.RawMessage{object.Key, object.Value})
+		}
+	}
+
+	for _, pair := range pairs {
+		if len(pair) < 2 {
+			continue
+		}
+		score, err1 := json_int(pair[0])
+		count, err2 := json_int(pair[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		res = append(res, &SHScoreCount{Score: score, Count: count})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Score > res[j].Score })
+	return res
+}
+
+// Число из json, записанное числом или строкой
+func json_int(raw json.RawMessage) (int, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return strconv.Atoi(strings.TrimSpace(text))
+	}
+	var number float64
+	if err := json.Unmarshal(raw, &number); err != nil {
+		return 0, err
+	}
+	return int(number), nil
+}
+
+// Картинка элемента: meta itemprop="image" или вариант 1x из img src и srcset
+func entry_picture(s *goquery.Selection) string {
+	if content, exists := s.Find("meta[itemprop=\"image\"]").First().Attr("content"); exists && content != "" {
+		return content
+	}
+	return t.SrcsetVariant(img_srcset(s.Find("img").First()), 1)
+}
+
+// Загружает дополнительную страницу аниме для ExtendedAnimeInfo
+func (sh *ShikimoriParser) additional_page(link string) (*goquery.Document, error) {
+	headers := models.Headers{
+		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0",
+	}
+	resp, err := sh.mirrors.Request(sh.context, "GET", link, nil, headers, false, nil)
+	if err != nil {
+		error_message := fmt.Sprintf("Shikimori parser error : ExtendedAnimeInfo : RequestWithContext вернул ошибку для %s: %v", link, err)
+		log.Println(error_message)
+		return nil, t.WrapRequestError(err, error_message)
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Data))
+	if err != nil {
+		error_message := fmt.Sprintf("Shikimori parser error : ExtendedAnimeInfo : goquery не смог преобразовать ответ %s в документ. Ошибка: %v", link, err)
+		log.Println(error_message)
+		return nil, errs.NewServiceError(error_message)
+	}
+	if shikimori_age_gate(doc) {
+		return nil, age_restricted_error("Shikimori", "ExtendedAnimeInfo", link, sh.authenticated())
+	}
+	return doc, nil
+}
+
+// Получение дополнительных данных об аниме (AdditionalAnimeInfo) вместе с данными с других страниц аниме:
+// распределение оценок и внешние ссылки (главная страница), персонажи с ролями и сэйю (/characters), хронология (/chronology).
+// Делает до 4 запросов вместо одного.
+//
+// :shikimori_link: ссылка на страницу шикимори с информацией (прим: https://shikimori.one/animes/z20-naruto)
+//
+// Если ошибка AdditionalAnimeInfo, возвращает только ее. Если не удалось загрузить дополнительные страницы,
+// возвращает результат с пустыми соответствующими полями вместе с ошибкой errors.Join(...)
+//
+// Возвращает ссылку на SHAdditionalAnimeInfo
+func (sh *ShikimoriParser) ExtendedAnimeInfo(shikimori_link string) (*SHAdditionalAnimeInfo, error) {
+	res, err := sh.AdditionalAnimeInfo(shikimori_link)
+	if err != nil {
+		return nil, err
+	}
+
+	base_link := strings.TrimSuffix(shikimori_link, "/")
+	errors_list := make([]error, 0)
+	if doc, err := sh.additional_page(base_link); err != nil {
+		errors_list = append(errors_list, err)
+	} else {
+		sh.parse_additional_page(doc, res)
+		res.ScoreDistribution = parse_score_distribution(doc)
+	}
+	if doc, err := sh.additional_page(base_link + "/characters"); err != nil {
+		errors_list = append(errors_list, err)
+	} else {
+		sh.parse_additional_page(doc, res)
+	}
+	if len(res.Chronology) == 0 {
+		if doc, err := sh.additional_page(base_link + "/chronology"); err != nil {
+			errors_list = append(errors_list, err)
+		} else {
+			res.Chronology = sh.parse_chronology(doc.Selection)
+		}
+	}
+	return res, errors.Join(errors_list...)
+}
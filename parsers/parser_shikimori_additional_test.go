@@ -0,0 +1,182 @@
+package parsers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Главная страница аниме: главные герои (персонаж во вложенном блоке с заголовком не относится к ним) и внешние ссылки
+const test_shikimori_main_page = `<div class="c-characters">
+<div class="subheadline">Главные герои</div>
+<div class="cc">
+	<article>
+		<a href="/characters/17-naruto-uzumaki"><span class="name-ru">Наруто Узумаки</span><span class="name-en">Naruto Uzumaki</span></a>
+		<a href="/people/16-junko-takeuchi" title="Дзюнко Такэути"></a>
+	</article>
+	<div class="c-nested">
+		<div class="subheadline">Другие персонажи</div>
+		<article><a href="/characters/99-other">Другой</a></article>
+	</div>
+</div>
+</div>
+<div class="c-links">
+<div class="subheadline">На других сайтах</div>
+<div class="b-external_link myanimelist b-menu-line"><a href="https://myanimelist.net/anime/20">MyAnimeList</a></div>
+<div class="b-external_link b-menu-line wikipedia"><span data-href="https://ru.wikipedia.org/wiki/Naruto">Википедия</span></div>
+<div class="b-external_link"><span>Без ссылки</span></div>
+</div>
+<div class="c-more-links">
+<div class="subheadline">Ссылки</div>
+<div class="b-external_link myanimelist"><a href="https://myanimelist.net/anime/20">MyAnimeList</a></div>
+<div class="b-external_link b-menu-line"><a href="https://example.test/naruto">Сайт</a></div>
+</div>`
+
+// Страница /characters: Наруто повторяется с картинкой и еще одним сэйю, Саске - второстепенный персонаж
+const test_shikimori_characters_page = `<div class="c-characters">
+<div class="subheadline">Второстепенные персонажи</div>
+<div class="cc">
+	<div class="b-db_entry-variant-list_item" data-url="/characters/17-naruto-uzumaki">
+		<span class="name-ru">Наруто</span>
+		<img src="/system/characters/17.jpg" srcset="/system/characters/17_2x.jpg 2x">
+		<a href="/people/16-junko-takeuchi" title="Дзюнко Такэути"></a>
+		<a href="/seyu/1000-maile-flanagan">Мэйл Флэнаган</a>
+	</div>
+	<article><a href="/characters/13-sasuke-uchiha"><span class="name-ru">Саске Учиха</span></a></article>
+</div>
+</div>`
+
+func TestParseAdditionalPage(test *testing.T) {
+	sh := NewShikimoriParser("shikimori.one")
+	res := &SHAdditionalAnimeInfo{}
+	sh.parse_additional_page(test_goquery(test, test_shikimori_main_page), res)
+	sh.parse_additional_page(test_goquery(test, test_shikimori_characters_page), res)
+
+	if len(res.Characters) != 2 {
+		test.Fatalf("персонажей %d, want 2: %+v", len(res.Characters), res.Characters)
+	}
+	naruto, sasuke := res.Characters[0], res.Characters[1]
+	if naruto.Name != "Наруто Узумаки" || naruto.OriginalName != "Naruto Uzumaki" || naruto.Role != CharacterRoleMain ||
+		naruto.Link != "https://shikimori.one/characters/17-naruto-uzumaki" || naruto.Picture != "/system/characters/17.jpg" {
+		test.Errorf("персонаж 0 = %+v", naruto)
+	}
+	if len(naruto.Seiyu) != 2 || naruto.Seiyu[0].Name != "Дзюнко Такэути" || naruto.Seiyu[1].Name != "Мэйл Флэнаган" ||
+		naruto.Seiyu[1].Link != "https://shikimori.one/seyu/1000-maile-flanagan" {
+		test.Errorf("сэйю персонажа 0: %+v", naruto.Seiyu)
+	}
+	if sasuke.Name != "Саске Учиха" || sasuke.Role != CharacterRoleSupporting || len(sasuke.Seiyu) != 0 {
+		test.Errorf("персонаж 1 = %+v", sasuke)
+	}
+
+	want := []SHExternalLink{
+		{Kind: ExternalLinkMyAnimeList, Name: "MyAnimeList", Link: "https://myanimelist.net/anime/20"},
+		{Kind: ExternalLinkWikipedia, Name: "Википедия", Link: "https://ru.wikipedia.org/wiki/Naruto"},
+		{Kind: "", Name: "Сайт", Link: "https://example.test/naruto"},
+	}
+	if len(res.ExternalLinks) != len(want) {
+		test.Fatalf("внешних ссылок %d, want %d: %+v", len(res.ExternalLinks), len(want), res.ExternalLinks)
+	}
+	for i, link := range res.ExternalLinks {
+		if *link != want[i] {
+			test.Errorf("ExternalLinks[%d] = %+v, want %+v", i, *link, want[i])
+		}
+	}
+}
+
+func TestInSection(test *testing.T) {
+	doc := test_goquery(test, test_shikimori_main_page)
+	section := doc.Find("div.c-characters")
+	nested := doc.Find("div.c-nested")
+	main_article := doc.Find("a[href=\"/characters/17-naruto-uzumaki\"]").Closest("article")
+	nested_article := nested.Find("article")
+
+	if !in_section(main_article, section) {
+		test.Error("in_section для персонажа блока = false")
+	}
+	if in_section(nested_article, section) || !in_section(nested_article, nested) {
+		test.Error("персонаж вложенного блока должен относиться только к вложенному блоку")
+	}
+	if in_section(doc.Find("div.c-links div.b-external_link").First(), section) {
+		test.Error("in_section для элемента другого блока = true")
+	}
+}
+
+func TestMergeCharacters(test *testing.T) {
+	first := []*SHCharacter{
+		{Name: "Наруто", Link: "/characters/17", Role: CharacterRoleMain, Seiyu: []*SHSeiyu{{Name: "Дзюнко Такэути", Link: "/people/16"}}},
+	}
+	second := []*SHCharacter{
+		{Name: "Наруто", OriginalName: "Naruto", Link: "/characters/17", Role: CharacterRoleSupporting, Picture: "/17.jpg",
+			Seiyu: []*SHSeiyu{{Name: "Дзюнко Такэути", Link: "/people/16"}, {Name: "Мэйл Флэнаган", Link: "/people/1000"}}},
+		{Name: "Саске", Link: "/characters/13", Role: CharacterRoleSupporting},
+	}
+	res := merge_characters(first, second)
+	if len(res) != 2 || res[1].Name != "Саске" {
+		test.Fatalf("merge_characters = %+v", res)
+	}
+	naruto := res[0]
+	if naruto.Role != CharacterRoleMain || naruto.OriginalName != "Naruto" || naruto.Picture != "/17.jpg" || len(naruto.Seiyu) != 2 {
+		test.Errorf("повторяющийся персонаж = %+v", naruto)
+	}
+}
+
+func TestParseExternalLinks(test *testing.T) {
+	links := parse_external_links(test_goquery(test, test_shikimori_main_page).Find("div.c-links"))
+	if len(links) != 2 || links[0].Kind != ExternalLinkMyAnimeList || links[1].Kind != ExternalLinkWikipedia || links[1].Link != "https://ru.wikipedia.org/wiki/Naruto" {
+		test.Errorf("parse_external_links = %+v", links)
+	}
+}
+
+func TestParseScoreDistribution(test *testing.T) {
+	tests := map[string]string{
+		"пары":    `[["8", 40], ["10", 120], ["9", 80]]`,
+		"объекты": `[{"key": "9", "value": 80}, {"key": 10, "value": "120"}, {"key": "8", "value": 40.0}]`,
+	}
+	for name, stats := range tests {
+		doc := test_goquery(test, `<div id="rates_scores_stats" data-stats='`+stats+`'></div>`)
+		res := parse_score_distribution(doc)
+		want := []SHScoreCount{{Score: 10, Count: 120}, {Score: 9, Count: 80}, {Score: 8, Count: 40}}
+		if len(res) != len(want) {
+			test.Errorf("%s: parse_score_distribution вернул %d оценок, want %d", name, len(res), len(want))
+			continue
+		}
+		for i, score := range res {
+			if *score != want[i] {
+				test.Errorf("%s: [%d] = %+v, want %+v", name, i, *score, want[i])
+			}
+		}
+	}
+	for _, content := range []string{`<div id="rates_scores_stats" data-stats="не json"></div>`, `<div></div>`} {
+		if res := parse_score_distribution(test_goquery(test, content)); len(res) != 0 {
+			test.Errorf("parse_score_distribution(%q) = %+v, want пустой срез", content, res)
+		}
+	}
+}
+
+func TestAdditionalPageAgeRestricted(test *testing.T) {
+	use_test_sites(test, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<div class="b-age_restricted"><a href="/users/sign_in">Войти</a></div>`))
+	})
+	sh := NewShikimoriParser("shikimori.one")
+
+	var restricted *errs.AgeRestricted
+	_, err := sh.additional_page("https://shikimori.one/animes/z20-naruto")
+	if !errors.As(err, &restricted) || !strings.Contains(err.Error(), "нужна сессия с входом") {
+		test.Errorf("additional_page без входа вернул %T: %v, want *errs.AgeRestricted с подсказкой о входе", err, err)
+	}
+
+	session, err := t.NewSession("")
+	if err != nil {
+		test.Fatal(err)
+	}
+	session.Jar().Add(&t.Cookie{Name: shikimoriSessionCookie, Value: "session", Domain: "shikimori.one"})
+	sh.SetCookieSession(session)
+	_, err = sh.additional_page("https://shikimori.one/animes/z20-naruto")
+	if !errors.As(err, &restricted) || !strings.Contains(err.Error(), "даже в сессии с входом") {
+		test.Errorf("additional_page с входом вернул %T: %v, want *errs.AgeRestricted с подсказкой о возрасте", err, err)
+	}
+}
@@ -0,0 +1,81 @@
+package parsers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Интервал между запросами к api shikimori: лимит api - 5 запросов в секунду и 90 в минуту
+const shikimoriAPIInterval = time.Minute / 90
+
+// Выдерживает интервал между запросами к api одного парсера
+type sh_api_pacer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	last     time.Time
+}
+
+// Ждет, пока с предыдущего запроса не пройдет interval. Возвращает ошибку контекста, если он отменен раньше
+func (p *sh_api_pacer) wait(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if wait := p.interval - time.Since(p.last); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	p.last = time.Now()
+	return nil
+}
+
+// Обрезает страницу ответа api до limit элементов: api может вернуть на один элемент больше limit как признак следующей страницы.
+//
+// Возвращает элементы страницы и true, если есть следующая страница
+func api_page[T any](items []T, limit int) ([]T, bool) {
+	if len(items) > limit {
+		return items[:limit], true
+	}
+	return items, len(items) == limit
+}
+
+// GET запрос к api shikimori (https://<домен>/api/<path>) с декодированием json в out.
+// Запросы выдерживают интервал shikimoriAPIInterval и отправляются одним воркером (см. tools.WithRequestWorkers),
+// чтобы не превышать лимит api
+//
+// :method: название вызывающего метода для сообщений об ошибках
+func (sh *ShikimoriParser) api_get(method, path string, params models.Params, out any) error {
+	if err := sh.api_pacer.wait(sh.context); err != nil {
+		error_message := fmt.Sprintf("Shikimori parser error : %s : запрос %s отменен. Ошибка: %v", method, path, err)
+		log.Println(error_message)
+		return errs.NewServiceError(error_message)
+	}
+	headers := models.Headers{
+		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0",
+		"Accept":     "application/json",
+	}
+	URL := fmt.Sprintf("https://%s/api/%s", sh.domain(), path)
+	resp, err := sh.mirrors.Request(t.WithRequestWorkers(sh.context, 1), "GET", URL, params, headers, false, nil)
+	if err != nil {
+		error_message := fmt.Sprintf("Shikimori parser error : %s : RequestWithContext вернул ошибку для %s: %v", method, path, err)
+		log.Println(error_message)
+		return t.WrapRequestError(err, error_message)
+	}
+	if err := json.Unmarshal(resp.Data, out); err != nil {
+		error_message := fmt.Sprintf("Shikimori parser error : %s : ошибка декодирования json %s: %v", method, path, err)
+		log.Println(error_message)
+		return errs.NewJsonDecodeFailureError(error_message)
+	}
+	return nil
+}
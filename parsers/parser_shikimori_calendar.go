@@ -0,0 +1,172 @@
+package parsers
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Сезоны для Season
+const (
+	SeasonWinter = "winter"
+	SeasonSpring = "spring"
+	SeasonSummer = "summer"
+	SeasonFall   = "fall"
+)
+
+var seasons = map[string]string{
+	"winter": SeasonWinter, "зима": SeasonWinter,
+	"spring": SeasonSpring, "весна": SeasonSpring,
+	"summer": SeasonSummer, "лето": SeasonSummer,
+	"fall": SeasonFall, "autumn": SeasonFall, "осень": SeasonFall,
+}
+
+// Аниме в календаре выхода эпизодов
+type SHCalendarEntry struct {
+	ShikimoriID   string `json:"shikimori_id"`
+	Title         string `json:"title"`
+	OriginalTitle string `json:"original_title"`
+	Link          string `json:"link"`
+	Poster        string `json:"poster"`
+	Type          string `json:"type"`
+	// anons или ongoing
+	Status        string  `json:"status"`
+	Score         float64 `json:"score"`
+	Episodes      int     `json:"episodes"`
+	EpisodesAired int     `json:"episodes_aired"`
+	// Номер следующего эпизода
+	NextEpisode int `json:"next_episode"`
+	// Время выхода следующего эпизода (в часовом поясе tools.MoscowLocation)
+	NextEpisodeAt time.Time `json:"next_episode_at"`
+	// Длительность эпизода в минутах (0 - неизвестно)
+	Duration int `json:"duration"`
+}
+
+// Элемент ответа /api/calendar
+type sh_calendar_json struct {
+	NextEpisode   int    `json:"next_episode"`
+	NextEpisodeAt string `json:"next_episode_at"`
+	Duration      *int   `json:"duration"`
+	Anime         struct {
+		ID            int64  `json:"id"`
+		Name          string `json:"name"`
+		Russian       string `json:"russian"`
+		URL           string `json:"url"`
+		Kind          string `json:"kind"`
+		Score         string `json:"score"`
+		Status        string `json:"status"`
+		Episodes      int    `json:"episodes"`
+		EpisodesAired int    `json:"episodes_aired"`
+		Image         struct {
+			Original string `json:"original"`
+		} `json:"image"`
+	} `json:"anime"`
+}
+
+// Время следующего эпизода из NextEpisode (атрибут data-datetime) в часовом поясе tools.MoscowLocation
+func (r *SHAnimeInfoResult) NextEpisodeAt() (time.Time, error) {
+	if r.NextEpisode == "" {
+		return time.Time{}, errs.NewNoResultsError("Shikimori parser error : NextEpisodeAt : у аниме не указан следующий эпизод")
+	}
+	return t.ParseSiteTime(r.NextEpisode)
+}
+
+// Получение календаря выхода эпизодов (онгоинги и ближайшие анонсы) через публичное api shikimori.
+//
+// Возвращает срез ссылок на SHCalendarEntry, отсортированный по времени выхода следующего эпизода (аниме без времени - в конце)
+func (sh *ShikimoriParser) Calendar() ([]*SHCalendarEntry, error) {
+	items := make([]*sh_calendar_json, 0)
+	if err := sh.api_get("Calendar", "calendar", nil, &items); err != nil {
+		return nil, err
+	}
+
+	res := make([]*SHCalendarEntry, 0, len(items))
+	for _, item := range items {
+		entry := &SHCalendarEntry{
+			ShikimoriID:   strconv.FormatInt(item.Anime.ID, 10),
+			Title:         item.Anime.Russian,
+			OriginalTitle: item.Anime.Name,
+			Link:          sh.site_link(item.Anime.URL),
+			Poster:        sh.site_link(item.Anime.Image.Original),
+			Type:          item.Anime.Kind,
+			Status:        item.Anime.Status,
+			Episodes:      item.Anime.Episodes,
+			EpisodesAired: item.Anime.EpisodesAired,
+			NextEpisode:   item.NextEpisode,
+		}
+		entry.Score, _ = strconv.ParseFloat(item.Anime.Score, 64)
+		if item.Duration != nil {
+			entry.Duration = *item.Duration
+		}
+		if item.NextEpisodeAt != "" {
+			var err error
+			if entry.NextEpisodeAt, err = t.ParseSiteTime(item.NextEpisodeAt); err != nil {
+				log.Printf("Shikimori parser warning : Calendar : не удалось разобрать время эпизода для %s: %v", entry.ShikimoriID, err)
+			}
+		}
+		res = append(res, entry)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		a, b := res[i].NextEpisodeAt, res[j].NextEpisodeAt
+		// Аниме без времени следующего эпизода - в конце
+		if a.IsZero() || b.IsZero() {
+			return !a.IsZero() && b.IsZero()
+		}
+		return a.Before(b)
+	})
+	return res, nil
+}
+
+// Получение выходящих сейчас аниме (Calendar без анонсов) с номером и временем следующего эпизода
+//
+// Возвращает срез ссылок на SHCalendarEntry, отсортированный по времени выхода следующего эпизода
+func (sh *ShikimoriParser) Ongoings() ([]*SHCalendarEntry, error) {
+	calendar, err := sh.Calendar()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*SHCalendarEntry, 0, len(calendar))
+	for _, entry := range calendar {
+		if entry.Status == "ongoing" {
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+// Получение аниме сезона из каталога shikimori (/animes/season/<сезон>_<год>).
+//
+// :year: год (прим: 2024)
+//
+// :season: сезон: winter, spring, summer, fall (или зима, весна, лето, осень)
+//
+// Загружает все страницы каталога. Если сезон указан неверно, возвращает ошибку errs.PostArgumentsError
+//
+// Возвращает срез ссылок на SHSearchResult в порядке каталога
+func (sh *ShikimoriParser) Season(year int, season string) ([]*SHSearchResult, error) {
+	slug, ok := seasons[strings.ToLower(strings.TrimSpace(season))]
+	if !ok || year < 1900 {
+		error_message := fmt.Sprintf("Shikimori parser error : Season : неверный сезон %q или год %d (сезоны: winter, spring, summer, fall)", season, year)
+		log.Println(error_message)
+		return nil, errs.NewPostArgumentsError(error_message)
+	}
+	URL := fmt.Sprintf("https://%s/animes/season/%s_%d", sh.domain(), slug, year)
+	return sh.catalog(URL, nil, 0, "Season")
+}
+
+// Превращает путь с сайта (прим: /animes/20-naruto) в полную ссылку на активное зеркало
+func (sh *ShikimoriParser) site_link(path string) string {
+	if path == "" {
+		return ""
+	}
+	if strings.HasPrefix(path, "/") {
+		return fmt.Sprintf("https://%s%s", sh.domain(), path)
+	}
+	return sh.mirrors.Rewrite(path)
+}
@@ -0,0 +1,131 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+// Календарь: анонс и онгоинг без времени следующего эпизода, два онгоинга не по порядку времени
+const test_shikimori_calendar = `[
+	{"next_episode": 1, "next_episode_at": null, "duration": null,
+		"anime": {"id": 1, "name": "Announce", "russian": "Анонс", "url": "/animes/1-announce", "kind": "tv", "score": "0.0", "status": "anons",
+			"image": {"original": "/system/animes/original/1.jpg"}}},
+	{"next_episode": 8, "next_episode_at": "2024-10-20T10:00:00.000+03:00", "duration": 24,
+		"anime": {"id": 2, "name": "Later", "russian": "Позже", "url": "/animes/2-later", "kind": "tv", "score": "7.5", "status": "ongoing",
+			"episodes": 12, "episodes_aired": 7}},
+	{"next_episode": 3, "next_episode_at": "",
+		"anime": {"id": 3, "name": "Unknown", "url": "/animes/3-unknown", "kind": "ona", "score": "6.1", "status": "ongoing"}},
+	{"next_episode": 5, "next_episode_at": "2024-10-19T17:30:00.000+03:00", "duration": 23,
+		"anime": {"id": 4, "name": "Sooner", "russian": "Раньше", "url": "/animes/4-sooner", "kind": "tv", "score": "8.2", "status": "ongoing",
+			"episodes": 0, "episodes_aired": 4}}
+]`
+
+// Каталог сезона: первая страница со ссылкой на вторую
+const test_shikimori_season_page_1 = `<article class="c-anime" id="52991">
+	<a class="cover" href="/animes/52991-sousou-no-frieren"><img src="/system/animes/preview/52991.jpg"></a>
+	<a class="title"><span class="name-ru">Провожающая в последний путь Фрирен</span><span class="name-en">Sousou no Frieren</span></a>
+	<div class="misc"><span>TV Сериал</span><span class="right">2023</span></div>
+</article>
+<article class="c-anime" id="51009">
+	<a class="cover" href="/animes/51009-jujutsu-kaisen-2nd-season"></a>
+	<a class="title">Магическая битва 2</a>
+</article>
+<a class="link-next" href="/animes/season/fall_2023/page/2">Далее</a>`
+
+const test_shikimori_season_page_2 = `<article class="c-anime" id="51009"><a class="cover" href="/animes/51009-jujutsu-kaisen-2nd-season"></a></article>
+<article class="c-anime" id="54595"><a class="cover" href="/animes/54595-kage-no-jitsuryokusha-ni-naritakute-2nd-season"></a><a class="title">Восхождение в тени 2</a></article>`
+
+func new_test_calendar_parser(test *testing.T) *ShikimoriParser {
+	use_test_sites(test, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Host + r.URL.Path {
+		case "shikimori.one/api/calendar":
+			fmt.Fprint(w, test_shikimori_calendar)
+		case "shikimori.one/animes/season/fall_2023":
+			fmt.Fprint(w, test_shikimori_season_page_1)
+		case "shikimori.one/animes/season/fall_2023/page/2":
+			fmt.Fprint(w, test_shikimori_season_page_2)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	sh := NewShikimoriParser("shikimori.one")
+	sh.api_pacer.interval = 0
+	return sh
+}
+
+func TestCalendar(test *testing.T) {
+	sh := new_test_calendar_parser(test)
+
+	calendar, err := sh.Calendar()
+	if err != nil {
+		test.Fatalf("Calendar вернул ошибку: %v", err)
+	}
+	ids := make([]string, 0, len(calendar))
+	for _, entry := range calendar {
+		ids = append(ids, entry.ShikimoriID)
+	}
+	// По времени следующего эпизода, аниме без времени - в конце в порядке api
+	if fmt.Sprint(ids) != "[4 2 1 3]" {
+		test.Errorf("порядок календаря %v, want [4 2 1 3]", ids)
+	}
+
+	sooner := calendar[0]
+	if sooner.Title != "Раньше" || sooner.OriginalTitle != "Sooner" || sooner.Link != "https://shikimori.one/animes/4-sooner" ||
+		sooner.Score != 8.2 || sooner.NextEpisode != 5 || sooner.Duration != 23 || sooner.EpisodesAired != 4 {
+		test.Errorf("Calendar[0] = %+v", sooner)
+	}
+	if !sooner.NextEpisodeAt.Equal(time.Date(2024, 10, 19, 14, 30, 0, 0, time.UTC)) {
+		test.Errorf("время эпизода Calendar[0] = %v", sooner.NextEpisodeAt)
+	}
+	if announce := calendar[2]; announce.Status != "anons" || !announce.NextEpisodeAt.IsZero() || announce.Duration != 0 ||
+		announce.Poster != "https://shikimori.one/system/animes/original/1.jpg" {
+		test.Errorf("Calendar[2] = %+v", announce)
+	}
+
+	ongoings, err := sh.Ongoings()
+	if err != nil {
+		test.Fatalf("Ongoings вернул ошибку: %v", err)
+	}
+	ids = ids[:0]
+	for _, entry := range ongoings {
+		ids = append(ids, entry.ShikimoriID)
+	}
+	if fmt.Sprint(ids) != "[4 2 3]" {
+		test.Errorf("Ongoings = %v, want [4 2 3]", ids)
+	}
+}
+
+func TestSeason(test *testing.T) {
+	sh := new_test_calendar_parser(test)
+
+	res, err := sh.Season(2023, "Осень")
+	if err != nil {
+		test.Fatalf("Season вернул ошибку: %v", err)
+	}
+	ids := make([]string, 0, len(res))
+	for _, entry := range res {
+		ids = append(ids, entry.ShikimoriID)
+	}
+	if fmt.Sprint(ids) != "[52991 51009 54595]" {
+		test.Errorf("Season = %v, want [52991 51009 54595]", ids)
+	}
+	if first := res[0]; first.Title != "Провожающая в последний путь Фрирен" || first.OriginalTitle != "Sousou no Frieren" ||
+		first.Link != "https://shikimori.one/animes/52991-sousou-no-frieren" || first.Type != "TV Сериал" || first.Year != "2023" {
+		test.Errorf("Season[0] = %+v", first)
+	}
+
+	var arguments *errs.PostArgumentsError
+	for _, season := range []string{"демисезон", ""} {
+		if _, err := sh.Season(2023, season); !errors.As(err, &arguments) {
+			test.Errorf("Season(2023, %q) вернул %T: %v, want *errs.PostArgumentsError", season, err, err)
+		}
+	}
+	if _, err := sh.Season(0, SeasonFall); !errors.As(err, &arguments) {
+		test.Errorf("Season(0, fall) вернул %T: %v, want *errs.PostArgumentsError", err, err)
+	}
+}
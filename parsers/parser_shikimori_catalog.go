@@ -0,0 +1,115 @@
+package parsers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Разбирает карточки аниме каталога shikimori (article.c-anime на страницах /animes, /animes/season/...)
+func parse_catalog(doc *goquery.Document) []*SHSearchResult {
+	res := make([]*SHSearchResult, 0)
+	doc.Find("article.c-anime, article.b-catalog_entry").Each(func(i int, s *goquery.Selection) {
+		c_data := &SHSearchResult{Genres: make([]string, 0)}
+
+		cover := s.Find("a.cover, a.title").First()
+		link, exists := cover.Attr("href")
+		if !exists || link == "" {
+			log.Println("Shikimori parser error : parse_catalog : goquery не смог найти атрибут href в article.c-anime:a.cover")
+			return
+		}
+		c_data.Link = link
+		if id, exists := s.Attr("id"); exists && id != "" {
+			c_data.ShikimoriID = id
+		} else {
+			c_data.ShikimoriID = ShikimoriIDFromLink(link)
+		}
+
+		title := s.Find(".title").First()
+		c_data.Title = strings.TrimSpace(title.Find(".name-ru").First().Text())
+		c_data.OriginalTitle = strings.TrimSpace(title.Find(".name-en").First().Text())
+		if c_data.Title == "" {
+			c_data.Title = strings.TrimSpace(title.Text())
+		}
+
+		img := s.Find("picture img, img").First()
+		if srcset := img_srcset(img); srcset != "" {
+			c_data.PosterSrcset = srcset
+			c_data.Poster = t.SrcsetVariant(srcset, 1)
+		}
+
+		misc := s.Find(".misc").First()
+		c_data.Type = strings.TrimSpace(misc.Find("span").Not(".right").First().Text())
+		c_data.Year = strings.TrimSpace(misc.Find("span.right").First().Text())
+		if c_data.Year == "" {
+			if year := t.ParseYear(misc.Text()); year != 0 {
+				c_data.Year = fmt.Sprint(year)
+			}
+		}
+
+		res = append(res, c_data)
+	})
+	return res
+}
+
+// Загружает страницы каталога shikimori, начиная с URL, пока есть ссылка на следующую страницу.
+//
+// :URL: ссылка на первую страницу (прим: https://shikimori.one/animes/season/summer_2024)
+//
+// :params: параметры ссылки (фильтры каталога)
+//
+// :max_pages: максимальное количество страниц (0 - без ограничения, но не больше maxUserListPages)
+//
+// :method: название вызывающего метода для сообщений об ошибках
+func (sh *ShikimoriParser) catalog(URL string, params models.Params, max_pages int, method string) ([]*SHSearchResult, error) {
+	if max_pages <= 0 || max_pages > maxUserListPages {
+		max_pages = maxUserListPages
+	}
+	headers := models.Headers{
+		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0",
+	}
+
+	res := make([]*SHSearchResult, 0)
+	seen := make(map[string]bool)
+	for page := 1; URL != "" && page <= max_pages; page++ {
+		resp, err := sh.mirrors.Request(sh.context, "GET", URL, params, headers, false, nil)
+		if err != nil {
+			error_message := fmt.Sprintf("Shikimori parser error : %s : RequestWithContext вернул ошибку: %v", method, err)
+			log.Println(error_message)
+			return nil, t.WrapRequestError(err, error_message)
+		}
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Data))
+		if err != nil {
+			error_message := fmt.Sprintf("Shikimori parser error : %s : goquery не смог преобразовать ответ в документ. Ошибка: %v", method, err)
+			log.Println(error_message)
+			return nil, errs.NewServiceError(error_message)
+		}
+
+		added := 0
+		for _, entry := range parse_catalog(doc) {
+			if seen[entry.ShikimoriID] {
+				continue
+			}
+			seen[entry.ShikimoriID] = true
+			if strings.HasPrefix(entry.Link, "/") {
+				entry.Link = fmt.Sprintf("https://%s%s", sh.domain(), entry.Link)
+			}
+			entry.Link = sh.mirrors.Rewrite(entry.Link)
+			res = append(res, entry)
+			added++
+		}
+		if added == 0 {
+			break
+		}
+		// Ссылка на следующую страницу уже содержит параметры фильтров
+		URL = next_list_page(doc, resp.Response.Request.URL.String())
+		params = nil
+	}
+	return res, nil
+}
@@ -0,0 +1,242 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+// Глубина обхода франшизы по умолчанию (если в Franchise передана глубина <= 0)
+const defaultFranchiseDepth = 10
+
+// Максимальное количество узлов графа франшизы (защита от обхода "всего shikimori" через связи adaptation/character)
+const maxFranchiseNodes = 300
+
+// Вид узла графа франшизы
+const (
+	FranchiseAnime = "anime"
+	FranchiseManga = "manga"
+)
+
+// Типы связей SHFranchiseEdge.Relation (relation из api shikimori в snake_case)
+const (
+	RelationSequel             = "sequel"
+	RelationPrequel            = "prequel"
+	RelationSideStory          = "side_story"
+	RelationParentStory        = "parent_story"
+	RelationSummary            = "summary"
+	RelationFullStory          = "full_story"
+	RelationAlternativeVersion = "alternative_version"
+	RelationAlternativeSetting = "alternative_setting"
+	RelationSpinOff            = "spin_off"
+	RelationAdaptation         = "adaptation"
+	RelationCharacter          = "character"
+	RelationOther              = "other"
+)
+
+// id и вид тайтла в ссылке shikimori (прим: https://shikimori.one/mangas/z11-naruto > mangas, 11)
+var shikimori_entry_re = regexp.MustCompile(`/(animes|mangas|ranobe)/[a-z]*(\d+)`)
+
+type SHFranchiseNode struct {
+	ShikimoriID string `json:"shikimori_id"`
+	// FranchiseAnime или FranchiseManga (ранобэ считается мангой)
+	Target        string `json:"target"`
+	Title         string `json:"title"`
+	OriginalTitle string `json:"original_title"`
+	Link          string `json:"link"`
+	// Тип по shikimori (прим: tv, movie, ova, manga, light_novel)
+	Kind string `json:"kind"`
+	// Дата начала выхода в формате 2006-01-02 ("" - неизвестно)
+	AiredOn string `json:"aired_on"`
+	// Расстояние от начального тайтла (количество связей)
+	Depth int `json:"depth"`
+}
+
+// Ключ узла в SHFranchiseGraph.Nodes (прим: anime:20)
+func (n *SHFranchiseNode) Key() string {
+	return n.Target + ":" + n.ShikimoriID
+}
+
+type SHFranchiseEdge struct {
+	// Ключи узлов (SHFranchiseNode.Key)
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Тип связи (прим: RelationSequel)
+	Relation string `json:"relation"`
+	// Тип связи на русском (прим: Продолжение)
+	RelationRU string `json:"relation_ru"`
+}
+
+type SHFranchiseGraph struct {
+	// Ключ начального узла
+	Root  string                      `json:"root"`
+	Nodes map[string]*SHFranchiseNode `json:"nodes"`
+	Edges []*SHFranchiseEdge          `json:"edges"`
+	// true, если у тайтлов графа есть связи с тайтлами, не вошедшими в него из-за ограничения глубины или количества узлов
+	Truncated bool `json:"truncated"`
+}
+
+// Исходящие связи узла
+func (g *SHFranchiseGraph) EdgesFrom(key string) []*SHFranchiseEdge {
+	res := make([]*SHFranchiseEdge, 0)
+	for _, edge := range g.Edges {
+		if edge.From == key {
+			res = append(res, edge)
+		}
+	}
+	return res
+}
+
+// Порядок просмотра: аниме графа, отсортированные по дате начала выхода.
+// Аниме без даты (анонсы) идут в конце, при равных датах порядок определяется по id
+func (g *SHFranchiseGraph) WatchOrder() []*SHFranchiseNode {
+	res := make([]*SHFranchiseNode, 0)
+	for _, node := range g.Nodes {
+		if node.Target == FranchiseAnime {
+			res = append(res, node)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if a.AiredOn != b.AiredOn {
+			if a.AiredOn == "" || b.AiredOn == "" {
+				return b.AiredOn == ""
+			}
+			return a.AiredOn < b.AiredOn
+		}
+		id_a, _ := strconv.Atoi(a.ShikimoriID)
+		id_b, _ := strconv.Atoi(b.ShikimoriID)
+		return id_a < id_b
+	})
+	return res
+}
+
+// Тайтл в ответах api shikimori (/api/animes/:id, поле anime/manga в /related)
+type sh_entry_json struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Russian string `json:"russian"`
+	URL     string `json:"url"`
+	Kind    string `json:"kind"`
+	AiredOn string `json:"aired_on"`
+}
+
+// Элемент ответа /api/animes/:id/related
+type sh_related_json struct {
+	Relation        string         `json:"relation"`
+	RelationRussian string         `json:"relation_russian"`
+	Anime           *sh_entry_json `json:"anime"`
+	Manga           *sh_entry_json `json:"manga"`
+}
+
+// Приводит relation из api (прим: "Side Story") к константе Relation* (прим: side_story)
+func normalize_relation(relation string) string {
+	relation = strings.ToLower(strings.TrimSpace(relation))
+	relation = strings.NewReplacer(" ", "_", "-", "_").Replace(relation)
+	if relation == "" {
+		return RelationOther
+	}
+	return relation
+}
+
+func (sh *ShikimoriParser) franchise_node(entry *sh_entry_json, target string, depth int) *SHFranchiseNode {
+	return &SHFranchiseNode{
+		ShikimoriID:   strconv.FormatInt(entry.ID, 10),
+		Target:        target,
+		Title:         entry.Russian,
+		OriginalTitle: entry.Name,
+		Link:          sh.site_link(entry.URL),
+		Kind:          entry.Kind,
+		AiredOn:       entry.AiredOn,
+		Depth:         depth,
+	}
+}
+
+// Обход связей (SHRelated) в ширину, начиная с тайтла, для построения графа франшизы.
+// Каждый тайтл посещается один раз, поэтому циклы (продолжение <-> предыстория) не приводят к повторным запросам:
+// связь с уже посещенным тайтлом только добавляется в Edges.
+// Запросы к api выполняются последовательно с интервалом shikimoriAPIInterval, поэтому обход большой франшизы занимает минуты.
+//
+// :shikimori_link: ссылка на аниме или мангу на shikimori (прим: https://shikimori.one/animes/z20-naruto)
+//
+// :max_depth: максимальное количество связей от начального тайтла (<= 0 - defaultFranchiseDepth)
+//
+// Связи ведут только к узлам из Nodes. Если не удалось загрузить связи части тайтлов, возвращает граф без них вместе с ошибкой errors.Join(...)
+//
+// Возвращает ссылку на SHFranchiseGraph
+func (sh *ShikimoriParser) Franchise(shikimori_link string, max_depth int) (*SHFranchiseGraph, error) {
+	match := shikimori_entry_re.FindStringSubmatch(shikimori_link)
+	if match == nil {
+		error_message := fmt.Sprintf("Shikimori parser error : Franchise : не удалось получить id из ссылки %q", shikimori_link)
+		log.Println(error_message)
+		return nil, errs.NewPostArgumentsError(error_message)
+	}
+	if max_depth <= 0 {
+		max_depth = defaultFranchiseDepth
+	}
+	target := FranchiseAnime
+	if match[1] != "animes" {
+		target = FranchiseManga
+	}
+
+	root_json := &sh_entry_json{}
+	if err := sh.api_get("Franchise", fmt.Sprintf("%ss/%s", target, match[2]), nil, root_json); err != nil {
+		return nil, err
+	}
+	root := sh.franchise_node(root_json, target, 0)
+	graph := &SHFranchiseGraph{
+		Root:  root.Key(),
+		Nodes: map[string]*SHFranchiseNode{root.Key(): root},
+		Edges: make([]*SHFranchiseEdge, 0),
+	}
+
+	errors_list := make([]error, 0)
+	queue := []*SHFranchiseNode{root}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		// Связи тайтлов на максимальной глубине загружаются, чтобы добавить связи между тайтлами графа
+		// и отметить Truncated, только если за ограничением действительно есть тайтлы
+		related := make([]*sh_related_json, 0)
+		if err := sh.api_get("Franchise", fmt.Sprintf("%ss/%s/related", node.Target, node.ShikimoriID), nil, &related); err != nil {
+			errors_list = append(errors_list, err)
+			continue
+		}
+		for _, item := range related {
+			entry, entry_target := item.Anime, FranchiseAnime
+			if entry == nil {
+				entry, entry_target = item.Manga, FranchiseManga
+			}
+			if entry == nil || entry.ID == 0 {
+				continue
+			}
+			next := sh.franchise_node(entry, entry_target, node.Depth+1)
+			if _, visited := graph.Nodes[next.Key()]; !visited {
+				if node.Depth >= max_depth || len(graph.Nodes) >= maxFranchiseNodes {
+					graph.Truncated = true
+					continue
+				}
+				graph.Nodes[next.Key()] = next
+				queue = append(queue, next)
+			}
+			graph.Edges = append(graph.Edges, &SHFranchiseEdge{
+				From:       node.Key(),
+				To:         next.Key(),
+				Relation:   normalize_relation(item.Relation),
+				RelationRU: item.RelationRussian,
+			})
+		}
+	}
+
+	if len(errors_list) > 0 {
+		return graph, errors.Join(errors_list...)
+	}
+	return graph, nil
+}
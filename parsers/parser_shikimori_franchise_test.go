@@ -0,0 +1,128 @@
+package parsers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// api shikimori для франшизы: 1 <-> 2 <-> 3 (продолжение и предыстория), у 1 есть манга 11.
+// У 2 нет даты начала выхода (анонс)
+func test_shikimori_franchise(w http.ResponseWriter, r *http.Request) {
+	entry := func(id int64, kind, aired_on string) *sh_entry_json {
+		return &sh_entry_json{ID: id, Name: "Title", Russian: "Тайтл", URL: "/animes/" + kind, Kind: kind, AiredOn: aired_on}
+	}
+	naruto, shippuuden, boruto := entry(1, "tv", "2002-10-03"), entry(2, "tv", ""), entry(3, "tv", "2007-02-15")
+	manga := &sh_entry_json{ID: 11, Name: "Naruto", URL: "/mangas/11-naruto", Kind: "manga", AiredOn: "1999-09-21"}
+
+	switch r.URL.Host + r.URL.Path {
+	case "shikimori.one/api/animes/1":
+		write_test_json(w, naruto)
+	case "shikimori.one/api/animes/1/related":
+		write_test_json(w, []*sh_related_json{
+			{Relation: "Sequel", RelationRussian: "Продолжение", Anime: shippuuden},
+			{Relation: "Adaptation", RelationRussian: "Адаптация", Manga: manga},
+		})
+	case "shikimori.one/api/animes/2/related":
+		write_test_json(w, []*sh_related_json{
+			{Relation: "Prequel", RelationRussian: "Предыстория", Anime: naruto},
+			{Relation: "Sequel", RelationRussian: "Продолжение", Anime: boruto},
+		})
+	case "shikimori.one/api/animes/3/related":
+		write_test_json(w, []*sh_related_json{{Relation: "Prequel", RelationRussian: "Предыстория", Anime: shippuuden}})
+	case "shikimori.one/api/mangas/11/related":
+		write_test_json(w, []*sh_related_json{})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func new_test_franchise_parser(test *testing.T) (*test_sites, *ShikimoriParser) {
+	sites := use_test_sites(test, test_shikimori_franchise)
+	sh := NewShikimoriParserWithMirrors("shikimori.one")
+	sh.api_pacer.interval = 0
+	return sites, sh
+}
+
+func TestFranchiseCycle(test *testing.T) {
+	sites, sh := new_test_franchise_parser(test)
+	graph, err := sh.Franchise("https://shikimori.one/animes/z1-naruto", 0)
+	if err != nil {
+		test.Fatalf("Franchise вернул ошибку: %v", err)
+	}
+	if graph.Root != "anime:1" || len(graph.Nodes) != 4 || graph.Truncated {
+		test.Errorf("Franchise = %+v", graph)
+	}
+	if node := graph.Nodes["anime:3"]; node == nil || node.Depth != 2 {
+		test.Errorf("узел anime:3 = %+v", node)
+	}
+
+	edges := make([]string, 0)
+	for _, edge := range graph.Edges {
+		edges = append(edges, edge.From+">"+edge.To+":"+edge.Relation)
+	}
+	if got := strings.Join(edges, " "); got != "anime:1>anime:2:sequel anime:1>manga:11:adaptation anime:2>anime:1:prequel anime:2>anime:3:sequel anime:3>anime:2:prequel" {
+		test.Errorf("Edges = %s", got)
+	}
+
+	// Каждый тайтл запрашивается один раз, каждый запрос отправляется одним воркером
+	if count := sites.count(); count != 5 {
+		test.Errorf("Franchise выполнил %d запросов, want 5", count)
+	}
+}
+
+func TestFranchiseDepth(test *testing.T) {
+	_, sh := new_test_franchise_parser(test)
+
+	// На глубине 1 у Shippuuden остается продолжение, не вошедшее в граф
+	graph, err := sh.Franchise("https://shikimori.one/animes/1", 1)
+	if err != nil {
+		test.Fatalf("Franchise вернул ошибку: %v", err)
+	}
+	if len(graph.Nodes) != 3 || graph.Nodes["anime:3"] != nil || !graph.Truncated {
+		test.Errorf("Franchise(1) = %+v", graph)
+	}
+	if edges := graph.EdgesFrom("anime:2"); len(edges) != 1 || edges[0].To != "anime:1" {
+		test.Errorf("связи anime:2 = %+v", edges)
+	}
+
+	// На глубине 2 у крайнего тайтла только связь назад: граф полный
+	graph, err = sh.Franchise("https://shikimori.one/animes/1", 2)
+	if err != nil {
+		test.Fatalf("Franchise вернул ошибку: %v", err)
+	}
+	if len(graph.Nodes) != 4 || graph.Truncated {
+		test.Errorf("Franchise(2) = %+v", graph)
+	}
+}
+
+func TestFranchiseWatchOrder(test *testing.T) {
+	_, sh := new_test_franchise_parser(test)
+	graph, err := sh.Franchise("https://shikimori.one/animes/1", 0)
+	if err != nil {
+		test.Fatalf("Franchise вернул ошибку: %v", err)
+	}
+	order := make([]string, 0)
+	for _, node := range graph.WatchOrder() {
+		order = append(order, node.ShikimoriID)
+	}
+	// Манга не входит в порядок просмотра, анонс без даты - в конце
+	if got := strings.Join(order, ","); got != "1,3,2" {
+		test.Errorf("WatchOrder = %s, want 1,3,2", got)
+	}
+}
+
+func TestShikimoriAPIPacing(test *testing.T) {
+	_, sh := new_test_franchise_parser(test)
+	sh.api_pacer.interval = 30 * time.Millisecond
+
+	start := time.Now()
+	if _, err := sh.Franchise("https://shikimori.one/animes/1", 0); err != nil {
+		test.Fatalf("Franchise вернул ошибку: %v", err)
+	}
+	// 5 запросов - не меньше 4 интервалов
+	if elapsed := time.Since(start); elapsed < 4*sh.api_pacer.interval {
+		test.Errorf("5 запросов выполнены за %v, want не меньше %v", elapsed, 4*sh.api_pacer.interval)
+	}
+}
@@ -0,0 +1,289 @@
+package parsers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Количество комментариев на странице /api/comments (максимум api)
+const commentsPageLimit = 30
+
+// Мнения обзоров в SHReview.Opinion
+const (
+	OpinionPositive = "positive"
+	OpinionNeutral  = "neutral"
+	OpinionNegative = "negative"
+)
+
+var review_opinions = map[string]string{
+	"положительный": OpinionPositive, "positive": OpinionPositive,
+	"нейтральный": OpinionNeutral, "neutral": OpinionNeutral,
+	"отрицательный": OpinionNegative, "negative": OpinionNegative,
+}
+
+type SHReview struct {
+	ID         string `json:"id"`
+	Author     string `json:"author"`
+	AuthorLink string `json:"author_link"`
+	Link       string `json:"link"`
+	// OpinionPositive, OpinionNeutral, OpinionNegative или "" (старые обзоры с оценкой)
+	Opinion string `json:"opinion"`
+	// Оценка автора от 1 до 10 (0 - не указана)
+	Score int       `json:"score"`
+	Text  string    `json:"text"`
+	Date  time.Time `json:"date"`
+	// Реакции на обзор (прим: {"Полезно": 12, "Не полезно": 3})
+	Reactions map[string]int `json:"reactions"`
+}
+
+type SHComment struct {
+	ID         string    `json:"id"`
+	Author     string    `json:"author"`
+	AuthorLink string    `json:"author_link"`
+	Avatar     string    `json:"avatar"`
+	Text       string    `json:"text"`
+	HTML       string    `json:"html"`
+	Date       time.Time `json:"date"`
+	IsOfftopic bool      `json:"is_offtopic"`
+	// Комментарий отмечен как отзыв
+	IsSummary bool `json:"is_summary"`
+}
+
+// Элемент ответа /api/comments
+type sh_comment_json struct {
+	ID         int64  `json:"id"`
+	Body       string `json:"body"`
+	HTMLBody   string `json:"html_body"`
+	CreatedAt  string `json:"created_at"`
+	IsOfftopic bool   `json:"is_offtopic"`
+	IsSummary  bool   `json:"is_summary"`
+	User       struct {
+		Nickname string `json:"nickname"`
+		Avatar   string `json:"avatar"`
+		URL      string `json:"url"`
+	} `json:"user"`
+}
+
+// Получение обзоров аниме со страницы /reviews с переходом по страницам.
+//
+// :shikimori_link: ссылка на страницу шикимори с информацией (прим: https://shikimori.one/animes/z20-naruto)
+//
+// :max_pages: максимальное количество страниц (0 - все страницы, но не больше maxUserListPages)
+//
+// Возвращает срез ссылок на SHReview в порядке сайта (сначала новые)
+func (sh *ShikimoriParser) Reviews(shikimori_link string, max_pages int) ([]*SHReview, error) {
+	if max_pages <= 0 || max_pages > maxUserListPages {
+		max_pages = maxUserListPages
+	}
+	headers := models.Headers{
+		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0",
+	}
+	URL := strings.TrimSuffix(shikimori_link, "/") + "/reviews"
+
+	res := make([]*SHReview, 0)
+	seen := make(map[string]bool)
+	for page := 1; URL != "" && page <= max_pages; page++ {
+		resp, err := sh.mirrors.Request(sh.context, "GET", URL, nil, headers, false, nil)
+		if err != nil {
+			error_message := fmt.Sprintf("Shikimori parser error : Reviews : RequestWithContext вернул ошибку: %v", err)
+			log.Println(error_message)
+			return nil, t.WrapRequestError(err, error_message)
+		}
+
+		// Следующие страницы (postloader) могут приходить как json с html в поле content
+		data := resp.Data
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+			jr := &SHJsonResponse{}
+			if err := jr.Decode(bytes.NewReader(trimmed)); err == nil {
+				data = []byte(jr.Content)
+			}
+		}
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+		if err != nil {
+			error_message := fmt.Sprintf("Shikimori parser error : Reviews : goquery не смог преобразовать ответ в документ. Ошибка: %v", err)
+			log.Println(error_message)
+			return nil, errs.NewServiceError(error_message)
+		}
+		if page == 1 && shikimori_age_gate(doc) {
+			return nil, age_restricted_error("Shikimori", "Reviews", URL, sh.authenticated())
+		}
+
+		added := 0
+		for _, review := range sh.parse_reviews(doc) {
+			if review.ID != "" && seen[review.ID] {
+				continue
+			}
+			seen[review.ID] = true
+			res = append(res, review)
+			added++
+		}
+		if added == 0 {
+			break
+		}
+		URL = next_list_page(doc, URL)
+	}
+	return res, nil
+}
+
+// Разбирает обзоры страницы (article.b-review-topic)
+func (sh *ShikimoriParser) parse_reviews(doc *goquery.Document) []*SHReview {
+	res := make([]*SHReview, 0)
+	doc.Find("article.b-review-topic, .b-review-topic, article.b-review").Each(func(i int, s *goquery.Selection) {
+		// Вложенные элементы с тем же классом уже обработаны вместе с родителем
+		if s.ParentsFiltered(".b-review-topic, article.b-review").Length() > 0 {
+			return
+		}
+		c_data := &SHReview{Reactions: make(map[string]int)}
+		for _, attr := range []string{"data-id", "id"} {
+			if id, exists := s.Attr(attr); exists && id != "" {
+				c_data.ID = id
+				break
+			}
+		}
+
+		author := s.Find(".b-author .name, a.name, .name").First()
+		c_data.Author = strings.TrimSpace(author.Text())
+		if href, exists := author.Attr("href"); exists {
+			c_data.AuthorLink = sh.site_link(href)
+		} else if href, exists := s.Find(".b-author a").First().Attr("href"); exists {
+			c_data.AuthorLink = sh.site_link(href)
+		}
+		if href, exists := s.Find("a.b-link[href*=\"/reviews/\"], a[href*=\"/reviews/\"]").First().Attr("href"); exists {
+			c_data.Link = sh.site_link(href)
+		}
+
+		if datetime, exists := s.Find("time[datetime]").First().Attr("datetime"); exists {
+			if date, err := t.ParseSiteTime(datetime); err == nil {
+				c_data.Date = date
+			}
+		}
+
+		body := s.Find(".body, .b-review_topic-body, .review-body").First()
+		c_data.Text = strings.TrimSpace(body.Text())
+
+		opinion := strings.ToLower(strings.TrimSpace(s.Find(".b-review_opinion, .opinion, [data-opinion]").First().Text()))
+		if value, exists := s.Find("[data-opinion]").First().Attr("data-opinion"); exists {
+			opinion = strings.ToLower(value)
+		}
+		for key, value := range review_opinions {
+			if strings.Contains(opinion, key) {
+				c_data.Opinion = value
+				break
+			}
+		}
+
+		if score, exists := s.Find("[data-score]").First().Attr("data-score"); exists {
+			c_data.Score, _ = strconv.Atoi(strings.TrimSpace(score))
+		} else {
+			c_data.Score, _ = strconv.Atoi(strings.TrimSpace(s.Find(".score-value, .rate-score").First().Text()))
+		}
+
+		s.Find("[data-reaction], .b-reaction, .vote").Each(func(i int, reaction *goquery.Selection) {
+			name, exists := reaction.Attr("data-reaction")
+			if !exists || name == "" {
+				name, _ = reaction.Attr("title")
+			}
+			numbers := t.ParseNumbers(reaction.Text())
+			if name == "" || len(numbers) == 0 {
+				return
+			}
+			c_data.Reactions[strings.TrimSpace(name)] += numbers[len(numbers)-1]
+		})
+
+		if c_data.Text == "" && c_data.Author == "" {
+			log.Println("Shikimori parser warning : Reviews : в обзоре не найдены автор и текст")
+			return
+		}
+		res = append(res, c_data)
+	})
+	return res
+}
+
+// Получение комментариев обсуждения аниме (основной топик) через api shikimori с переходом по страницам.
+//
+// :shikimori_link: ссылка на страницу шикимори с информацией (прим: https://shikimori.one/animes/z20-naruto)
+//
+// :max_pages: максимальное количество страниц по commentsPageLimit комментариев (0 - все страницы, но не больше maxUserListPages)
+//
+// # Если у аниме нет топика обсуждения, возвращает ошибку errs.NoResults
+//
+// Возвращает срез ссылок на SHComment (сначала новые)
+func (sh *ShikimoriParser) Comments(shikimori_link string, max_pages int) ([]*SHComment, error) {
+	shikimori_id := ShikimoriIDFromLink(shikimori_link)
+	if shikimori_id == "" {
+		error_message := fmt.Sprintf("Shikimori parser error : Comments : не удалось получить id аниме из ссылки %q", shikimori_link)
+		log.Println(error_message)
+		return nil, errs.NewPostArgumentsError(error_message)
+	}
+	if max_pages <= 0 || max_pages > maxUserListPages {
+		max_pages = maxUserListPages
+	}
+
+	anime := &struct {
+		TopicID int64 `json:"topic_id"`
+	}{}
+	if err := sh.api_get("Comments", "animes/"+shikimori_id, nil, anime); err != nil {
+		return nil, err
+	}
+	if anime.TopicID == 0 {
+		error_message := fmt.Sprintf("Shikimori parser error : Comments : у аниме %s нет топика обсуждения", shikimori_id)
+		log.Println(error_message)
+		return nil, errs.NewNoResultsError(error_message)
+	}
+	return sh.TopicComments(strconv.FormatInt(anime.TopicID, 10), max_pages)
+}
+
+// Получение комментариев топика shikimori через api с переходом по страницам.
+//
+// :topic_id: id топика (прим: 1234)
+//
+// :max_pages: максимальное количество страниц по commentsPageLimit комментариев (0 - все страницы, но не больше maxUserListPages)
+//
+// Возвращает срез ссылок на SHComment (сначала новые)
+func (sh *ShikimoriParser) TopicComments(topic_id string, max_pages int) ([]*SHComment, error) {
+	if max_pages <= 0 || max_pages > maxUserListPages {
+		max_pages = maxUserListPages
+	}
+	res := make([]*SHComment, 0)
+	for page := 1; page <= max_pages; page++ {
+		params := models.Params{
+			"commentable_id":   topic_id,
+			"commentable_type": "Topic",
+			"page":             strconv.Itoa(page),
+			"limit":            strconv.Itoa(commentsPageLimit),
+			"desc":             "1",
+		}
+		items := make([]*sh_comment_json, 0)
+		if err := sh.api_get("TopicComments", "comments", params, &items); err != nil {
+			return nil, err
+		}
+		items, more := api_page(items, commentsPageLimit)
+		for _, item := range items {
+			c_data := &SHComment{
+				ID:         strconv.FormatInt(item.ID, 10),
+				Author:     item.User.Nickname,
+				AuthorLink: sh.site_link(item.User.URL),
+				Avatar:     item.User.Avatar,
+				Text:       item.Body,
+				HTML:       item.HTMLBody,
+				IsOfftopic: item.IsOfftopic,
+				IsSummary:  item.IsSummary,
+			}
+			c_data.Date, _ = t.ParseSiteTime(item.CreatedAt)
+			res = append(res, c_data)
+		}
+		if !more {
+			break
+		}
+	}
+	return res, nil
+}
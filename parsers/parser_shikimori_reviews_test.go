@@ -0,0 +1,184 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+// Первая страница обзоров: новый обзор с мнением и реакциями, обзор с вложенным блоком того же класса и старый обзор с оценкой
+const test_shikimori_reviews_page_1 = `<article class="b-review-topic" data-id="101">
+	<div class="b-author"><a class="name" href="/users/alice">alice</a></div>
+	<a class="b-link" href="/animes/z20-naruto/reviews/101">Обзор</a>
+	<time datetime="2024-10-19T17:30:00.000+03:00">19 октября</time>
+	<div class="b-review_opinion">Положительный</div>
+	<div class="body">Отличное аниме</div>
+	<span data-reaction="Полезно">12</span>
+	<span class="b-reaction" title="Не полезно">Не полезно: 3</span>
+</article>
+<article class="b-review-topic" data-id="102">
+	<div class="b-author"><a class="name" href="/users/bob">bob</a></div>
+	<div data-opinion="negative" data-score="4"></div>
+	<div class="body">Затянуто<div class="b-review-topic" data-id="999">вложенный блок</div></div>
+</article>
+<article class="b-review" id="103">
+	<a class="name" href="/users/carol">carol</a>
+	<span class="score-value">8</span>
+	<div class="body">Старый обзор</div>
+</article>
+<a class="link-next" href="/animes/z20-naruto/reviews?page=2">Далее</a>`
+
+// Вторая страница приходит как json postloader: повтор обзора 103 и новый обзор 104
+const test_shikimori_reviews_page_2 = `<article class="b-review" id="103"><a class="name" href="/users/carol">carol</a><div class="body">Старый обзор</div></article>
+<article class="b-review-topic" data-id="104"><a class="name" href="/users/dave">dave</a><div class="body">Нейтральный обзор</div><div class="opinion">нейтральный</div></article>
+<div class="b-postloader" data-href="/animes/z20-naruto/reviews?page=3"></div>`
+
+// Третья страница без новых обзоров: загрузка останавливается, несмотря на ссылку на следующую страницу
+const test_shikimori_reviews_page_3 = `<article class="b-review-topic" data-id="104"><a class="name" href="/users/dave">dave</a><div class="body">Нейтральный обзор</div></article>
+<a class="link-next" href="/animes/z20-naruto/reviews?page=4">Далее</a>`
+
+func TestParseReviews(test *testing.T) {
+	sh := NewShikimoriParser("shikimori.one")
+	reviews := sh.parse_reviews(test_goquery(test, test_shikimori_reviews_page_1))
+	if len(reviews) != 3 {
+		test.Fatalf("parse_reviews вернул %d обзоров, want 3", len(reviews))
+	}
+
+	first := reviews[0]
+	if first.ID != "101" || first.Author != "alice" || first.AuthorLink != "https://shikimori.one/users/alice" ||
+		first.Link != "https://shikimori.one/animes/z20-naruto/reviews/101" || first.Text != "Отличное аниме" ||
+		first.Opinion != OpinionPositive || first.Score != 0 {
+		test.Errorf("обзор 0 = %+v", first)
+	}
+	if !first.Date.Equal(time.Date(2024, 10, 19, 14, 30, 0, 0, time.UTC)) {
+		test.Errorf("дата обзора 0 = %v", first.Date)
+	}
+	if len(first.Reactions) != 2 || first.Reactions["Полезно"] != 12 || first.Reactions["Не полезно"] != 3 {
+		test.Errorf("реакции обзора 0 = %v", first.Reactions)
+	}
+
+	if second := reviews[1]; second.ID != "102" || second.Opinion != OpinionNegative || second.Score != 4 {
+		test.Errorf("обзор 1 = %+v", second)
+	}
+	if third := reviews[2]; third.ID != "103" || third.Opinion != "" || third.Score != 8 || third.Author != "carol" {
+		test.Errorf("обзор 2 = %+v", third)
+	}
+}
+
+func TestReviewsPages(test *testing.T) {
+	mu := sync.Mutex{}
+	pages := make(map[string]bool)
+	use_test_sites(test, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host+r.URL.Path != "shikimori.one/animes/z20-naruto/reviews" {
+			http.NotFound(w, r)
+			return
+		}
+		page := r.URL.Query().Get("page")
+		mu.Lock()
+		pages[page] = true
+		mu.Unlock()
+		switch page {
+		case "":
+			fmt.Fprint(w, test_shikimori_reviews_page_1)
+		case "2":
+			write_test_json(w, map[string]string{"content": test_shikimori_reviews_page_2})
+		case "3":
+			fmt.Fprint(w, test_shikimori_reviews_page_3)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	sh := NewShikimoriParser("shikimori.one")
+
+	reviews, err := sh.Reviews("https://shikimori.one/animes/z20-naruto/", 0)
+	if err != nil {
+		test.Fatalf("Reviews вернул ошибку: %v", err)
+	}
+	ids := make([]string, 0, len(reviews))
+	for _, review := range reviews {
+		ids = append(ids, review.ID)
+	}
+	if fmt.Sprint(ids) != "[101 102 103 104]" {
+		test.Errorf("обзоры %v, want [101 102 103 104]", ids)
+	}
+	if reviews[3].Opinion != OpinionNeutral {
+		test.Errorf("обзор со второй страницы = %+v", reviews[3])
+	}
+	if len(pages) != 3 || pages["4"] {
+		test.Errorf("загружены страницы %v, want 1-3", pages)
+	}
+
+	// Ограничение количества страниц
+	reviews, err = sh.Reviews("https://shikimori.one/animes/z20-naruto", 1)
+	if err != nil || len(reviews) != 3 {
+		test.Errorf("Reviews с max_pages 1 вернул %d обзоров, %v", len(reviews), err)
+	}
+}
+
+// api комментариев: страница 1 - limit+1 комментариев (признак следующей страницы), 2 - ровно limit, 3 - 5 комментариев
+func test_shikimori_comments(test *testing.T) http.HandlerFunc {
+	sizes := map[string]int{"1": commentsPageLimit + 1, "2": commentsPageLimit, "3": 5}
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch r.URL.Host + r.URL.Path {
+		case "shikimori.one/api/animes/20":
+			write_test_json(w, map[string]int{"topic_id": 555})
+		case "shikimori.one/api/animes/21":
+			write_test_json(w, map[string]any{"topic_id": nil})
+		case "shikimori.one/api/comments":
+			if query.Get("commentable_id") != "555" || query.Get("commentable_type") != "Topic" || query.Get("limit") != strconv.Itoa(commentsPageLimit) || query.Get("desc") != "1" {
+				test.Errorf("параметры запроса комментариев: %v", query)
+			}
+			page, _ := strconv.Atoi(query.Get("page"))
+			items := make([]map[string]any, 0)
+			for i := 0; i < sizes[query.Get("page")]; i++ {
+				items = append(items, map[string]any{
+					"id":         page*100 + i,
+					"body":       "Комментарий",
+					"created_at": "2024-10-19T17:30:00.000+03:00",
+					"user":       map[string]string{"nickname": "alice", "url": "/users/alice"},
+				})
+			}
+			write_test_json(w, items)
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func TestTopicCommentsPages(test *testing.T) {
+	sites := use_test_sites(test, test_shikimori_comments(test))
+	sh := NewShikimoriParser("shikimori.one")
+	sh.api_pacer.interval = 0
+
+	comments, err := sh.Comments("https://shikimori.one/animes/z20-naruto", 0)
+	if err != nil {
+		test.Fatalf("Comments вернул ошибку: %v", err)
+	}
+	// Лишний элемент первой страницы отбрасывается, страница ровно из limit элементов не последняя
+	if len(comments) != 2*commentsPageLimit+5 || sites.count() != 4 {
+		test.Errorf("Comments вернул %d комментариев, want %d", len(comments), 2*commentsPageLimit+5)
+	}
+	if comments[commentsPageLimit-1].ID != strconv.Itoa(100+commentsPageLimit-1) || comments[commentsPageLimit].ID != "200" {
+		test.Errorf("комментарии на границе страниц: %s, %s", comments[commentsPageLimit-1].ID, comments[commentsPageLimit].ID)
+	}
+	if first := comments[0]; first.Author != "alice" || first.AuthorLink != "https://shikimori.one/users/alice" || first.Date.IsZero() {
+		test.Errorf("комментарий 0 = %+v", first)
+	}
+
+	comments, err = sh.TopicComments("555", 1)
+	if err != nil || len(comments) != commentsPageLimit || sites.count() != 1 {
+		test.Errorf("TopicComments с max_pages 1 вернул %d комментариев, %v", len(comments), err)
+	}
+
+	var no_results *errs.NoResults
+	if _, err := sh.Comments("https://shikimori.one/animes/21-naruto", 0); !errors.As(err, &no_results) {
+		test.Errorf("Comments без топика вернул %T: %v, want *errs.NoResults", err, err)
+	}
+}
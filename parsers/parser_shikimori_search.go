@@ -0,0 +1,189 @@
+package parsers
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+)
+
+// Количество аниме на странице /api/animes (максимум api)
+const catalogPageLimit = 50
+
+// Типы аниме из api в том виде, в котором их показывает сайт (как в Search)
+var sh_kind_names = map[string]string{
+	"tv":         "TV Сериал",
+	"movie":      "Фильм",
+	"ova":        "OVA",
+	"ona":        "ONA",
+	"special":    "Спешл",
+	"tv_special": "TV Спешл",
+	"music":      "Клип",
+	"pv":         "Проморолик",
+	"cm":         "CM",
+}
+
+// Статусы аниме из api в том виде, в котором их показывает сайт (как в Search)
+var sh_status_names = map[string]string{
+	"anons":    "анонс",
+	"ongoing":  "онгоинг",
+	"released": "вышло",
+}
+
+// Значение из словаря names или исходное значение, если его нет в словаре
+func sh_api_name(names map[string]string, value string) string {
+	if name, exists := names[value]; exists {
+		return name
+	}
+	return value
+}
+
+// Фильтры расширенного поиска SearchCatalog. Пустые поля не используются.
+// Значения совпадают с фильтрами каталога shikimori (через запятую можно указать несколько, "!" в начале исключает значение)
+type SHSearchFilters struct {
+	// Тип (прим: tv, movie, ova, ona, special, music или "tv,movie")
+	Kind string `json:"kind"`
+	// Статус (прим: anons, ongoing, released)
+	Status string `json:"status"`
+	// Сезон (прим: summer_2024, 2024, 2020_2024)
+	Season string `json:"season"`
+	// Минимальная оценка (прим: 7)
+	Score int `json:"score"`
+	// id жанров через запятую (прим: "1,2")
+	Genre string `json:"genre"`
+	// Возрастной рейтинг (прим: pg_13, r, "!rx")
+	Rating string `json:"rating"`
+	// Порядок сортировки api (прим: ranked, popularity, aired_on, name). Пустая строка - по сходству названия с запросом
+	Order string `json:"order"`
+}
+
+// Параметры запроса /api/animes для фильтров
+func (f *SHSearchFilters) params() models.Params {
+	params := models.Params{}
+	if f == nil {
+		return params
+	}
+	for key, value := range map[string]string{
+		"kind":   f.Kind,
+		"status": f.Status,
+		"season": f.Season,
+		"genre":  f.Genre,
+		"rating": f.Rating,
+		"order":  f.Order,
+	} {
+		if value = strings.TrimSpace(value); value != "" {
+			params[key] = value
+		}
+	}
+	if f.Score > 0 {
+		params["score"] = strconv.Itoa(f.Score)
+	}
+	return params
+}
+
+// Элемент ответа /api/animes
+type sh_anime_json struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Russian       string `json:"russian"`
+	URL           string `json:"url"`
+	Kind          string `json:"kind"`
+	Score         string `json:"score"`
+	Status        string `json:"status"`
+	Episodes      int    `json:"episodes"`
+	EpisodesAired int    `json:"episodes_aired"`
+	AiredOn       string `json:"aired_on"`
+	Image         struct {
+		Original string `json:"original"`
+	} `json:"image"`
+}
+
+// Расширенный поиск аниме по всему каталогу shikimori (без ограничения autocomplete) с переходом по страницам.
+//
+// :title: название аниме ("" - весь каталог по фильтрам)
+//
+// :filters: фильтры каталога (nil - без фильтров)
+//
+// :max_pages: максимальное количество страниц по catalogPageLimit аниме (0 - все страницы, но не больше maxUserListPages)
+//
+// Type и Status заполняются так же, как в Search (прим: "TV Сериал", "вышло"), Score и Episodes - оценкой и количеством эпизодов
+// (для онгоингов без известного количества - количеством вышедших эпизодов). Жанры и студия не заполняются.
+// Если не указаны ни название, ни фильтры, возвращает ошибку errs.PostArgumentsError.
+// Если ошибка произошла не на первой странице, возвращает уже найденные аниме вместе с ошибкой
+//
+// Возвращает список ссылок на SHSearchResult, отсортированный по сходству названий с запросом (или в порядке filters.Order)
+func (sh *ShikimoriParser) SearchCatalog(title string, filters *SHSearchFilters, max_pages int) ([]*SHSearchResult, error) {
+	params := filters.params()
+	if title = strings.TrimSpace(title); title != "" {
+		params["search"] = title
+	}
+	if len(params) == 0 {
+		error_message := "Shikimori parser error : SearchCatalog : не указаны ни название, ни фильтры"
+		log.Println(error_message)
+		return nil, errs.NewPostArgumentsError(error_message)
+	}
+	if max_pages <= 0 || max_pages > maxUserListPages {
+		max_pages = maxUserListPages
+	}
+	params["limit"] = strconv.Itoa(catalogPageLimit)
+
+	res := make([]*SHSearchResult, 0)
+	seen := make(map[int64]bool)
+	for page := 1; page <= max_pages; page++ {
+		params["page"] = strconv.Itoa(page)
+		items := make([]*sh_anime_json, 0)
+		if err := sh.api_get("SearchCatalog", "animes", params, &items); err != nil {
+			if len(res) > 0 {
+				return res, err
+			}
+			return nil, err
+		}
+
+		items, more := api_page(items, catalogPageLimit)
+		for _, item := range items {
+			if seen[item.ID] {
+				continue
+			}
+			seen[item.ID] = true
+			res = append(res, sh.catalog_result(item))
+		}
+		if !more {
+			break
+		}
+	}
+
+	if title != "" && (filters == nil || filters.Order == "") {
+		rank_sh_search(title, res)
+	}
+	return res, nil
+}
+
+func (sh *ShikimoriParser) catalog_result(item *sh_anime_json) *SHSearchResult {
+	c_data := &SHSearchResult{
+		Genres:        make([]string, 0),
+		Link:          sh.site_link(item.URL),
+		OriginalTitle: item.Name,
+		Poster:        sh.site_link(item.Image.Original),
+		ShikimoriID:   strconv.FormatInt(item.ID, 10),
+		Status:        sh_api_name(sh_status_names, item.Status),
+		Title:         item.Russian,
+		Type:          sh_api_name(sh_kind_names, item.Kind),
+	}
+	if c_data.Title == "" {
+		c_data.Title = item.Name
+	}
+	if len(item.AiredOn) >= 4 {
+		c_data.Year = item.AiredOn[:4]
+	}
+	if item.Score != "" && item.Score != "0.0" {
+		c_data.Score = item.Score
+	}
+	if item.Episodes > 0 {
+		c_data.Episodes = strconv.Itoa(item.Episodes)
+	} else if item.EpisodesAired > 0 {
+		c_data.Episodes = strconv.Itoa(item.EpisodesAired)
+	}
+	return c_data
+}
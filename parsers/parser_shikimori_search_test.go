@@ -0,0 +1,169 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+)
+
+func TestCatalogNames(test *testing.T) {
+	// Названия должны разбираться так же, как значения api
+	for kind, name := range sh_kind_names {
+		if got := models.ParseAnimeKind(name); got != models.AnimeKind(kind) {
+			test.Errorf("ParseAnimeKind(%q) = %q, want %q", name, got, kind)
+		}
+	}
+	for status, name := range sh_status_names {
+		if got, want := models.ParseAnimeStatus(name), models.ParseAnimeStatus(status); got != want {
+			test.Errorf("ParseAnimeStatus(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if got := sh_api_name(sh_kind_names, "unknown_kind"); got != "unknown_kind" {
+		test.Errorf("sh_api_name для неизвестного значения = %q, want %q", got, "unknown_kind")
+	}
+}
+
+// api каталога: sizes - количество аниме на страницах (id аниме - номер страницы * 1000 + номер на странице),
+// у первого аниме каждой страницы после первой id совпадает с последним (в пределах limit) аниме предыдущей страницы.
+// Страницы из failed отвечают ошибкой сервера
+type test_catalog struct {
+	mu     sync.Mutex
+	sizes  map[int]int
+	failed map[int]bool
+	params []url.Values
+}
+
+func (c *test_catalog) handler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Host+r.URL.Path != "shikimori.one/api/animes" {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+	c.mu.Lock()
+	c.params = append(c.params, query)
+	c.mu.Unlock()
+	page, _ := strconv.Atoi(query.Get("page"))
+	if c.failed[page] {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	items := make([]*sh_anime_json, 0)
+	for i := 0; i < c.sizes[page]; i++ {
+		id := int64(page*1000 + i)
+		if i == 0 && page > 1 {
+			id = int64((page-1)*1000 + min(c.sizes[page-1], catalogPageLimit) - 1)
+		}
+		items = append(items, &sh_anime_json{ID: id, Name: fmt.Sprintf("Anime %d", id), URL: fmt.Sprintf("/animes/%d", id), Kind: "tv", Status: "released"})
+	}
+	write_test_json(w, items)
+}
+
+func new_test_catalog(test *testing.T, sizes map[int]int, failed ...int) (*test_catalog, *ShikimoriParser) {
+	catalog := &test_catalog{sizes: sizes, failed: make(map[int]bool)}
+	for _, page := range failed {
+		catalog.failed[page] = true
+	}
+	use_test_sites(test, catalog.handler)
+	sh := NewShikimoriParser("shikimori.one")
+	sh.api_pacer.interval = 0
+	return catalog, sh
+}
+
+func TestSearchCatalogPages(test *testing.T) {
+	// Страница 1 - limit+1 аниме (лишнее отбрасывается), страница 2 - ровно limit (не последняя), страница 3 - последняя
+	catalog, sh := new_test_catalog(test, map[int]int{1: catalogPageLimit + 1, 2: catalogPageLimit, 3: 3})
+	filters := &SHSearchFilters{Kind: " tv,movie ", Status: "released", Score: 7, Order: "ranked"}
+
+	res, err := sh.SearchCatalog("Наруто", filters, 0)
+	if err != nil {
+		test.Fatalf("SearchCatalog вернул ошибку: %v", err)
+	}
+	// Повторы на границе страниц пропускаются
+	if want := catalogPageLimit + (catalogPageLimit - 1) + 2; len(res) != want {
+		test.Errorf("SearchCatalog вернул %d аниме, want %d", len(res), want)
+	}
+	if len(catalog.params) != 3 {
+		test.Fatalf("SearchCatalog выполнил %d запросов, want 3", len(catalog.params))
+	}
+	for i, params := range catalog.params {
+		if params.Get("page") != strconv.Itoa(i+1) || params.Get("limit") != strconv.Itoa(catalogPageLimit) || params.Get("search") != "Наруто" ||
+			params.Get("kind") != "tv,movie" || params.Get("status") != "released" || params.Get("score") != "7" || params.Get("order") != "ranked" || params.Has("season") {
+			test.Errorf("параметры запроса %d: %v", i+1, params)
+		}
+	}
+	// Порядок filters.Order сохраняется
+	if res[0].ShikimoriID != "1000" || res[catalogPageLimit].ShikimoriID != "2001" {
+		test.Errorf("порядок результатов: %s, %s", res[0].ShikimoriID, res[catalogPageLimit].ShikimoriID)
+	}
+
+	// Ограничение количества страниц
+	catalog.params = nil
+	if res, err := sh.SearchCatalog("", filters, 1); err != nil || len(res) != catalogPageLimit || len(catalog.params) != 1 || catalog.params[0].Has("search") {
+		test.Errorf("SearchCatalog с max_pages 1 вернул %d аниме, %v, параметры %v", len(res), err, catalog.params)
+	}
+
+	var arguments *errs.PostArgumentsError
+	catalog.params = nil
+	if _, err := sh.SearchCatalog("  ", &SHSearchFilters{}, 0); !errors.As(err, &arguments) || len(catalog.params) != 0 {
+		test.Errorf("SearchCatalog без названия и фильтров вернул %T: %v, want *errs.PostArgumentsError без запросов", err, err)
+	}
+}
+
+func TestSearchCatalogErrors(test *testing.T) {
+	// Ошибка на второй странице: возвращаются аниме первой страницы вместе с ошибкой
+	_, sh := new_test_catalog(test, map[int]int{1: catalogPageLimit, 2: catalogPageLimit}, 2)
+	res, err := sh.SearchCatalog("", &SHSearchFilters{Season: "2024"}, 0)
+	if err == nil || len(res) != catalogPageLimit {
+		test.Errorf("SearchCatalog с ошибкой на странице 2 вернул %d аниме, %v", len(res), err)
+	}
+
+	// Ошибка на первой странице
+	_, sh = new_test_catalog(test, map[int]int{1: catalogPageLimit}, 1)
+	if res, err := sh.SearchCatalog("", &SHSearchFilters{Season: "2024"}, 0); err == nil || res != nil {
+		test.Errorf("SearchCatalog с ошибкой на странице 1 вернул %v, %v", res, err)
+	}
+}
+
+func TestCatalogResult(test *testing.T) {
+	sh := NewShikimoriParser("shikimori.one")
+	item := &sh_anime_json{ID: 20, Name: "Naruto", Russian: "Наруто", URL: "/animes/z20-naruto", Kind: "tv", Score: "8.0", Status: "released",
+		Episodes: 220, EpisodesAired: 0, AiredOn: "2002-10-03"}
+	item.Image.Original = "/system/animes/original/20.jpg"
+	res := sh.catalog_result(item)
+	if res.ShikimoriID != "20" || res.Title != "Наруто" || res.OriginalTitle != "Naruto" || res.Link != "https://shikimori.one/animes/z20-naruto" ||
+		res.Poster != "https://shikimori.one/system/animes/original/20.jpg" || res.Type != "TV Сериал" || res.Status != "вышло" ||
+		res.Year != "2002" || res.Score != "8.0" || res.Episodes != "220" || len(res.Genres) != 0 {
+		test.Errorf("catalog_result = %+v", res)
+	}
+
+	// Онгоинг без названия на русском, оценки и количества эпизодов
+	item = &sh_anime_json{ID: 52991, Name: "Sousou no Frieren", URL: "/animes/52991", Kind: "tv_special", Score: "0.0", Status: "ongoing", EpisodesAired: 5}
+	res = sh.catalog_result(item)
+	if res.Title != "Sousou no Frieren" || res.Score != "" || res.Episodes != "5" || res.Year != "" || res.Type != "TV Спешл" || res.Status != "онгоинг" {
+		test.Errorf("catalog_result для онгоинга = %+v", res)
+	}
+}
+
+func TestSearchCatalogRanking(test *testing.T) {
+	use_test_sites(test, func(w http.ResponseWriter, r *http.Request) {
+		write_test_json(w, []*sh_anime_json{
+			{ID: 1735, Name: "Naruto: Shippuuden", URL: "/animes/1735"},
+			{ID: 20, Name: "Naruto", URL: "/animes/20"},
+		})
+	})
+	sh := NewShikimoriParser("shikimori.one")
+	sh.api_pacer.interval = 0
+
+	// Без filters.Order результаты сортируются по сходству названия с запросом
+	res, err := sh.SearchCatalog("naruto", nil, 0)
+	if err != nil || len(res) != 2 || res[0].ShikimoriID != "20" {
+		test.Errorf("SearchCatalog = %v, %v, want Naruto первым", res, err)
+	}
+}
@@ -0,0 +1,327 @@
+package parsers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Ограничение количества страниц списка (защита от бесконечной пагинации)
+const maxUserListPages = 200
+
+// Статусы в списке пользователя по заголовкам разделов на странице списка
+var user_list_statuses = map[string]string{
+	"запланировано":      "planned",
+	"смотрю":             "watching",
+	"пересматриваю":      "rewatching",
+	"просмотрено":        "completed",
+	"отложено":           "on_hold",
+	"брошено":            "dropped",
+	"planned":            "planned",
+	"watching":           "watching",
+	"rewatching":         "rewatching",
+	"completed":          "completed",
+	"on hold":            "on_hold",
+	"dropped":            "dropped",
+	"plan to watch":      "planned",
+	"re-watching":        "rewatching",
+	"currently watching": "watching",
+}
+
+// Запись в публичном списке аниме пользователя
+type SHUserAnimeListEntry struct {
+	ShikimoriID   string `json:"shikimori_id"`
+	Title         string `json:"title"`
+	OriginalTitle string `json:"original_title"`
+	Link          string `json:"link"`
+	// Оценка пользователя (0 - без оценки)
+	Score int `json:"score"`
+	// Просмотрено эпизодов
+	Episodes int `json:"episodes"`
+	// Всего эпизодов (0 - неизвестно)
+	TotalEpisodes int    `json:"total_episodes"`
+	Type          string `json:"type"`
+	// planned, watching, rewatching, completed, on_hold, dropped
+	Status string `json:"status"`
+}
+
+// Статистика по списку аниме пользователя
+type SHUserStats struct {
+	// Количество аниме по статусам списка (planned, watching, ...)
+	Statuses map[string]int `json:"statuses"`
+	// Количество оценок по значению ("10" > 15)
+	Scores map[string]int `json:"scores"`
+	// Количество аниме по типам (tv, movie, ...)
+	Types map[string]int `json:"types"`
+	// Количество аниме по рейтингам (pg_13, r, ...)
+	Ratings map[string]int `json:"ratings"`
+}
+
+// Публичный профиль пользователя shikimori
+type SHUserProfile struct {
+	ID         string   `json:"id"`
+	Nickname   string   `json:"nickname"`
+	Name       string   `json:"name"`
+	Avatar     string   `json:"avatar"`
+	Sex        string   `json:"sex"`
+	Age        int      `json:"age"`
+	Website    string   `json:"website"`
+	About      string   `json:"about"`
+	LastOnline string   `json:"last_online"`
+	CommonInfo []string `json:"common_info"`
+	Link       string   `json:"link"`
+	Banned     bool     `json:"banned"`
+	// Статистика по аниме
+	AnimeStats *SHUserStats `json:"anime_stats"`
+}
+
+// Путь к странице пользователя (никнеймы могут содержать пробелы и кириллицу)
+func (sh *ShikimoriParser) user_link(nickname string) string {
+	return fmt.Sprintf("https://%s/%s", sh.domain(), url.PathEscape(strings.TrimSpace(nickname)))
+}
+
+// Получение публичного списка аниме пользователя парсингом страницы /nickname/list/anime.
+//
+// :nickname: никнейм пользователя
+//
+// :status: статус в списке (planned, watching, rewatching, completed, on_hold, dropped). Пустая строка - весь список
+//
+// Загружает все страницы списка. Если список пуст или скрыт настройками приватности, возвращает ошибку errs.NoResults.
+// Если пользователь не найден, возвращает errs.ServiceError
+//
+// Возвращает срез ссылок на SHUserAnimeListEntry
+func (sh *ShikimoriParser) UserAnimeList(nickname, status string) ([]*SHUserAnimeListEntry, error) {
+	URL := sh.user_link(nickname) + "/list/anime"
+	if status != "" {
+		URL += "/mylist/" + status
+	}
+	headers := models.Headers{
+		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0",
+	}
+
+	res := make([]*SHUserAnimeListEntry, 0)
+	seen := make(map[string]bool)
+	for page := 1; URL != "" && page <= maxUserListPages; page++ {
+		resp, err := sh.mirrors.Request(sh.context, "GET", URL, nil, headers, false, nil)
+		if err != nil {
+			error_message := fmt.Sprintf("Shikimori parser error : UserAnimeList : RequestWithContext вернул ошибку: %v", err)
+			log.Println(error_message)
+			return nil, t.WrapRequestError(err, error_message)
+		}
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Data))
+		if err != nil {
+			error_message := fmt.Sprintf("Shikimori parser error : UserAnimeList : goquery не смог преобразовать ответ в документ. Ошибка: %v", err)
+			log.Println(error_message)
+			return nil, errs.NewServiceError(error_message)
+		}
+
+		entries := parse_user_anime_list(doc, status)
+		added := 0
+		for _, entry := range entries {
+			key := entry.Status + ":" + entry.ShikimoriID
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if strings.HasPrefix(entry.Link, "/") {
+				entry.Link = fmt.Sprintf("https://%s%s", sh.domain(), entry.Link)
+			}
+			entry.Link = sh.mirrors.Rewrite(entry.Link)
+			res = append(res, entry)
+			added++
+		}
+		if page == 1 && len(entries) == 0 {
+			error_message := fmt.Sprintf("Shikimori parser error : UserAnimeList : список пользователя %s пуст или скрыт", nickname)
+			log.Println(error_message)
+			return nil, errs.NewNoResultsError(error_message)
+		}
+		// Страница без новых записей - пагинация закончилась
+		if added == 0 {
+			break
+		}
+		URL = next_list_page(doc, URL)
+	}
+	return res, nil
+}
+
+// Разбирает строки таблиц списка. Статус берется из заголовка раздела (если status пустой)
+func parse_user_anime_list(doc *goquery.Document, status string) []*SHUserAnimeListEntry {
+	res := make([]*SHUserAnimeListEntry, 0)
+	current_status := status
+	doc.Find(".subheadline, header.l-header, tr.user_rate").Each(func(i int, s *goquery.Selection) {
+		if !s.Is("tr.user_rate") {
+			if status == "" {
+				if parsed, ok := parse_list_status(s.Text()); ok {
+					current_status = parsed
+				}
+			}
+			return
+		}
+
+		entry := &SHUserAnimeListEntry{Status: current_status}
+		if target_type, exists := s.Attr("data-target_type"); exists && target_type != "Anime" {
+			return
+		}
+		entry.ShikimoriID, _ = s.Attr("data-target_id")
+
+		name := s.Find("a.tooltipped, td.name a").First()
+		if href, exists := name.Attr("href"); exists {
+			entry.Link = href
+		} else if target_url, exists := s.Attr("data-target_url"); exists {
+			entry.Link = target_url
+		}
+		if entry.ShikimoriID == "" {
+			entry.ShikimoriID = ShikimoriIDFromLink(entry.Link)
+		}
+		entry.Title = strings.TrimSpace(name.Find(".name-ru").First().Text())
+		entry.OriginalTitle = strings.TrimSpace(name.Find(".name-en").First().Text())
+		if entry.Title == "" {
+			entry.Title = strings.TrimSpace(name.Text())
+		}
+
+		entry.Score, _ = strconv.Atoi(strings.TrimSpace(s.Find("[data-field=\"score\"]").First().Text()))
+		episodes := s.Find("[data-field=\"episodes\"]").First()
+		entry.Episodes, _ = strconv.Atoi(strings.TrimSpace(episodes.Text()))
+		// Рядом с просмотренными эпизодами указано общее количество: "5 / 12"
+		if total := strings.Split(episodes.Parent().Text(), "/"); len(total) == 2 {
+			entry.TotalEpisodes, _ = strconv.Atoi(strings.TrimSpace(total[1]))
+		}
+		cells := s.Find("td")
+		if cells.Length() > 0 {
+			entry.Type = strings.TrimSpace(cells.Last().Text())
+		}
+
+		if entry.ShikimoriID == "" {
+			log.Println("Shikimori parser warning : UserAnimeList : в строке списка не найден id аниме")
+			return
+		}
+		res = append(res, entry)
+	})
+	return res
+}
+
+// Определяет статус по заголовку раздела (прим: "Смотрю (12)" > "watching")
+func parse_list_status(header string) (string, bool) {
+	header = strings.ToLower(strings.TrimSpace(header))
+	if i := strings.IndexAny(header, "(0123456789"); i != -1 {
+		header = strings.TrimSpace(header[:i])
+	}
+	status, ok := user_list_statuses[header]
+	return status, ok
+}
+
+// Возвращает ссылку на следующую страницу списка или пустую строку
+func next_list_page(doc *goquery.Document, current string) string {
+	for _, selector := range []string{"link[rel=\"next\"]", "a.link-next", "a.next", ".b-postloader"} {
+		item := doc.Find(selector).First()
+		if item.Length() == 0 {
+			continue
+		}
+		for _, attr := range []string{"href", "data-href"} {
+			if href, exists := item.Attr(attr); exists && href != "" {
+				base, err := url.Parse(current)
+				if err != nil {
+					return href
+				}
+				next, err := base.Parse(href)
+				if err != nil || next.String() == current {
+					return ""
+				}
+				return next.String()
+			}
+		}
+	}
+	return ""
+}
+
+// Ответ /api/users/:nickname (только используемые поля)
+type sh_user_json struct {
+	ID         int64    `json:"id"`
+	Nickname   string   `json:"nickname"`
+	Name       string   `json:"name"`
+	Avatar     string   `json:"avatar"`
+	Sex        string   `json:"sex"`
+	FullYears  int      `json:"full_years"`
+	Website    string   `json:"website"`
+	About      string   `json:"about"`
+	LastOnline string   `json:"last_online"`
+	CommonInfo []string `json:"common_info"`
+	URL        string   `json:"url"`
+	Banned     bool     `json:"banned"`
+	Stats      struct {
+		FullStatuses struct {
+			Anime []struct {
+				Name string `json:"name"`
+				Size int    `json:"size"`
+			} `json:"anime"`
+		} `json:"full_statuses"`
+		Scores  sh_user_stat_values `json:"scores"`
+		Types   sh_user_stat_values `json:"types"`
+		Ratings sh_user_stat_values `json:"ratings"`
+	} `json:"stats"`
+}
+
+type sh_user_stat_values struct {
+	Anime []struct {
+		Name  string `json:"name"`
+		Value int    `json:"value"`
+	} `json:"anime"`
+}
+
+func (v sh_user_stat_values) to_map() map[string]int {
+	res := make(map[string]int, len(v.Anime))
+	for _, item := range v.Anime {
+		res[item.Name] = item.Value
+	}
+	return res
+}
+
+// Получение публичного профиля пользователя и статистики его списка аниме (без OAuth).
+//
+// :nickname: никнейм пользователя
+//
+// # Если пользователь не найден, возвращает ошибку errs.ServiceError
+//
+// Возвращает ссылку на SHUserProfile
+func (sh *ShikimoriParser) UserProfile(nickname string) (*SHUserProfile, error) {
+	params := models.Params{
+		"is_nickname": "1",
+	}
+	user := &sh_user_json{}
+	if err := sh.api_get("UserProfile", "users/"+url.PathEscape(strings.TrimSpace(nickname)), params, user); err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]int)
+	for _, status := range user.Stats.FullStatuses.Anime {
+		statuses[status.Name] = status.Size
+	}
+	return &SHUserProfile{
+		ID:         strconv.FormatInt(user.ID, 10),
+		Nickname:   user.Nickname,
+		Name:       user.Name,
+		Avatar:     user.Avatar,
+		Sex:        user.Sex,
+		Age:        user.FullYears,
+		Website:    user.Website,
+		About:      user.About,
+		LastOnline: user.LastOnline,
+		CommonInfo: user.CommonInfo,
+		Link:       sh.mirrors.Rewrite(user.URL),
+		Banned:     user.Banned,
+		AnimeStats: &SHUserStats{
+			Statuses: statuses,
+			Scores:   user.Stats.Scores.to_map(),
+			Types:    user.Stats.Types.to_map(),
+			Ratings:  user.Stats.Ratings.to_map(),
+		},
+	}, nil
+}
@@ -0,0 +1,185 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+// Первая страница списка: разделы "Смотрю" и "Просмотрено", строки манги и без ссылки пропускаются
+const test_shikimori_list_page_1 = `<header class="l-header"><h1>Список аниме</h1></header>
+<div class="subheadline">Смотрю (1)</div>
+<table>
+<tr class="user_rate" data-target_id="20" data-target_type="Anime">
+	<td class="name"><a class="tooltipped" href="/animes/z20-naruto"><span class="name-ru">Наруто</span><span class="name-en">Naruto</span></a></td>
+	<td><span data-field="score">8</span></td>
+	<td><span><span data-field="episodes">5</span> / 220</span></td>
+	<td>TV Сериал</td>
+</tr>
+<tr class="user_rate" data-target_id="11" data-target_type="Manga">
+	<td class="name"><a href="/mangas/11-naruto">Наруто</a></td>
+</tr>
+</table>
+<div class="subheadline">Просмотрено (2)</div>
+<table>
+<tr class="user_rate" data-target_url="/animes/1735-naruto-shippuuden">
+	<td class="name"><a>Наруто: Ураганные хроники</a></td>
+	<td><span data-field="score">0</span></td>
+	<td><span><span data-field="episodes">500</span> / 500</span></td>
+	<td>TV Сериал</td>
+</tr>
+<tr class="user_rate"><td class="name">Без ссылки</td></tr>
+</table>
+<a class="link-next" href="/alice/list/anime/page/2">Далее</a>`
+
+// Вторая страница: повтор записи 1735 и раздел "Брошено"
+const test_shikimori_list_page_2 = `<div class="subheadline">Просмотрено (2)</div>
+<table>
+<tr class="user_rate" data-target_id="1735" data-target_type="Anime">
+	<td class="name"><a class="tooltipped" href="/animes/1735-naruto-shippuuden">Наруто: Ураганные хроники</a></td>
+</tr>
+</table>
+<div class="subheadline">Брошено (1)</div>
+<table>
+<tr class="user_rate" data-target_id="34566" data-target_type="Anime">
+	<td class="name"><a class="tooltipped" href="https://shikimori.one/animes/34566-boruto">Боруто</a></td>
+	<td><span data-field="score">3</span></td>
+	<td><span><span data-field="episodes">12</span> / 293</span></td>
+	<td>TV Сериал</td>
+</tr>
+</table>`
+
+func TestParseListStatus(test *testing.T) {
+	tests := map[string]string{
+		"Смотрю (12)":      "watching",
+		" Запланировано 3": "planned",
+		"Отложено":         "on_hold",
+		"On Hold (2)":      "on_hold",
+		"Plan to Watch":    "planned",
+		"Re-watching":      "rewatching",
+	}
+	for header, want := range tests {
+		if got, ok := parse_list_status(header); !ok || got != want {
+			test.Errorf("parse_list_status(%q) = %q, %v, want %q", header, got, ok, want)
+		}
+	}
+	for _, header := range []string{"Список аниме", "Избранное (5)", ""} {
+		if got, ok := parse_list_status(header); ok {
+			test.Errorf("parse_list_status(%q) = %q, want не найден", header, got)
+		}
+	}
+}
+
+func TestParseUserAnimeList(test *testing.T) {
+	entries := parse_user_anime_list(test_goquery(test, test_shikimori_list_page_1), "")
+	want := []SHUserAnimeListEntry{
+		{ShikimoriID: "20", Title: "Наруто", OriginalTitle: "Naruto", Link: "/animes/z20-naruto", Score: 8, Episodes: 5, TotalEpisodes: 220, Type: "TV Сериал", Status: "watching"},
+		{ShikimoriID: "1735", Title: "Наруто: Ураганные хроники", Link: "/animes/1735-naruto-shippuuden", Episodes: 500, TotalEpisodes: 500, Type: "TV Сериал", Status: "completed"},
+	}
+	if len(entries) != len(want) {
+		test.Fatalf("parse_user_anime_list вернул %d записей, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, entry := range entries {
+		if *entry != want[i] {
+			test.Errorf("запись %d = %+v, want %+v", i, *entry, want[i])
+		}
+	}
+
+	// Статус из запроса заменяет заголовки разделов
+	for _, entry := range parse_user_anime_list(test_goquery(test, test_shikimori_list_page_1), "dropped") {
+		if entry.Status != "dropped" {
+			test.Errorf("запись %s со статусом %q, want dropped", entry.ShikimoriID, entry.Status)
+		}
+	}
+}
+
+func TestNextListPage(test *testing.T) {
+	const current = "https://shikimori.one/alice/list/anime"
+	tests := map[string]string{
+		`<link rel="next" href="/alice/list/anime/page/2">`:                       "https://shikimori.one/alice/list/anime/page/2",
+		`<a class="link-next" href="https://shikimori.me/alice/page/3">Далее</a>`: "https://shikimori.me/alice/page/3",
+		`<div class="b-postloader" data-href="?page=2"></div>`:                    "https://shikimori.one/alice/list/anime?page=2",
+		`<a class="next" href="/alice/list/anime">Далее</a>`:                      "",
+		`<a href="/alice/list/anime/page/2">2</a>`:                                "",
+	}
+	for content, want := range tests {
+		if got := next_list_page(test_goquery(test, content), current); got != want {
+			test.Errorf("next_list_page(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
+func TestUserAnimeListPages(test *testing.T) {
+	use_test_sites(test, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Host + r.URL.Path {
+		case "shikimori.one/alice/list/anime":
+			fmt.Fprint(w, test_shikimori_list_page_1)
+		case "shikimori.one/alice/list/anime/page/2":
+			fmt.Fprint(w, test_shikimori_list_page_2)
+		case "shikimori.one/bob/list/anime":
+			fmt.Fprint(w, `<div class="subheadline">Список пуст</div>`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	sh := NewShikimoriParser("shikimori.one")
+
+	entries, err := sh.UserAnimeList("alice", "")
+	if err != nil {
+		test.Fatalf("UserAnimeList вернул ошибку: %v", err)
+	}
+	statuses := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		statuses = append(statuses, entry.ShikimoriID+":"+entry.Status)
+	}
+	if fmt.Sprint(statuses) != "[20:watching 1735:completed 34566:dropped]" {
+		test.Errorf("UserAnimeList = %v", statuses)
+	}
+	if entries[0].Link != "https://shikimori.one/animes/z20-naruto" || entries[2].Link != "https://shikimori.one/animes/34566-boruto" {
+		test.Errorf("ссылки записей: %s, %s", entries[0].Link, entries[2].Link)
+	}
+
+	var no_results *errs.NoResults
+	if _, err := sh.UserAnimeList("bob", ""); !errors.As(err, &no_results) {
+		test.Errorf("UserAnimeList для пустого списка вернул %T: %v, want *errs.NoResults", err, err)
+	}
+}
+
+func TestUserProfile(test *testing.T) {
+	sites := use_test_sites(test, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host+r.URL.Path != "shikimori.one/api/users/alice" || r.URL.Query().Get("is_nickname") != "1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{
+			"id": 1, "nickname": "alice", "full_years": 25, "url": "https://shikimori.one/alice",
+			"stats": {
+				"full_statuses": {"anime": [{"name": "watching", "size": 1}, {"name": "completed", "size": 2}]},
+				"scores": {"anime": [{"name": "8", "value": 1}]},
+				"types": {"anime": [{"name": "tv", "value": 3}]},
+				"ratings": {"anime": [{"name": "pg_13", "value": 3}]}
+			}
+		}`))
+	})
+	sh := NewShikimoriParserWithMirrors("shikimori.one")
+	sh.api_pacer.interval = 0
+
+	profile, err := sh.UserProfile(" alice ")
+	if err != nil {
+		test.Fatalf("UserProfile вернул ошибку: %v", err)
+	}
+	if profile.ID != "1" || profile.Nickname != "alice" || profile.Age != 25 || profile.Link != "https://shikimori.one/alice" {
+		test.Errorf("UserProfile = %+v", profile)
+	}
+	stats := profile.AnimeStats
+	if stats.Statuses["completed"] != 2 || stats.Scores["8"] != 1 || stats.Types["tv"] != 3 || stats.Ratings["pg_13"] != 3 {
+		test.Errorf("статистика = %+v", stats)
+	}
+	// Запрос к api отправляется одним воркером
+	if count := sites.count(); count != 1 {
+		test.Errorf("UserProfile выполнил %d запросов, want 1", count)
+	}
+}
@@ -0,0 +1,38 @@
+package parsers
+
+import (
+	"sort"
+
+	"github.com/Quavke/AnimeParsersGo/titles"
+)
+
+// Сортирует результаты поиска по сходству названий с запросом (см. titles.BestScore).
+// Сортировка устойчивая: результаты с одинаковой оценкой остаются в порядке сайта
+func rank_by_title[T any](query string, results []T, names func(T) []string) {
+	scores := make(map[int]float64, len(results))
+	indexes := make([]int, len(results))
+	for i, result := range results {
+		indexes[i] = i
+		scores[i] = titles.BestScore(query, names(result)...)
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return scores[indexes[i]] > scores[indexes[j]]
+	})
+	ranked := make([]T, len(results))
+	for i, index := range indexes {
+		ranked[i] = results[index]
+	}
+	copy(results, ranked)
+}
+
+func rank_fast_search(query string, results []*FastSearchResult) {
+	rank_by_title(query, results, func(r *FastSearchResult) []string {
+		return []string{r.Title, r.OtherTitle}
+	})
+}
+
+func rank_sh_search(query string, results []*SHSearchResult) {
+	rank_by_title(query, results, func(r *SHSearchResult) []string {
+		return []string{r.Title, r.OriginalTitle}
+	})
+}
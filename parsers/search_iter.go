@@ -0,0 +1,138 @@
+package parsers
+
+import (
+	"iter"
+	"log"
+	"sync"
+)
+
+// Результат загрузки данных одного элемента поиска
+type enriched[R any] struct {
+	index  int
+	result R
+	err    error
+}
+
+// Параллельно загружает данные для items пулом из workers горутин.
+// Результаты отправляются в канал по мере готовности (не в порядке items), канал закрывается после обработки всех элементов.
+// После закрытия done воркеры перестают брать новые элементы и отправлять результаты
+func enrich_each[S, R any](items []S, workers int, fetch func(S) (R, error), done <-chan struct{}) <-chan enriched[R] {
+	out := make(chan enriched[R])
+	jobs := make(chan int)
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < min(max(workers, 1), len(items)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				result, err := fetch(items[index])
+				select {
+				case out <- enriched[R]{index: index, result: result, err: err}:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for index := range items {
+			select {
+			case jobs <- index:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Расширенный поиск через animego.me в виде итератора (см. Search).
+//
+// :title: Название
+//
+// Каждый результат отдается сразу после загрузки его страницы, поэтому порядок совпадает с порядком готовности, а не FastSearch.
+// Для аниме, данные которого загрузить не удалось, отдается пара (nil, *ABSearchError).
+// Если не удался сам FastSearch, отдается одна пара (nil, ошибка).
+// При выходе из цикла (break) оставшиеся страницы не загружаются
+func (ab *AniboomParser) SearchIter(title string) iter.Seq2[*ABSearchResult, error] {
+	return func(yield func(*ABSearchResult, error) bool) {
+		elements, err := ab.FastSearch(title)
+		if err != nil {
+			log.Printf("Aniboom parser error : SearchIter : FastSearch не смог найти данные для title %s. Ошибка: %v", title, err)
+			yield(nil, err)
+			return
+		}
+
+		done := make(chan struct{})
+		defer close(done)
+		for item := range enrich_each(elements, ab.search_workers, func(anime *FastSearchResult) (*ABSearchResult, error) {
+			return ab.AnimeInfo(anime.Link)
+		}, done) {
+			if item.err != nil {
+				anime := elements[item.index]
+				item_error := &ABSearchError{Index: item.index, Title: anime.Title, Link: anime.Link, Err: item.err}
+				log.Println(item_error.Error())
+				if !yield(nil, item_error) {
+					return
+				}
+				continue
+			}
+			if !yield(item.result, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Поиск с загрузкой полных данных каждого аниме в виде итератора.
+//
+// :title: название аниме
+//
+// Для каждого результата Search параллельно (см. SetSearchWorkers) загружается AnimeInfo.
+// Каждый результат отдается сразу после разбора его страницы, порядок совпадает с порядком готовности.
+// Каждый результат отдается вместе с результатом Search, для которого он загружен (см. SHSearchItem).
+// Для аниме, данные которого загрузить не удалось, отдается пара (nil, *SHSearchError).
+// Если не удался сам Search, отдается одна пара (nil, ошибка).
+// При выходе из цикла (break) оставшиеся страницы не загружаются
+func (sh *ShikimoriParser) SearchIter(title string) iter.Seq2[*SHSearchItem, error] {
+	return func(yield func(*SHSearchItem, error) bool) {
+		elements, err := sh.Search(title)
+		if err != nil {
+			log.Printf("Shikimori parser error : SearchIter : Search не смог найти данные для title %s. Ошибка: %v", title, err)
+			yield(nil, err)
+			return
+		}
+
+		done := make(chan struct{})
+		defer close(done)
+		for item := range enrich_each(elements, sh.search_workers, func(anime *SHSearchResult) (*SHAnimeInfoResult, error) {
+			return sh.AnimeInfo(anime.Link)
+		}, done) {
+			if item.err != nil {
+				anime := elements[item.index]
+				item_error := &SHSearchError{Index: item.index, Title: anime.Title, Link: anime.Link, Err: item.err}
+				log.Println(item_error.Error())
+				if !yield(nil, item_error) {
+					return
+				}
+				continue
+			}
+			if !yield(&SHSearchItem{Index: item.index, Search: elements[item.index], Info: item.result}, nil) {
+				return
+			}
+		}
+	}
+}
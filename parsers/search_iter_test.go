@@ -0,0 +1,138 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEnrichEachOrder(test *testing.T) {
+	items := []int{0, 1, 2, 3, 4}
+	done := make(chan struct{})
+	defer close(done)
+
+	// Элементы загружаются в обратном порядке (следующий - после получения предыдущего результата):
+	// результаты приходят по готовности, а index указывает на элемент
+	release := make([]chan struct{}, len(items))
+	for i := range release {
+		release[i] = make(chan struct{})
+	}
+	close(release[len(items)-1])
+	order := make([]int, 0, len(items))
+	for item := range enrich_each(items, len(items), func(item int) (string, error) {
+		<-release[item]
+		if item == 2 {
+			return "", errors.New("ошибка")
+		}
+		return fmt.Sprint(item * 10), nil
+	}, done) {
+		order = append(order, item.index)
+		if item.index > 0 {
+			close(release[item.index-1])
+		}
+		if item.index == 2 {
+			if item.err == nil {
+				test.Errorf("элемент 2 без ошибки: %+v", item)
+			}
+		} else if item.err != nil || item.result != fmt.Sprint(items[item.index]*10) {
+			test.Errorf("элемент %d = %+v", item.index, item)
+		}
+	}
+	if fmt.Sprint(order) != "[4 3 2 1 0]" {
+		test.Errorf("порядок результатов %v, want [4 3 2 1 0]", order)
+	}
+}
+
+func TestEnrichEachWorkers(test *testing.T) {
+	items := make([]int, 12)
+	done := make(chan struct{})
+	defer close(done)
+
+	var active, peak atomic.Int32
+	count := 0
+	for range enrich_each(items, 3, func(item int) (int, error) {
+		now := active.Add(1)
+		for {
+			old := peak.Load()
+			if now <= old || peak.CompareAndSwap(old, now) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		active.Add(-1)
+		return item, nil
+	}, done) {
+		count++
+	}
+	if count != len(items) {
+		test.Errorf("получено %d результатов, want %d", count, len(items))
+	}
+	if peak.Load() != 3 {
+		test.Errorf("одновременно работало %d воркеров, want 3", peak.Load())
+	}
+}
+
+func TestEnrichEachDone(test *testing.T) {
+	const workers = 2
+	items := make([]int, 20)
+	done := make(chan struct{})
+
+	var fetched atomic.Int32
+	out := enrich_each(items, workers, func(item int) (int, error) {
+		fetched.Add(1)
+		time.Sleep(5 * time.Millisecond)
+		return item, nil
+	}, done)
+
+	// Выход из цикла после первого результата: загружаются только элементы, которые уже были в работе
+	<-out
+	close(done)
+	for range out {
+	}
+	if got := fetched.Load(); got > workers+1 {
+		test.Errorf("после закрытия done загружено %d элементов из %d, want не больше %d", got, len(items), workers+1)
+	}
+}
+
+func TestShikimoriSearchIter(test *testing.T) {
+	use_test_sites(test, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host+r.URL.Path == "shikimori.one/animes/20-naruto" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, test_shikimori_page)
+			return
+		}
+		test_animego_shikimori(w, r)
+	})
+	sh := NewShikimoriParserWithMirrors("shikimori.one")
+
+	found := make(map[string]*SHSearchItem)
+	failed := make(map[int]*SHSearchError)
+	for item, err := range sh.SearchIter("naruto") {
+		if err != nil {
+			search_error, ok := err.(*SHSearchError)
+			if !ok {
+				test.Fatalf("SearchIter вернул ошибку %T: %v", err, err)
+			}
+			failed[search_error.Index] = search_error
+		} else {
+			found[item.Search.Link] = item
+		}
+	}
+
+	// Страницы Naruto: Shippuuden нет, ошибка указывает на его результат Search, а данные Naruto - на свой
+	naruto := found["https://shikimori.one/animes/20-naruto"]
+	if len(found) != 1 || naruto == nil || naruto.Search.ShikimoriID != "20" || naruto.Info.Title != "Наруто" {
+		test.Errorf("SearchIter результаты = %+v", found)
+	}
+	if len(failed) != 1 {
+		test.Fatalf("SearchIter ошибки = %+v", failed)
+	}
+	for index, search_error := range failed {
+		if index == naruto.Index || search_error.Link != "https://shikimori.one/animes/1735-naruto-shippuuden" {
+			test.Errorf("SearchIter ошибка = %+v", search_error)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package parsers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+	t "github.com/Quavke/AnimeParsersGo/tools"
+)
+
+// Признаки возрастного ограничения в тексте причины блокировки или предупреждения (в нижнем регистре)
+var age_gate_markers = []string{
+	"18+",
+	"18 лет",
+	"старше 18",
+	"возрастн",
+	"совершеннолет",
+	"для взрослых",
+}
+
+// Элементы страницы shikimori с предупреждением о контенте для взрослых
+const shikimoriAgeGateSelector = ".b-age_restricted, .age-restricted-warning, #age_restricted"
+
+// Cookie, которую animego выдает после входа с "запомнить меня" (прим: из браузера через импорт cookies)
+const animegoSessionCookie = "REMEMBERME"
+
+// Cookie, которую shikimori выдает после входа
+const shikimoriSessionCookie = "_kawai_session"
+
+// Проверяет, что текст (прим: причина блокировки плеера) говорит о возрастном ограничении
+func is_age_gate_text(text string) bool {
+	lower := strings.ToLower(text)
+	for _, marker := range age_gate_markers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Возвращает ошибку errs.AgeRestricted с подсказкой, как получить доступ
+func age_restricted_error(parser, method, link string, authenticated bool) error {
+	var hint string
+	if authenticated {
+		hint = "контент недоступен даже в сессии с входом (возможно, в профиле не подтвержден возраст)"
+	} else {
+		hint = "нужна сессия с входом на сайт (см. SetCookieSession и Login или импорт cookies из браузера)"
+	}
+	error_message := fmt.Sprintf("%s parser error : %s : страница %s имеет возрастное ограничение: %s", parser, method, link, hint)
+	log.Println(error_message)
+	return errs.NewAgeRestrictedError(error_message)
+}
+
+// Aniboom
+
+// Задает сессию cookies, которые отправляются со всеми запросами парсера (прим: cookies аккаунта animego для контента 18+).
+// Не путать с NewSession, которая создает кэш данных одного аниме (ABSession)
+func (ab *AniboomParser) SetCookieSession(session *t.Session) {
+	ab.context = t.WithSession(ab.context, session)
+}
+
+// Сессия cookies парсера или nil
+func (ab *AniboomParser) CookieSession() *t.Session {
+	return t.SessionFromContext(ab.context)
+}
+
+// Проверяет, есть ли в сессии cookie с именем name для одного из зеркал
+func session_has_cookie(session *t.Session, mirrors *t.Mirrors, name string) bool {
+	if session == nil {
+		return false
+	}
+	for _, mirror := range mirrors.List() {
+		if session.HasCookie(mirror, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Проверяет, есть ли в сессии парсера cookie входа на animego (сам вход на animego парсер не выполняет)
+func (ab *AniboomParser) authenticated() bool {
+	return session_has_cookie(ab.CookieSession(), ab.mirrors, animegoSessionCookie)
+}
+
+// Shikimori
+
+// Задает сессию cookies, которые отправляются со всеми запросами парсера (прим: после Login или импорта cookies из браузера)
+func (sh *ShikimoriParser) SetCookieSession(session *t.Session) {
+	sh.context = t.WithSession(sh.context, session)
+}
+
+// Сессия cookies парсера или nil
+func (sh *ShikimoriParser) CookieSession() *t.Session {
+	return t.SessionFromContext(sh.context)
+}
+
+// Проверяет, есть ли в сессии парсера cookie входа на одно из зеркал shikimori
+func (sh *ShikimoriParser) authenticated() bool {
+	return session_has_cookie(sh.CookieSession(), sh.mirrors, shikimoriSessionCookie)
+}
+
+// Проверяет, показывает ли страница shikimori предупреждение о контенте для взрослых
+func shikimori_age_gate(doc *goquery.Document) bool {
+	gate := doc.Find(shikimoriAgeGateSelector)
+	return gate.Length() > 0 && (gate.Find("form, a, button").Length() > 0 || is_age_gate_text(gate.Text()))
+}
+
+// Вход на shikimori по логину и паролю. Cookies входа сохраняются в сессию парсера (если сессии нет, создается сессия в памяти).
+// Если у сессии есть файл, cookies сохраняются в него.
+//
+// :nickname: никнейм или email
+//
+// :password: пароль
+//
+// Если сайт потребовал капчу или данные неверны, возвращает ошибку errs.TokenError.
+// В этом случае можно войти в браузере и импортировать cookies (см. tools.Session.ImportNetscapeFile)
+func (sh *ShikimoriParser) Login(nickname, password string) error {
+	session := sh.CookieSession()
+	if session == nil {
+		session, _ = t.NewSession("")
+		sh.SetCookieSession(session)
+	}
+
+	sign_in := fmt.Sprintf("https://%s/users/sign_in", sh.domain())
+	headers := models.Headers{
+		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0",
+	}
+	response, err := sh.mirrors.Request(sh.context, "GET", sign_in, nil, headers, false, nil)
+	if err != nil {
+		error_message := fmt.Sprintf("Shikimori parser error : Login : RequestWithContext вернул ошибку: %v", err)
+		log.Println(error_message)
+		return t.WrapRequestError(err, error_message)
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(response.Data))
+	if err != nil {
+		error_message := fmt.Sprintf("Shikimori parser error : Login : goquery не смог преобразовать ответ в документ. Ошибка: %v", err)
+		log.Println(error_message)
+		return errs.NewServiceError(error_message)
+	}
+	csrf_token, exists := doc.Find("meta[name=\"csrf-token\"]").First().Attr("content")
+	if !exists || csrf_token == "" {
+		error_message := "Shikimori parser error : Login : на странице входа не найден meta csrf-token"
+		log.Println(error_message)
+		return errs.NewHTMLParseError(error_message)
+	}
+
+	// Форма отправляется на активное зеркало, с которого получен csrf-token
+	sign_in = fmt.Sprintf("https://%s/users/sign_in", sh.domain())
+	form := url.Values{
+		"authenticity_token": {csrf_token},
+		"user[nickname]":     {nickname},
+		"user[password]":     {password},
+		"user[remember_me]":  {"1"},
+	}
+	headers["Referer"] = sign_in
+	headers["Origin"] = fmt.Sprintf("https://%s", sh.domain())
+	if result, err := session.PostForm(sh.context, sign_in, form, headers); err != nil {
+		// 4xx на форму входа (прим: 401 или 422 при неверном пароле или устаревшем csrf-token) - неудачный вход, а не ошибка сервиса
+		if result != nil && result.Response.StatusCode >= 400 && result.Response.StatusCode < 500 {
+			error_message := fmt.Sprintf("Shikimori parser error : Login : не удалось войти как %s: сервер отклонил форму входа (код %d)", nickname, result.Response.StatusCode)
+			log.Println(error_message)
+			return errs.NewTokenError(error_message)
+		}
+		log.Printf("Shikimori parser error : Login : PostForm вернул ошибку: %v", err)
+		return err
+	}
+
+	authenticated, err := sh.Authenticated()
+	if err != nil {
+		return err
+	}
+	if !authenticated {
+		error_message := fmt.Sprintf("Shikimori parser error : Login : не удалось войти как %s (неверные данные или требуется капча)", nickname)
+		log.Println(error_message)
+		return errs.NewTokenError(error_message)
+	}
+	if err := session.Save(); err != nil {
+		log.Printf("Shikimori parser warning : Login : не удалось сохранить сессию: %v", err)
+	}
+	return nil
+}
+
+// Проверяет, выполнен ли вход на shikimori в сессии парсера (запросом /api/users/whoami)
+func (sh *ShikimoriParser) Authenticated() (bool, error) {
+	if sh.CookieSession() == nil {
+		return false, nil
+	}
+	headers := models.Headers{
+		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0",
+		"Accept":     "application/json",
+	}
+	URL := fmt.Sprintf("https://%s/api/users/whoami", sh.domain())
+	response, err := sh.mirrors.Request(sh.context, "GET", URL, nil, headers, false, nil)
+	if err != nil {
+		error_message := fmt.Sprintf("Shikimori parser error : Authenticated : RequestWithContext вернул ошибку: %v", err)
+		log.Println(error_message)
+		return false, t.WrapRequestError(err, error_message)
+	}
+	body := strings.TrimSpace(string(response.Data))
+	return body != "" && body != "null", nil
+}
@@ -0,0 +1,222 @@
+package parsers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+)
+
+// Общий интерфейс источников аниме. Результаты возвращаются в едином виде models.Anime.
+//
+// Метод поиска называется SearchAnime, так как Search у парсеров уже занят и возвращает типы конкретного сайта.
+// Дополнительные возможности источника проверяются приведением к EpisodesSource, TranslationsSource и StreamSource
+type Source interface {
+	// Название источника (models.SourceAniboom, models.SourceShikimori, ...)
+	Name() string
+	// Поиск аниме по названию
+	SearchAnime(title string) ([]*models.Anime, error)
+	// Данные об аниме по ссылке на его страницу в источнике
+	Info(link string) (*models.Anime, error)
+}
+
+// Источник, который умеет возвращать список эпизодов
+type EpisodesSource interface {
+	// :link: ссылка на страницу аниме в источнике
+	Episodes(link string) ([]*models.Episode, error)
+}
+
+// Источник, который умеет возвращать переводы
+type TranslationsSource interface {
+	// :id: id аниме в источнике (прим: models.Anime.AnimegoID для aniboom)
+	Translations(id string) ([]*models.Translation, error)
+}
+
+// Источник, который умеет возвращать видео эпизода
+type StreamSource interface {
+	// :id: id аниме в источнике
+	//
+	// :translation_id: id перевода (models.Translation.ID)
+	//
+	// :episode: Номер эпизода (вышедшего) (Если фильм - 0)
+	Stream(id, translation_id string, episode int) (*models.Stream, error)
+}
+
+// Создает источник. mirror - домен сайта, пустая строка - домен по умолчанию
+type SourceFactory func(mirror string) Source
+
+var (
+	sources_mu sync.RWMutex
+	sources    = map[string]SourceFactory{
+		models.SourceAniboom:   func(mirror string) Source { return NewAniboomParser(mirror) },
+		models.SourceShikimori: func(mirror string) Source { return NewShikimoriParser(mirror) },
+		models.SourceKodik:     new_kodik_source,
+	}
+)
+
+// Для kodik mirror - домен API (прим: kodikapi.com). Токен берется из скрипта публичного плеера kodik
+func new_kodik_source(mirror string) Source {
+	kp := NewKodikParser("")
+	if mirror = strings.TrimSpace(mirror); mirror != "" {
+		kp.client.BaseURL = "https://" + strings.TrimSuffix(mirror, "/")
+	}
+	return kp
+}
+
+// Регистрирует источник под указанным названием. Существующий источник с тем же названием заменяется
+func RegisterSource(name string, factory SourceFactory) {
+	sources_mu.Lock()
+	defer sources_mu.Unlock()
+	sources[strings.ToLower(strings.TrimSpace(name))] = factory
+}
+
+// Возвращает отсортированные названия зарегистрированных источников
+func SourceNames() []string {
+	sources_mu.RLock()
+	defer sources_mu.RUnlock()
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Создает зарегистрированный источник по названию (прим: из конфигурации сервиса).
+//
+// :name: название источника без учета регистра (прим: aniboom, shikimori, kodik)
+//
+// :mirror: домен сайта, пустая строка - домен по умолчанию
+//
+// Если источник не зарегистрирован, возвращает ошибку errs.UnknownSource
+func NewSource(name, mirror string) (Source, error) {
+	sources_mu.RLock()
+	factory, exists := sources[strings.ToLower(strings.TrimSpace(name))]
+	sources_mu.RUnlock()
+	if !exists {
+		return nil, errs.NewUnknownSourceError(fmt.Sprintf("Source error : NewSource : источник %q не зарегистрирован. Доступные источники: %v", name, SourceNames()))
+	}
+	return factory(mirror), nil
+}
+
+// Aniboom
+
+func (ab *AniboomParser) Name() string {
+	return models.SourceAniboom
+}
+
+// Быстрый поиск (см. FastSearch). Для полных данных используйте Info по ссылке из результата
+func (ab *AniboomParser) SearchAnime(title string) ([]*models.Anime, error) {
+	results, err := ab.FastSearch(title)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.Anime, 0, len(results))
+	for _, result := range results {
+		res = append(res, result.Normalize())
+	}
+	return res, nil
+}
+
+// Данные об аниме (см. AnimeInfo)
+func (ab *AniboomParser) Info(link string) (*models.Anime, error) {
+	result, err := ab.AnimeInfo(link)
+	if err != nil {
+		return nil, err
+	}
+	return result.Normalize(), nil
+}
+
+// Расписание эпизодов (см. EpisodesInfo)
+func (ab *AniboomParser) Episodes(link string) ([]*models.Episode, error) {
+	episodes_info, err := ab.EpisodesInfo(link)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.Episode, 0, len(episodes_info))
+	for _, episode_info := range episodes_info {
+		typed := episode_info.Typed()
+		res = append(res, &models.Episode{
+			Number:  typed.Num,
+			Title:   episode_info.Title,
+			Status:  typed.Status,
+			AiredAt: typed.Date,
+		})
+	}
+	return res, nil
+}
+
+// Переводы, доступные в плеере aniboom (см. GetTranslationsInfo). ID - id перевода для плеера aniboom.
+// Переводы только других провайдеров пропускаются, так как Stream их не воспроизводит (см. ResolvePlayer)
+func (ab *AniboomParser) Translations(animego_id string) ([]*models.Translation, error) {
+	translations, err := ab.GetTranslationsInfo(animego_id)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.Translation, 0, len(translations))
+	for _, translation := range translations {
+		if translation.TranslationID == "" {
+			continue
+		}
+		res = append(res, &models.Translation{
+			ID:   translation.TranslationID,
+			Name: translation.Name,
+			Kind: translation.Kind,
+		})
+	}
+	return res, nil
+}
+
+// mpd плейлист эпизода (см. GetMPDPlaylist)
+func (ab *AniboomParser) Stream(animego_id, translation_id string, episode int) (*models.Stream, error) {
+	playlist, err := ab.GetMPDPlaylist(animego_id, translation_id, episode)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Stream{
+		Kind:    ABStreamPlaylist,
+		Content: playlist,
+	}, nil
+}
+
+// Shikimori
+
+func (sh *ShikimoriParser) Name() string {
+	return models.SourceShikimori
+}
+
+// Поиск (см. Search)
+func (sh *ShikimoriParser) SearchAnime(title string) ([]*models.Anime, error) {
+	results, err := sh.Search(title)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*models.Anime, 0, len(results))
+	for _, result := range results {
+		res = append(res, result.Normalize())
+	}
+	return res, nil
+}
+
+// Данные об аниме (см. AnimeInfo)
+func (sh *ShikimoriParser) Info(link string) (*models.Anime, error) {
+	result, err := sh.AnimeInfo(link)
+	if err != nil {
+		return nil, err
+	}
+	return result.Normalize(link), nil
+}
+
+var (
+	_ Source             = (*AniboomParser)(nil)
+	_ EpisodesSource     = (*AniboomParser)(nil)
+	_ TranslationsSource = (*AniboomParser)(nil)
+	_ StreamSource       = (*AniboomParser)(nil)
+	_ Source             = (*ShikimoriParser)(nil)
+	_ Source             = (*KodikParser)(nil)
+	_ TranslationsSource = (*KodikParser)(nil)
+	_ StreamSource       = (*KodikParser)(nil)
+)
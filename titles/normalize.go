@@ -0,0 +1,70 @@
+package titles
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Суффиксы сезонов и частей (применяются к уже нормализованной строке).
+// Убираются только явные обозначения: число без слова сезона или части может быть частью названия (прим: "Steins;Gate 0", "Kaiji 2")
+var season_suffix_res = []*regexp.Regexp{
+	regexp.MustCompile(`\s+(\d+|[ivx]+)(st|nd|rd|th)?\s+(season|сезон|part|часть|cour)$`),
+	regexp.MustCompile(`\s+(season|сезон|part|часть|cour)\s+(\d+|[ivx]+)$`),
+	regexp.MustCompile(`\s+(tv|тв|movie|фильм|ova|ona)(\s+\d+)?$`),
+}
+
+// Приводит название к виду для сравнения: нижний регистр, ё > е, знаки препинания заменяются пробелами, повторные пробелы убираются
+//
+// прим: "Поднятие уровня в одиночку: Восстань из тени!" > "поднятие уровня в одиночку восстань из тени"
+func Normalize(title string) string {
+	return normalize(title, true)
+}
+
+// fold_yo - заменять ли ё на е. Для транслитерации ё сохраняется (прим: "кё" > "kyo")
+func normalize(title string, fold_yo bool) string {
+	var b strings.Builder
+	space := true
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r == 'ё' && fold_yo:
+			r = 'е'
+		case r == '’' || r == '\'' || r == '`':
+			// Апострофы не разделяют слова (прим: "Frieren's" > "frierens")
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+		} else if !space {
+			b.WriteRune(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Убирает из нормализованного названия обозначение сезона или части (прим: "shingeki no kyojin season 2" > "shingeki no kyojin")
+func StripSeason(normalized string) string {
+	for {
+		stripped := normalized
+		for _, re := range season_suffix_res {
+			stripped = re.ReplaceAllString(stripped, "")
+		}
+		if stripped == normalized || stripped == "" {
+			return normalized
+		}
+		normalized = stripped
+	}
+}
+
+// Ключ названия для точного сравнения: Normalize + транслитерация в латиницу без пробелов.
+// Названия одного тайтла на русском (по Поливанову) и ромадзи часто дают один ключ
+func Key(title string) string {
+	return strings.ReplaceAll(ToLatin(normalize(title, false)), " ", "")
+}
+
+// То же, что Key, но без обозначения сезона. Разные сезоны одного тайтла дают один ключ
+func SeriesKey(title string) string {
+	return strings.ReplaceAll(ToLatin(StripSeason(normalize(title, false))), " ", "")
+}
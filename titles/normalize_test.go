@@ -0,0 +1,65 @@
+package titles
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Поднятие уровня в одиночку: Восстань из тени!", "поднятие уровня в одиночку восстань из тени"},
+		{"  Frieren's   Journey  ", "frierens journey"},
+		{"Ёсино", "есино"},
+		{"Re:Zero -Starting Life-", "re zero starting life"},
+		{"!!!", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.title); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestStripSeason(t *testing.T) {
+	tests := []struct {
+		normalized string
+		want       string
+	}{
+		{"shingeki no kyojin season 2", "shingeki no kyojin"},
+		{"shingeki no kyojin 2nd season", "shingeki no kyojin"},
+		{"атака титанов 2 сезон", "атака титанов"},
+		{"ванпанчмен часть ii", "ванпанчмен"},
+		{"kaguya sama 3rd season part 2", "kaguya sama"},
+		{"ванпанчмен ii", "ванпанчмен ii"},
+		{"steins gate 0", "steins gate 0"},
+		{"kaiji 2", "kaiji 2"},
+		{"kimetsu no yaiba movie", "kimetsu no yaiba"},
+		{"naruto", "naruto"},
+	}
+	for _, tt := range tests {
+		if got := StripSeason(tt.normalized); got != tt.want {
+			t.Errorf("StripSeason(%q) = %q, want %q", tt.normalized, got, tt.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		title  string
+		key    string
+		series string
+	}{
+		{"Сингэки но Кёдзин", "shingekinokyojin", "shingekinokyojin"},
+		{"Shingeki no Kyojin Season 2", "shingekinokyojinseason2", "shingekinokyojin"},
+		{"Ёсино", "yoshino", "yoshino"},
+		{"Дзеро", "zero", "zero"},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.title); got != tt.key {
+			t.Errorf("Key(%q) = %q, want %q", tt.title, got, tt.key)
+		}
+		if got := SeriesKey(tt.title); got != tt.series {
+			t.Errorf("SeriesKey(%q) = %q, want %q", tt.title, got, tt.series)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package titles
+
+import "strings"
+
+// Расстояние Левенштейна между строками (по символам, а не байтам)
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 {
+		return len(rb)
+	}
+	if len(rb) == 0 {
+		return len(ra)
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// Сходство по расстоянию Левенштейна от 0 до 1 (1 - строки совпадают)
+func LevenshteinSimilarity(a, b string) float64 {
+	longest := max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(Levenshtein(a, b))/float64(longest)
+}
+
+// Сходство Джаро-Винклера от 0 до 1. Сильнее учитывает общий префикс, поэтому хорошо подходит для коротких названий
+func JaroWinkler(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := max(len(ra), len(rb))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	matched_a := make([]bool, len(ra))
+	matched_b := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		from, to := max(0, i-window), min(len(rb), i+window+1)
+		for j := from; j < to; j++ {
+			if !matched_b[j] && ra[i] == rb[j] {
+				matched_a[i], matched_b[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range ra {
+		if !matched_a[i] {
+			continue
+		}
+		for !matched_b[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// Сходство нормализованных строк: максимум из Джаро-Винклера и Левенштейна
+func similarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	return max(JaroWinkler(a, b), LevenshteinSimilarity(a, b))
+}
+
+// Оценивает сходство двух названий от 0 до 1.
+//
+// Названия сравниваются после Normalize, а также после транслитерации (Key), поэтому
+// "Сингэки но Кёдзин" и "Shingeki no Kyojin" считаются одинаковыми.
+// Совпадение без обозначения сезона (SeriesKey) оценивается чуть ниже полного совпадения
+func Similarity(a, b string) float64 {
+	na, nb := Normalize(a), Normalize(b)
+	if na == "" || nb == "" {
+		return 0
+	}
+	if na == nb {
+		return 1
+	}
+	score := max(similarity(na, nb), similarity(Key(a), Key(b)))
+	if score < 0.95 {
+		if sa, sb := SeriesKey(a), SeriesKey(b); sa != "" && sa == sb {
+			score = 0.95
+		}
+	}
+	// Запрос, целиком содержащийся в названии (прим: "наруто" в "наруто ураганные хроники")
+	if score < 0.9 && (strings.Contains(" "+nb+" ", " "+na+" ") || strings.Contains(" "+na+" ", " "+nb+" ")) {
+		score = 0.9
+	}
+	return score
+}
+
+// Возвращает наибольшее сходство query с одним из названий candidates (прим: русское, оригинальное и другие названия аниме)
+func BestScore(query string, candidates ...string) float64 {
+	best := 0.0
+	for _, candidate := range candidates {
+		if score := Similarity(query, candidate); score > best {
+			best = score
+		}
+	}
+	return best
+}
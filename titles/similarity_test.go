@@ -0,0 +1,53 @@
+package titles
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"ёж", "еж", 1},
+		{"наруто", "наруто", 0},
+	}
+	for _, tt := range tests {
+		if got := Levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestScores(t *testing.T) {
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"LevenshteinSimilarity empty", LevenshteinSimilarity("", ""), 1},
+		{"LevenshteinSimilarity kitten", LevenshteinSimilarity("kitten", "sitting"), 1 - 3.0/7},
+		{"JaroWinkler martha", JaroWinkler("martha", "marhta"), 0.9611},
+		{"JaroWinkler dwayne", JaroWinkler("dwayne", "duane"), 0.84},
+		{"JaroWinkler dixon", JaroWinkler("dixon", "dicksonx"), 0.8133},
+		{"JaroWinkler empty", JaroWinkler("", "abc"), 0},
+		{"JaroWinkler no matches", JaroWinkler("abc", "xyz"), 0},
+		{"Similarity translit", Similarity("Сингэки но Кёдзин", "Shingeki no Kyojin"), 1},
+		{"Similarity season", Similarity("Shingeki no Kyojin Season 2", "Shingeki no Kyojin"), 0.95},
+		{"Similarity contains", Similarity("наруто", "Наруто: Ураганные хроники"), 0.9},
+		{"Similarity empty", Similarity("", "Naruto"), 0},
+		{"BestScore", BestScore("naruto", "Bleach", "Naruto"), 1},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 0.0001 {
+			t.Errorf("%s = %.4f, want %.4f", tt.name, tt.got, tt.want)
+		}
+	}
+	if score := Similarity("Naruto", "Bleach"); score >= 0.9 {
+		t.Errorf("Similarity(Naruto, Bleach) = %.4f, want < 0.9", score)
+	}
+}
@@ -0,0 +1,114 @@
+package titles
+
+import (
+	"sort"
+	"strings"
+)
+
+// Сочетания кириллицы, которые по системе Поливанова соответствуют слогам ромадзи (прим: "си" > "shi").
+// Проверяются раньше посимвольной таблицы
+var polivanov_to_latin = map[string]string{
+	"дзя": "ja", "дзю": "ju", "дзё": "jo", "дзе": "ze", "дзи": "ji", "дзу": "zu", "дзэ": "ze", "дзо": "zo", "дза": "za",
+	"тя": "cha", "тю": "chu", "тё": "cho", "ти": "chi",
+	"ся": "sha", "сю": "shu", "сё": "sho", "си": "shi",
+	"цу": "tsu", "дзь": "j",
+	"кя": "kya", "кю": "kyu", "кё": "kyo",
+	"ня": "nya", "ню": "nyu", "нё": "nyo",
+	"хя": "hya", "хю": "hyu", "хё": "hyo",
+	"мя": "mya", "мю": "myu", "мё": "myo",
+	"ря": "rya", "рю": "ryu", "рё": "ryo",
+	"гя": "gya", "гю": "gyu", "гё": "gyo",
+	"бя": "bya", "бю": "byu", "бё": "byo",
+	"пя": "pya", "пю": "pyu", "пё": "pyo",
+	"эй": "ei", "ой": "oi", "ай": "ai", "уй": "ui",
+}
+
+// Посимвольная транслитерация кириллицы (для остальных слов)
+var cyrillic_to_latin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh", 'з': "z", 'и': "i",
+	'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "h", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// Слоги ромадзи в кириллицу по системе Поливанова (прим: "shi" > "си", "tsu" > "цу")
+var latin_to_polivanov = map[string]string{
+	"kya": "кя", "kyu": "кю", "kyo": "кё", "sha": "ся", "shu": "сю", "sho": "сё", "shi": "си",
+	"cha": "тя", "chu": "тю", "cho": "тё", "chi": "ти", "tsu": "цу", "nya": "ня", "nyu": "ню", "nyo": "нё",
+	"hya": "хя", "hyu": "хю", "hyo": "хё", "mya": "мя", "myu": "мю", "myo": "мё", "rya": "ря", "ryu": "рю",
+	"ryo": "рё", "gya": "гя", "gyu": "гю", "gyo": "гё", "bya": "бя", "byu": "бю", "byo": "бё", "pya": "пя",
+	"pyu": "пю", "pyo": "пё", "ja": "дзя", "ju": "дзю", "jo": "дзё", "ji": "дзи", "zu": "дзу",
+	"ka": "ка", "ki": "ки", "ku": "ку", "ke": "кэ", "ko": "ко", "sa": "са", "su": "су", "se": "сэ", "so": "со",
+	"ta": "та", "te": "тэ", "to": "то", "na": "на", "ni": "ни", "nu": "ну", "ne": "нэ", "no": "но",
+	"ha": "ха", "hi": "хи", "fu": "фу", "he": "хэ", "ho": "хо", "ma": "ма", "mi": "ми", "mu": "му", "me": "мэ",
+	"mo": "мо", "ya": "я", "yu": "ю", "yo": "ё", "ra": "ра", "ri": "ри", "ru": "ру", "re": "рэ", "ro": "ро",
+	"wa": "ва", "wo": "о", "ga": "га", "gi": "ги", "gu": "гу", "ge": "гэ", "go": "го", "za": "дза", "ze": "дзэ",
+	"zo": "дзо", "da": "да", "de": "дэ", "do": "до", "ba": "ба", "bi": "би", "bu": "бу", "be": "бэ", "bo": "бо",
+	"pa": "па", "pi": "пи", "pu": "пу", "pe": "пэ", "po": "по", "ei": "эй", "ai": "ай", "oi": "ой", "ui": "уй",
+	"a": "а", "i": "и", "u": "у", "e": "э", "o": "о", "n": "н",
+}
+
+// Остальные латинские буквы
+var latin_to_cyrillic = map[rune]string{
+	'b': "б", 'c': "к", 'd': "д", 'f': "ф", 'g': "г", 'h': "х", 'j': "дж", 'k': "к", 'l': "л", 'm': "м",
+	'p': "п", 'q': "к", 'r': "р", 's': "с", 't': "т", 'v': "в", 'w': "в", 'x': "кс", 'y': "й", 'z': "з",
+}
+
+// Ключи таблицы, отсортированные по убыванию длины (для поиска самого длинного совпадения)
+func sorted_keys(table map[string]string) []string {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len([]rune(keys[i])) != len([]rune(keys[j])) {
+			return len([]rune(keys[i])) > len([]rune(keys[j]))
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+var (
+	polivanov_keys = sorted_keys(polivanov_to_latin)
+	romaji_keys    = sorted_keys(latin_to_polivanov)
+)
+
+func transliterate(text string, keys []string, table map[string]string, letters map[rune]string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		matched := false
+		for _, key := range keys {
+			key_runes := []rune(key)
+			if i+len(key_runes) <= len(runes) && string(runes[i:i+len(key_runes)]) == key {
+				b.WriteString(table[key])
+				i += len(key_runes)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		if value, exists := letters[runes[i]]; exists {
+			b.WriteString(value)
+		} else {
+			b.WriteRune(runes[i])
+		}
+		i++
+	}
+	return b.String()
+}
+
+// Транслитерирует кириллицу в латиницу. Японские слоги, записанные по Поливанову, переводятся в ромадзи (прим: "сингэки но кёдзин" > "shingeki no kyojin").
+// Ожидает строку в нижнем регистре (см. Normalize). Латиница и цифры не изменяются
+func ToLatin(text string) string {
+	return transliterate(text, polivanov_keys, polivanov_to_latin, cyrillic_to_latin)
+}
+
+// Транслитерирует ромадзи в кириллицу по системе Поливанова (прим: "shingeki no kyojin" > "сингэки но кёдзин").
+// Ожидает строку в нижнем регистре (см. Normalize). Кириллица и цифры не изменяются
+func ToCyrillic(text string) string {
+	return transliterate(text, romaji_keys, latin_to_polivanov, latin_to_cyrillic)
+}
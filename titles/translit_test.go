@@ -0,0 +1,42 @@
+package titles
+
+import "testing"
+
+func TestToLatin(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"сингэки но кёдзин", "shingeki no kyojin"},
+		{"дзе", "ze"},
+		{"дзэ", "ze"},
+		{"дзё", "jo"},
+		{"цукимити", "tsukimichi"},
+		{"рюдзин", "ryujin"},
+		{"щука", "schuka"},
+		{"naruto 2", "naruto 2"},
+	}
+	for _, tt := range tests {
+		if got := ToLatin(tt.text); got != tt.want {
+			t.Errorf("ToLatin(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestToCyrillic(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"shingeki no kyojin", "сингэки но кёдзин"},
+		{"tsukimichi", "цукимити"},
+		{"zero", "дзэро"},
+		{"tokyo ghoul", "токё гхоул"},
+		{"наруто 2", "наруто 2"},
+	}
+	for _, tt := range tests {
+		if got := ToCyrillic(tt.text); got != tt.want {
+			t.Errorf("ToCyrillic(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
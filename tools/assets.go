@@ -0,0 +1,343 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+)
+
+// Качество jpeg для миниатюр
+const thumbnailQuality = 85
+
+// Вариант картинки из атрибута srcset
+type SrcsetCandidate struct {
+	URL string
+	// Плотность пикселей (1 для "1x" и вариантов без описания). Для описаний вида "300w" - ширина относительно самого маленького варианта
+	Density float64
+	// Ширина из описания "300w" (0 - не указана)
+	Width int
+}
+
+// Разбирает атрибут srcset (прим: "/a.jpg, /a_2x.jpg 2x") на варианты.
+// Строка без описаний (просто ссылка) возвращается как один вариант 1x
+func ParseSrcset(srcset string) []*SrcsetCandidate {
+	res := make([]*SrcsetCandidate, 0)
+	min_width := 0
+	for _, part := range strings.Split(srcset, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		candidate := &SrcsetCandidate{URL: fields[0], Density: 1}
+		if len(fields) > 1 {
+			descriptor := strings.ToLower(fields[len(fields)-1])
+			switch {
+			case strings.HasSuffix(descriptor, "x"):
+				if density, err := strconv.ParseFloat(strings.TrimSuffix(descriptor, "x"), 64); err == nil && density > 0 {
+					candidate.Density = density
+				}
+			case strings.HasSuffix(descriptor, "w"):
+				if width, err := strconv.Atoi(strings.TrimSuffix(descriptor, "w")); err == nil && width > 0 {
+					candidate.Width = width
+					if min_width == 0 || width < min_width {
+						min_width = width
+					}
+				}
+			}
+		}
+		res = append(res, candidate)
+	}
+	if min_width > 0 {
+		for _, candidate := range res {
+			if candidate.Width > 0 {
+				candidate.Density = float64(candidate.Width) / float64(min_width)
+			}
+		}
+	}
+	return res
+}
+
+// Выбирает ссылку из srcset для плотности density (прим: 1 или 2): наименьший вариант не хуже density, иначе самый большой.
+// Для обычной ссылки возвращает ее саму
+func SrcsetVariant(srcset string, density float64) string {
+	candidates := ParseSrcset(srcset)
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Density < candidates[j].Density
+	})
+	for _, candidate := range candidates {
+		if candidate.Density >= density {
+			return candidate.URL
+		}
+	}
+	return candidates[len(candidates)-1].URL
+}
+
+// Загруженная картинка
+type Asset struct {
+	// Ссылка, по которой картинка была загружена (выбранный вариант srcset)
+	URL string `json:"url"`
+	// SHA-256 содержимого (hex)
+	Hash string `json:"hash"`
+	// Путь до файла: <папка>/<первые 2 символа хэша>/<хэш><расширение>
+	Path        string `json:"path"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	// Размер картинки (0, если формат не поддерживается для декодирования)
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Путь до миниатюры ("" - миниатюры отключены или не удалось создать)
+	Thumbnail string `json:"thumbnail"`
+	// true, если файл с таким содержимым уже был в папке
+	Existing bool `json:"existing"`
+}
+
+// Загрузчик картинок (постеры, скриншоты, персонажи) в локальную папку с адресацией по SHA-256.
+// Картинки загружаются с заголовком Referer сайта, поэтому не блокируются защитой от хотлинкинга
+type AssetFetcher struct {
+	dir     string
+	referer string
+	density float64
+	// Ширина миниатюр (0 - миниатюры не создаются)
+	thumbnail_width int
+	context         context.Context
+}
+
+// :dir: папка для файлов (прим: data/assets). Создается при первой загрузке
+//
+// :referer: заголовок Referer для запросов (прим: https://shikimori.one/). Пустая строка - адрес сайта картинки
+func NewAssetFetcher(dir, referer string) *AssetFetcher {
+	return &AssetFetcher{
+		dir:     dir,
+		referer: referer,
+		density: 1,
+		context: context.Background(),
+	}
+}
+
+func (f *AssetFetcher) SetContext(ctx context.Context) {
+	f.context = ctx
+}
+
+// Плотность варианта srcset (1 - обычный, 2 - вариант для экранов высокой плотности)
+func (f *AssetFetcher) SetDensity(density float64) {
+	if density > 0 {
+		f.density = density
+	}
+}
+
+// Ширина миниатюр в пикселях (0 - не создавать). Миниатюры сохраняются в jpeg в <папка>/thumbs/<ширина>/
+func (f *AssetFetcher) SetThumbnailWidth(width int) {
+	f.thumbnail_width = max(width, 0)
+}
+
+// Загружает картинку и сохраняет ее в папку загрузчика.
+//
+// :src: ссылка на картинку или значение srcset (вариант выбирается по SetDensity)
+//
+// Возвращает ссылку на Asset
+func (f *AssetFetcher) Fetch(src string) (*Asset, error) {
+	link := SrcsetVariant(src, f.density)
+	if link == "" {
+		return nil, errs.NewPostArgumentsError("Assets error : Fetch : пустая ссылка на картинку")
+	}
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Host == "" {
+		return nil, errs.NewPostArgumentsError(fmt.Sprintf("Assets error : Fetch : неверная ссылка на картинку %q", link))
+	}
+
+	referer := f.referer
+	if referer == "" {
+		referer = fmt.Sprintf("%s://%s/", parsed.Scheme, parsed.Host)
+	}
+	headers := models.Headers{
+		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0",
+		"Accept":     "image/avif,image/webp,image/png,image/jpeg,*/*;q=0.8",
+		"Referer":    referer,
+	}
+	// Картинка загружается одним запросом, без параллельных копий
+	resp, err := RequestWithContext(WithRequestWorkers(f.context, 1), "GET", link, nil, headers, false, nil)
+	if err != nil {
+		error_message := fmt.Sprintf("Assets error : Fetch : RequestWithContext вернул ошибку для %s: %v", link, err)
+		log.Println(error_message)
+		return nil, WrapRequestError(err, error_message)
+	}
+
+	// Заголовку Content-Type верится, только если тип не определился по содержимому (прим: avif),
+	// иначе страница блокировки с заголовком image/jpeg сохранилась бы как картинка
+	content_type := http.DetectContentType(resp.Data)
+	if header := resp.Response.Header.Get("Content-Type"); header != "" && content_type == "application/octet-stream" {
+		if media, _, err := mime.ParseMediaType(header); err == nil && strings.HasPrefix(media, "image/") {
+			content_type = media
+		}
+	}
+	if !strings.HasPrefix(content_type, "image/") {
+		error_message := fmt.Sprintf("Assets error : Fetch : по ссылке %s получена не картинка (%s)", link, content_type)
+		log.Println(error_message)
+		return nil, errs.NewUnexpectedBehaviorError(error_message)
+	}
+
+	sum := sha256.Sum256(resp.Data)
+	asset := &Asset{
+		URL:         link,
+		Hash:        hex.EncodeToString(sum[:]),
+		ContentType: content_type,
+		Size:        len(resp.Data),
+	}
+	asset.Path = filepath.Join(f.dir, asset.Hash[:2], asset.Hash+asset_extension(content_type, parsed.Path))
+	if asset.Existing, err = write_asset(asset.Path, resp.Data); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(resp.Data))
+	if err != nil {
+		// webp и avif не декодируются стандартной библиотекой: файл сохраняется без размеров и миниатюры
+		return asset, nil
+	}
+	asset.Width, asset.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	if f.thumbnail_width > 0 {
+		thumbnail := filepath.Join(f.dir, "thumbs", strconv.Itoa(f.thumbnail_width), asset.Hash[:2], asset.Hash+".jpg")
+		if err := write_thumbnail(thumbnail, img, f.thumbnail_width); err != nil {
+			log.Printf("Assets warning : Fetch : не удалось создать миниатюру для %s: %v", link, err)
+		} else {
+			asset.Thumbnail = thumbnail
+		}
+	}
+	return asset, nil
+}
+
+// Загружает несколько картинок (см. Fetch). Пустые ссылки пропускаются.
+//
+// Если часть картинок не загрузилась, возвращает остальные вместе с ошибкой errors.Join(...)
+//
+// Возвращает словарь ссылка (или srcset) > Asset
+func (f *AssetFetcher) FetchAll(srcs []string) (map[string]*Asset, error) {
+	res := make(map[string]*Asset)
+	errors_list := make([]error, 0)
+	for _, src := range srcs {
+		if src == "" || res[src] != nil {
+			continue
+		}
+		asset, err := f.Fetch(src)
+		if err != nil {
+			errors_list = append(errors_list, err)
+			continue
+		}
+		res[src] = asset
+	}
+	return res, errors.Join(errors_list...)
+}
+
+// Расширение файла по типу содержимого (или по ссылке, если тип неизвестен)
+func asset_extension(content_type, link_path string) string {
+	switch content_type {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "image/avif":
+		return ".avif"
+	}
+	if ext := strings.ToLower(path.Ext(link_path)); len(ext) > 1 && len(ext) <= 5 {
+		return ext
+	}
+	return ".bin"
+}
+
+// Записывает файл, если его еще нет. Возвращает true, если файл уже существовал
+func write_asset(file_path string, data []byte) (bool, error) {
+	if _, err := os.Stat(file_path); err == nil {
+		return true, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(file_path), 0o755); err != nil {
+		return false, errs.NewServiceError(fmt.Sprintf("Assets error : не удалось создать папку %s. Ошибка: %v", filepath.Dir(file_path), err))
+	}
+	// Временный файл с уникальным именем, чтобы параллельные загрузки одной картинки не писали в один файл
+	file, err := os.CreateTemp(filepath.Dir(file_path), filepath.Base(file_path)+".*.tmp")
+	if err != nil {
+		return false, errs.NewServiceError(fmt.Sprintf("Assets error : не удалось создать временный файл в %s. Ошибка: %v", filepath.Dir(file_path), err))
+	}
+	tmp := file.Name()
+	_, err = file.Write(data)
+	if close_err := file.Close(); err == nil {
+		err = close_err
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0o644)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return false, errs.NewServiceError(fmt.Sprintf("Assets error : не удалось записать файл %s. Ошибка: %v", tmp, err))
+	}
+	if err := os.Rename(tmp, file_path); err != nil {
+		os.Remove(tmp)
+		return false, errs.NewServiceError(fmt.Sprintf("Assets error : не удалось переименовать %s в %s. Ошибка: %v", tmp, file_path, err))
+	}
+	return false, nil
+}
+
+// Уменьшает картинку до ширины width (с сохранением пропорций, картинки меньше width не увеличиваются) и сохраняет в jpeg
+func write_thumbnail(file_path string, img image.Image, width int) error {
+	if _, err := os.Stat(file_path); err == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resize_image(img, width), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return err
+	}
+	_, err := write_asset(file_path, buf.Bytes())
+	return err
+}
+
+// Уменьшение картинки усреднением пикселей (box filter)
+func resize_image(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	src_w, src_h := bounds.Dx(), bounds.Dy()
+	if src_w <= width || src_w == 0 {
+		return img
+	}
+	height := max(src_h*width/src_w, 1)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*src_h/height, max((y+1)*src_h/height, y*src_h/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*src_w/width, max((x+1)*src_w/width, x*src_w/width+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,169 @@
+package tools
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+func TestParseSrcset(t *testing.T) {
+	tests := []struct {
+		srcset string
+		want   []SrcsetCandidate
+	}{
+		{"/a.jpg", []SrcsetCandidate{{URL: "/a.jpg", Density: 1}}},
+		{"/a.jpg, /a_2x.jpg 2x", []SrcsetCandidate{{URL: "/a.jpg", Density: 1}, {URL: "/a_2x.jpg", Density: 2}}},
+		{" /a_2x.jpg 2x ,/a_15.jpg 1.5x", []SrcsetCandidate{{URL: "/a_2x.jpg", Density: 2}, {URL: "/a_15.jpg", Density: 1.5}}},
+		{"/s.jpg 300w, /l.jpg 600w", []SrcsetCandidate{{URL: "/s.jpg", Density: 1, Width: 300}, {URL: "/l.jpg", Density: 2, Width: 600}}},
+		{"/a.jpg bad", []SrcsetCandidate{{URL: "/a.jpg", Density: 1}}},
+		{"", []SrcsetCandidate{}},
+	}
+	for _, tt := range tests {
+		got := ParseSrcset(tt.srcset)
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseSrcset(%q) вернул %d вариантов, want %d", tt.srcset, len(got), len(tt.want))
+			continue
+		}
+		for i, candidate := range got {
+			if *candidate != tt.want[i] {
+				t.Errorf("ParseSrcset(%q)[%d] = %+v, want %+v", tt.srcset, i, *candidate, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestSrcsetVariant(t *testing.T) {
+	tests := []struct {
+		srcset  string
+		density float64
+		want    string
+	}{
+		{"/a.jpg, /a_2x.jpg 2x", 1, "/a.jpg"},
+		{"/a.jpg, /a_2x.jpg 2x", 2, "/a_2x.jpg"},
+		{"/a_2x.jpg 2x, /a.jpg 1x", 1, "/a.jpg"},
+		{"/a.jpg, /a_2x.jpg 2x", 3, "/a_2x.jpg"},
+		{"/a.jpg, /a_2x.jpg 2x", 1.5, "/a_2x.jpg"},
+		{"/a_2x.jpg 2x", 1, "/a_2x.jpg"},
+		{"/s.jpg 300w, /l.jpg 600w", 2, "/l.jpg"},
+		{"https://shikimori.one/a.jpg", 2, "https://shikimori.one/a.jpg"},
+		{"", 1, ""},
+	}
+	for _, tt := range tests {
+		if got := SrcsetVariant(tt.srcset, tt.density); got != tt.want {
+			t.Errorf("SrcsetVariant(%q, %v) = %q, want %q", tt.srcset, tt.density, got, tt.want)
+		}
+	}
+}
+
+// Картинка png размером width x height
+func test_png(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 8), G: uint8(y * 8), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode вернул ошибку: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestAssetFetcherFetch(t *testing.T) {
+	data := test_png(t, 40, 20)
+	referers := make([]string, 0)
+	sites := use_test_mirrors(t, map[string]http.HandlerFunc{
+		"img.test": func(w http.ResponseWriter, r *http.Request) {
+			referers = append(referers, r.Header.Get("Referer"))
+			// Тип содержимого определяется по данным, если сервер его не указал
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(data)
+		},
+	})
+	dir := t.TempDir()
+	fetcher := NewAssetFetcher(dir, "https://shikimori.test/")
+	fetcher.SetThumbnailWidth(10)
+
+	asset, err := fetcher.Fetch("https://img.test/poster.jpg, https://img.test/poster_2x.jpg 2x")
+	if err != nil {
+		t.Fatalf("Fetch вернул ошибку: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	want := filepath.Join(dir, hash[:2], hash+".png")
+	if asset.URL != "https://img.test/poster.jpg" || asset.Hash != hash || asset.Path != want || asset.ContentType != "image/png" || asset.Existing {
+		t.Errorf("Fetch = %+v, want путь %s", asset, want)
+	}
+	if asset.Width != 40 || asset.Height != 20 || asset.Size != len(data) {
+		t.Errorf("Fetch размеры = %dx%d (%d байт), want 40x20 (%d байт)", asset.Width, asset.Height, asset.Size, len(data))
+	}
+	if saved, err := os.ReadFile(asset.Path); err != nil || !bytes.Equal(saved, data) {
+		t.Errorf("сохраненный файл отличается от загруженного (ошибка %v)", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(asset.Path)); len(entries) != 1 {
+		t.Errorf("в папке картинки %d файлов, want 1 (временные файлы должны удаляться)", len(entries))
+	}
+
+	// Миниатюра уменьшается до заданной ширины с сохранением пропорций
+	file, err := os.Open(asset.Thumbnail)
+	if err != nil {
+		t.Fatalf("миниатюра %q не создана: %v", asset.Thumbnail, err)
+	}
+	defer file.Close()
+	thumbnail, format, err := image.DecodeConfig(file)
+	if err != nil || format != "jpeg" || thumbnail.Width != 10 || thumbnail.Height != 5 {
+		t.Errorf("миниатюра %s %dx%d (ошибка %v), want jpeg 10x5", format, thumbnail.Width, thumbnail.Height, err)
+	}
+
+	// Повторная загрузка того же содержимого не перезаписывает файл
+	asset, err = fetcher.Fetch("https://img.test/poster.jpg")
+	if err != nil || !asset.Existing || asset.Path != want {
+		t.Errorf("повторный Fetch = %+v, %v, want Existing", asset, err)
+	}
+	// Каждая картинка загружается одним запросом
+	if count := sites.count("img.test"); count != 2 {
+		t.Errorf("запросов к img.test: %d, want 2", count)
+	}
+	for _, referer := range referers {
+		if referer != "https://shikimori.test/" {
+			t.Errorf("Referer = %q, want https://shikimori.test/", referer)
+		}
+	}
+
+	// Без заданного Referer отправляется адрес сайта картинки
+	referers = referers[:0]
+	if _, err := NewAssetFetcher(dir, "").Fetch("https://img.test/poster.jpg"); err != nil || len(referers) != 1 || referers[0] != "https://img.test/" {
+		t.Errorf("Fetch без Referer: ошибка %v, Referer %v", err, referers)
+	}
+}
+
+func TestAssetFetcherNotImage(t *testing.T) {
+	use_test_mirrors(t, map[string]http.HandlerFunc{
+		"img.test": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write([]byte("<html><body>Доступ запрещен</body></html>"))
+		},
+	})
+	dir := t.TempDir()
+
+	// Заголовок image/* не принимается, если содержимое не картинка
+	_, err := NewAssetFetcher(dir, "").Fetch("https://img.test/poster.jpg")
+	var unexpected *errs.UnexpectedBehavior
+	if !errors.As(err, &unexpected) {
+		t.Errorf("Fetch для страницы html вернул %T: %v, want *errs.UnexpectedBehavior", err, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("для страницы html в папке создано %d файлов", len(entries))
+	}
+}
@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+)
+
+// Названия анти-бот защит для errs.Challenge.Provider
+const (
+	ChallengeCloudflare = "cloudflare"
+	ChallengeDDoSGuard  = "ddos-guard"
+)
+
+// Сколько байт начала html страницы с кодом 200 проверяется на заголовок страницы проверки
+const challengeSniffLimit = 8 * 1024
+
+// Заголовки страниц проверки (в нижнем регистре). Только по ним распознаются страницы проверки с кодом 200
+var challenge_titles = map[string][]string{
+	ChallengeCloudflare: {
+		"<title>just a moment...</title>",
+		"<title>attention required! | cloudflare</title>",
+	},
+	ChallengeDDoSGuard: {
+		"<title>ddos-guard</title>",
+	},
+}
+
+// Признаки страниц проверки в теле ответа с кодом отличным от 200 (в нижнем регистре).
+// Обычные страницы сайта за защитой тоже могут их содержать (прим: cookie __ddg1), поэтому для ответов 200 они не используются
+var challenge_markers = map[string][]string{
+	ChallengeCloudflare: {
+		"_cf_chl_opt",
+		"cf-browser-verification",
+		"attention required! | cloudflare",
+	},
+	ChallengeDDoSGuard: {
+		"check.ddos-guard.net",
+		"ddos-guard/js-challenge",
+		"__ddg1",
+	},
+}
+
+// Решает проверку анти-бот защиты: возвращает заголовки (прим: Cookie с cf_clearance и User-Agent браузера, в котором они получены),
+// с которыми запрос повторяется один раз. Если решить проверку не удалось, возвращает ошибку
+type ChallengeSolver func(ctx context.Context, challenge *errs.Challenge) (models.Headers, error)
+
+var (
+	solver_mu sync.RWMutex
+	solver    ChallengeSolver
+)
+
+// Задает решатель проверок для всех запросов (nil - не решать, сразу возвращать errs.Challenge)
+func SetChallengeSolver(s ChallengeSolver) {
+	solver_mu.Lock()
+	defer solver_mu.Unlock()
+	solver = s
+}
+
+func challenge_solver() ChallengeSolver {
+	solver_mu.RLock()
+	defer solver_mu.RUnlock()
+	return solver
+}
+
+// Решатель, который подставляет заранее полученные cookies (прим: из браузера после прохождения проверки).
+//
+// :cookies: значение заголовка Cookie (прим: "cf_clearance=...; __ddg2_=...")
+//
+// :user_agent: User-Agent браузера, в котором получены cookies (Cloudflare привязывает cf_clearance к нему). Пустая строка - не менять
+func CookieSolver(cookies, user_agent string) ChallengeSolver {
+	return func(ctx context.Context, challenge *errs.Challenge) (models.Headers, error) {
+		headers := models.Headers{"Cookie": cookies}
+		if user_agent != "" {
+			headers["User-Agent"] = user_agent
+		}
+		return headers, nil
+	}
+}
+
+// Определяет, является ли ответ страницей проверки анти-бот защиты.
+// Для ответов с кодом 200 проверяются только заголовок cf-mitigated и заголовок html страницы в ее начале,
+// для остальных кодов - также признаки в теле ответа
+//
+// :resp: ответ сервера (тело уже прочитано)
+//
+// :body: тело ответа (достаточно начала страницы)
+//
+// Возвращает название защиты (ChallengeCloudflare, ChallengeDDoSGuard) или пустую строку
+func DetectChallenge(resp *http.Response, body []byte) string {
+	if resp != nil && strings.EqualFold(resp.Header.Get("cf-mitigated"), "challenge") {
+		return ChallengeCloudflare
+	}
+	if resp == nil || resp.StatusCode == http.StatusOK {
+		return detect_challenge_title(resp, body)
+	}
+	lower := bytes.ToLower(body)
+	for _, provider := range []string{ChallengeCloudflare, ChallengeDDoSGuard} {
+		for _, markers := range [][]string{challenge_titles[provider], challenge_markers[provider]} {
+			for _, marker := range markers {
+				if bytes.Contains(lower, []byte(marker)) {
+					return provider
+				}
+			}
+		}
+	}
+	if resp != nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusServiceUnavailable) {
+		// Страница 403/503 от DDoS-Guard без узнаваемой разметки
+		if strings.EqualFold(resp.Header.Get("Server"), "ddos-guard") {
+			return ChallengeDDoSGuard
+		}
+	}
+	return ""
+}
+
+// Ищет заголовок страницы проверки в начале html страницы (json, картинки и другие ответы не проверяются)
+func detect_challenge_title(resp *http.Response, body []byte) string {
+	if resp != nil {
+		if media, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err != nil || media != "text/html" {
+			return ""
+		}
+	}
+	if len(body) > challengeSniffLimit {
+		body = body[:challengeSniffLimit]
+	}
+	lower := bytes.ToLower(body)
+	for _, provider := range []string{ChallengeCloudflare, ChallengeDDoSGuard} {
+		for _, title := range challenge_titles[provider] {
+			if bytes.Contains(lower, []byte(title)) {
+				return provider
+			}
+		}
+	}
+	return ""
+}
+
+// Превращает ошибку запроса в errs.ServiceError с сообщением message, но ошибку errs.Challenge (в том числе обернутую)
+// возвращает как есть, чтобы вызывающий код мог распознать страницу проверки
+func WrapRequestError(err error, message string) error {
+	var challenge *errs.Challenge
+	if errors.As(err, &challenge) {
+		return challenge
+	}
+	return errs.NewServiceError(message)
+}
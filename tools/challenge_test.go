@@ -0,0 +1,125 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+func challenge_response(status int, content_type string, headers ...string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}}
+	if content_type != "" {
+		resp.Header.Set("Content-Type", content_type)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		resp.Header.Set(headers[i], headers[i+1])
+	}
+	return resp
+}
+
+func TestDetectChallenge(t *testing.T) {
+	cf_page := "<!DOCTYPE html><html><head><title>Just a moment...</title></head><body><script>window._cf_chl_opt={}</script></body></html>"
+	ddg_page := "<html><head><title>DDoS-Guard</title></head><body>check.ddos-guard.net</body></html>"
+	normal_page := "<html><head><title>Наруто</title><script>document.cookie='__ddg1=abc'</script></head><body>_cf_chl_opt cf-browser-verification</body></html>"
+	late_title := "<html><head>" + strings.Repeat(" ", challengeSniffLimit) + "<title>Just a moment...</title></head></html>"
+
+	tests := []struct {
+		name string
+		resp *http.Response
+		body string
+		want string
+	}{
+		{"cf-mitigated", challenge_response(http.StatusOK, "application/json", "cf-mitigated", "challenge"), "{}", ChallengeCloudflare},
+		{"cloudflare 403", challenge_response(http.StatusForbidden, "text/html"), cf_page, ChallengeCloudflare},
+		{"cloudflare 200", challenge_response(http.StatusOK, "text/html; charset=UTF-8"), cf_page, ChallengeCloudflare},
+		{"ddos-guard 503 markup", challenge_response(http.StatusServiceUnavailable, "text/html"), "<script src=\"/.well-known/ddos-guard/js-challenge/index.js\"></script>", ChallengeDDoSGuard},
+		{"ddos-guard 403 server", challenge_response(http.StatusForbidden, "text/html", "Server", "ddos-guard"), "", ChallengeDDoSGuard},
+		{"ddos-guard 200", challenge_response(http.StatusOK, "text/html"), ddg_page, ChallengeDDoSGuard},
+		{"loose markers on 200", challenge_response(http.StatusOK, "text/html"), normal_page, ""},
+		{"loose markers on 403", challenge_response(http.StatusForbidden, "text/html"), normal_page, ChallengeCloudflare},
+		{"title in json", challenge_response(http.StatusOK, "application/json"), `{"description":"<title>Just a moment...</title>"}`, ""},
+		{"title after sniff limit", challenge_response(http.StatusOK, "text/html"), late_title, ""},
+		{"404 without markers", challenge_response(http.StatusNotFound, "text/html"), "<title>Not found</title>", ""},
+	}
+	for _, tt := range tests {
+		if got := DetectChallenge(tt.resp, []byte(tt.body)); got != tt.want {
+			t.Errorf("%s: DetectChallenge = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWrapRequestError(t *testing.T) {
+	challenge := errs.NewChallengeError(ChallengeCloudflare, "https://example.com", http.StatusForbidden, "challenge")
+	for _, err := range []error{challenge, fmt.Errorf("mirrors: %w", challenge)} {
+		if got := WrapRequestError(err, "message"); got != challenge {
+			t.Errorf("WrapRequestError(%v) = %v, want errs.Challenge", err, got)
+		}
+	}
+	got := WrapRequestError(fmt.Errorf("timeout"), "message")
+	if _, ok := got.(*errs.ServiceError); !ok || got.Error() != "message" {
+		t.Errorf("WrapRequestError(timeout) = %#v, want errs.ServiceError", got)
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	use_test_mirrors(t, map[string]http.HandlerFunc{
+		"ok.test": func(w http.ResponseWriter, r *http.Request) {
+			cookie, err := r.Cookie("REMEMBERME")
+			if err != nil || cookie.Value != "token" {
+				t.Errorf("запрос без cookie сессии: %v", r.Cookies())
+			}
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "1", Path: "/"})
+			fmt.Fprint(w, "ok")
+		},
+		"missing.test": respond(http.StatusNotFound, "<title>Not found</title>"),
+		"cf.test":      respond(http.StatusForbidden, "<html><head><title>Just a moment...</title></head></html>"),
+	})
+	session, err := NewSession("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Jar().Add(&Cookie{Name: "REMEMBERME", Value: "token", Domain: "ok.test", HostOnly: true})
+	ctx := WithSession(context.Background(), session)
+	client := NewHTTPClient(time.Second)
+
+	get := func(link string) (*http.Response, error) {
+		request, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client.Do(request)
+	}
+
+	// Cookies сессии отправляются с запросом, cookies ответа сохраняются в сессию
+	resp, err := get("https://ok.test/")
+	if err != nil {
+		t.Fatalf("запрос вернул ошибку: %v", err)
+	}
+	resp.Body.Close()
+	if !session.HasCookie("ok.test", "sid") {
+		t.Error("cookie ответа не сохранена в сессию")
+	}
+
+	// Ответ с кодом отличным от 200 без страницы проверки возвращается целиком
+	resp, err = get("https://missing.test/")
+	if err != nil {
+		t.Fatalf("запрос вернул ошибку: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound || string(body) != "<title>Not found</title>" {
+		t.Errorf("ответ %d: %q", resp.StatusCode, body)
+	}
+
+	var challenge *errs.Challenge
+	if _, err := get("https://cf.test/"); !errors.As(err, &challenge) || challenge.Provider != ChallengeCloudflare {
+		t.Errorf("запрос страницы проверки вернул %T: %v, want *errs.Challenge", err, err)
+	}
+}
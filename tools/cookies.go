@@ -0,0 +1,314 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Префикс строки Netscape файла для cookies с флагом HttpOnly (так их сохраняют curl и расширения браузеров)
+const netscapeHttpOnlyPrefix = "#HttpOnly_"
+
+// Cookie в хранилище CookieJar
+type Cookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Домен без точки в начале (прим: shikimori.one)
+	Domain string `json:"domain"`
+	// true - cookie отправляется только на Domain, false - и на его поддомены
+	HostOnly bool   `json:"host_only"`
+	Path     string `json:"path"`
+	Secure   bool   `json:"secure"`
+	HttpOnly bool   `json:"http_only"`
+	// Нулевое время - сессионная cookie (в ExportNetscape выгружается, только если sessions = true)
+	Expires time.Time `json:"expires"`
+}
+
+func (c *Cookie) key() string {
+	return c.Domain + ";" + c.Path + ";" + c.Name
+}
+
+func (c *Cookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+func (c *Cookie) matches(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if c.HostOnly {
+		if host != c.Domain {
+			return false
+		}
+	} else if host != c.Domain && !strings.HasSuffix(host, "."+c.Domain) {
+		return false
+	}
+	if c.Secure && u.Scheme != "https" {
+		return false
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if c.Path == "" || c.Path == "/" || path == c.Path {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(c.Path, "/")+"/")
+}
+
+// Хранилище cookies (реализует http.CookieJar), которое можно выгрузить и загрузить в формате Netscape.
+//
+// В отличие от net/http/cookiejar позволяет получить все cookies, поэтому используется в Session для сохранения в файл
+type CookieJar struct {
+	mu      sync.RWMutex
+	cookies map[string]*Cookie
+}
+
+func NewCookieJar() *CookieJar {
+	return &CookieJar{cookies: make(map[string]*Cookie)}
+}
+
+// Сохраняет cookies из ответа сервера по ссылке u (http.CookieJar)
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, cookie := range cookies {
+		c := &Cookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   strings.ToLower(u.Hostname()),
+			HostOnly: true,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+		}
+		if cookie.Domain != "" {
+			domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+			host := strings.ToLower(u.Hostname())
+			switch {
+			case host == domain:
+				// Cookie для публичного суффикса (прим: github.io) допустима только как host-only cookie самого суффикса
+				if !is_public_suffix(domain) && net.ParseIP(host) == nil {
+					c.HostOnly = false
+				}
+			case strings.HasSuffix(host, "."+domain) && net.ParseIP(host) == nil && !is_public_suffix(domain):
+				// Сервер может задать cookie для родительского домена, но не для публичного суффикса (прим: com, co.uk)
+				c.Domain = domain
+				c.HostOnly = false
+			default:
+				continue
+			}
+		}
+		if c.Path == "" || !strings.HasPrefix(c.Path, "/") {
+			c.Path = default_cookie_path(u)
+		}
+		switch {
+		case cookie.MaxAge < 0:
+			c.Expires = now
+		case cookie.MaxAge > 0:
+			c.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		case !cookie.Expires.IsZero():
+			c.Expires = cookie.Expires
+		}
+		if c.expired(now) {
+			delete(j.cookies, c.key())
+			continue
+		}
+		j.cookies[c.key()] = c
+	}
+}
+
+// Проверяет, является ли домен публичным суффиксом (прим: com, co.uk, github.io), для которого сайты не могут задавать cookies
+func is_public_suffix(domain string) bool {
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return suffix == domain
+}
+
+func default_cookie_path(u *url.URL) string {
+	path := u.EscapedPath()
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+// Возвращает cookies для запроса по ссылке u (http.CookieJar)
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	now := time.Now()
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	matched := make([]*Cookie, 0)
+	for _, cookie := range j.cookies {
+		if !cookie.expired(now) && cookie.matches(u) {
+			matched = append(matched, cookie)
+		}
+	}
+	// Более точный путь первым (RFC 6265)
+	sort.Slice(matched, func(a, b int) bool {
+		if len(matched[a].Path) != len(matched[b].Path) {
+			return len(matched[a].Path) > len(matched[b].Path)
+		}
+		return matched[a].Name < matched[b].Name
+	})
+	result := make([]*http.Cookie, 0, len(matched))
+	for _, cookie := range matched {
+		result = append(result, &http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	return result
+}
+
+// Возвращает копии всех неистекших cookies, отсортированные по домену, пути и имени
+func (j *CookieJar) All() []*Cookie {
+	now := time.Now()
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	result := make([]*Cookie, 0, len(j.cookies))
+	for _, cookie := range j.cookies {
+		if cookie.expired(now) {
+			continue
+		}
+		c := *cookie
+		result = append(result, &c)
+	}
+	sort.Slice(result, func(a, b int) bool {
+		return result[a].key() < result[b].key()
+	})
+	return result
+}
+
+// Добавляет cookie (заменяет cookie с тем же доменом, путем и именем)
+func (j *CookieJar) Add(cookie *Cookie) {
+	c := *cookie
+	c.Domain = strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.cookies[c.key()] = &c
+}
+
+// Копирует cookies домена from (и его поддоменов) на домен to, если там еще нет cookie с тем же путем и именем
+// (прим: cookies входа на одно зеркало сайта для запросов на другое)
+func (j *CookieJar) CopyDomain(from, to string) {
+	from = strings.ToLower(strings.TrimPrefix(from, "."))
+	to = strings.ToLower(strings.TrimPrefix(to, "."))
+	if from == "" || to == "" || from == to {
+		return
+	}
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, cookie := range j.cookies {
+		if cookie.expired(now) || (cookie.Domain != from && !strings.HasSuffix(cookie.Domain, "."+from)) {
+			continue
+		}
+		c := *cookie
+		c.Domain = strings.TrimSuffix(cookie.Domain, from) + to
+		if _, exists := j.cookies[c.key()]; !exists {
+			j.cookies[c.key()] = &c
+		}
+	}
+}
+
+// Удаляет все cookies
+func (j *CookieJar) Clear() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.cookies = make(map[string]*Cookie)
+}
+
+// Загружает cookies в формате Netscape (cookies.txt, который выгружают curl, yt-dlp и расширения браузеров).
+//
+// Строки: домен, поддомены (TRUE/FALSE), путь, secure (TRUE/FALSE), время истечения (unix), имя, значение - через табуляцию.
+// Истекшие cookies пропускаются. Если строка не соответствует формату, возвращает ошибку errs.ServiceError с номером строки
+func (j *CookieJar) ImportNetscape(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	now := time.Now()
+	line_number := 0
+	for scanner.Scan() {
+		line_number++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		http_only := false
+		if strings.HasPrefix(line, netscapeHttpOnlyPrefix) {
+			line = strings.TrimPrefix(line, netscapeHttpOnlyPrefix)
+			http_only = true
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return errs.NewServiceError(fmt.Sprintf("Cookies error : ImportNetscape : строка %d: ожидалось 7 полей через табуляцию, найдено %d", line_number, len(fields)))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return errs.NewServiceError(fmt.Sprintf("Cookies error : ImportNetscape : строка %d: неверное время истечения %q", line_number, fields[4]))
+		}
+		cookie := &Cookie{
+			Domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: http_only,
+		}
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+		if cookie.expired(now) {
+			continue
+		}
+		j.Add(cookie)
+	}
+	if err := scanner.Err(); err != nil {
+		return errs.NewServiceError(fmt.Sprintf("Cookies error : ImportNetscape : не удалось прочитать cookies. Ошибка: %v", err))
+	}
+	return nil
+}
+
+// Выгружает cookies в формате Netscape. Сессионные cookies (без времени истечения) выгружаются, только если sessions = true
+func (j *CookieJar) ExportNetscape(w io.Writer, sessions bool) error {
+	b := &strings.Builder{}
+	b.WriteString("# Netscape HTTP Cookie File\n")
+	for _, cookie := range j.All() {
+		if cookie.Expires.IsZero() && !sessions {
+			continue
+		}
+		domain := cookie.Domain
+		subdomains := "FALSE"
+		if !cookie.HostOnly {
+			domain = "." + domain
+			subdomains = "TRUE"
+		}
+		if cookie.HttpOnly {
+			domain = netscapeHttpOnlyPrefix + domain
+		}
+		secure := "FALSE"
+		if cookie.Secure {
+			secure = "TRUE"
+		}
+		expires := int64(0)
+		if !cookie.Expires.IsZero() {
+			expires = cookie.Expires.Unix()
+		}
+		fmt.Fprintf(b, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", domain, subdomains, cookie.Path, secure, expires, cookie.Name, cookie.Value)
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return errs.NewServiceError(fmt.Sprintf("Cookies error : ExportNetscape : не удалось записать cookies. Ошибка: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func jar_cookie_names(jar *CookieJar, link string) string {
+	u, _ := url.Parse(link)
+	names := make([]string, 0)
+	for _, cookie := range jar.Cookies(u) {
+		names = append(names, cookie.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestCookieJarDomains(t *testing.T) {
+	jar := NewCookieJar()
+	from, _ := url.Parse("https://www.shikimori.one/")
+	jar.SetCookies(from, []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "parent", Value: "1", Domain: ".shikimori.one"},
+		{Name: "self", Value: "1", Domain: "www.shikimori.one"},
+		{Name: "tld", Value: "1", Domain: "one"},
+		{Name: "other", Value: "1", Domain: "animego.org"},
+	})
+	github, _ := url.Parse("https://user.github.io/")
+	jar.SetCookies(github, []*http.Cookie{
+		{Name: "suffix", Value: "1", Domain: "github.io"},
+		{Name: "user", Value: "1", Domain: "user.github.io"},
+	})
+	uk, _ := url.Parse("https://shop.example.co.uk/")
+	jar.SetCookies(uk, []*http.Cookie{{Name: "couk", Value: "1", Domain: ".co.uk"}})
+	ip, _ := url.Parse("http://127.0.0.1:8080/")
+	jar.SetCookies(ip, []*http.Cookie{{Name: "ip", Value: "1", Domain: "0.0.1"}, {Name: "ip_host", Value: "1", Domain: "127.0.0.1"}})
+
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"https://www.shikimori.one/", "host,parent,self"},
+		{"https://shikimori.one/", "parent"},
+		{"https://api.www.shikimori.one/", "parent,self"},
+		{"https://other.one/", ""},
+		{"https://animego.org/", ""},
+		{"https://github.io/", ""},
+		{"https://another.github.io/", ""},
+		{"https://user.github.io/", "user"},
+		{"https://other.co.uk/", ""},
+		{"http://127.0.0.1:8080/", "ip_host"},
+	}
+	for _, tt := range tests {
+		if got := jar_cookie_names(jar, tt.link); got != tt.want {
+			t.Errorf("Cookies(%s) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestCookieJarPathsAndExpiry(t *testing.T) {
+	jar := NewCookieJar()
+	from, _ := url.Parse("https://shikimori.one/api/users/whoami")
+	jar.SetCookies(from, []*http.Cookie{
+		{Name: "default_path", Value: "1"},
+		{Name: "root", Value: "1", Path: "/"},
+		{Name: "secure", Value: "1", Path: "/", Secure: true},
+		{Name: "expired", Value: "1", Path: "/", Expires: time.Now().Add(-time.Hour)},
+	})
+	if got := jar_cookie_names(jar, "https://shikimori.one/api/users/1"); got != "default_path,root,secure" {
+		t.Errorf("Cookies(/api/users/1) = %q", got)
+	}
+	if got := jar_cookie_names(jar, "http://shikimori.one/"); got != "root" {
+		t.Errorf("Cookies(http /) = %q", got)
+	}
+
+	// MaxAge < 0 удаляет cookie
+	jar.SetCookies(from, []*http.Cookie{{Name: "root", Value: "", Path: "/", MaxAge: -1}})
+	if got := jar_cookie_names(jar, "https://shikimori.one/"); got != "secure" {
+		t.Errorf("Cookies после удаления = %q", got)
+	}
+}
+
+func TestCookieJarNetscape(t *testing.T) {
+	expires := time.Now().Add(24 * time.Hour).Unix()
+	input := "# Netscape HTTP Cookie File\n" +
+		"#HttpOnly_.shikimori.one\tTRUE\t/\tTRUE\t" + strconv.FormatInt(expires, 10) + "\t_kawai_session\tabc\n" +
+		"animego.org\tFALSE\t/\tFALSE\t0\tsession\txyz\n" +
+		".old.one\tTRUE\t/\tFALSE\t1\texpired\t1\n"
+	jar := NewCookieJar()
+	if err := jar.ImportNetscape(strings.NewReader(input)); err != nil {
+		t.Fatalf("ImportNetscape вернул ошибку: %v", err)
+	}
+	if got := len(jar.All()); got != 2 {
+		t.Fatalf("после ImportNetscape %d cookies, want 2", got)
+	}
+	if got := jar_cookie_names(jar, "https://www.shikimori.one/"); got != "_kawai_session" {
+		t.Errorf("Cookies(www.shikimori.one) = %q", got)
+	}
+
+	var out strings.Builder
+	if err := jar.ExportNetscape(&out, false); err != nil {
+		t.Fatalf("ExportNetscape вернул ошибку: %v", err)
+	}
+	if !strings.Contains(out.String(), "#HttpOnly_.shikimori.one\tTRUE\t/\tTRUE\t") || strings.Contains(out.String(), "animego.org") {
+		t.Errorf("ExportNetscape без сессионных cookies = %q", out.String())
+	}
+
+	if err := jar.ImportNetscape(strings.NewReader("bad line\n")); err == nil {
+		t.Error("ImportNetscape для неверной строки должен вернуть ошибку")
+	}
+}
@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+// Начала названий месяцев в родительном падеже и сокращениях (прим: января, янв.)
+var russian_months = []struct {
+	prefix string
+	month  time.Month
+}{
+	{"янв", time.January},
+	{"фев", time.February},
+	{"мар", time.March},
+	{"апр", time.April},
+	{"май", time.May},
+	{"мая", time.May},
+	{"июн", time.June},
+	{"июл", time.July},
+	{"авг", time.August},
+	{"сен", time.September},
+	{"окт", time.October},
+	{"ноя", time.November},
+	{"дек", time.December},
+}
+
+var (
+	year_re     = regexp.MustCompile(`\b(19|20)\d{2}\b`)
+	duration_re = regexp.MustCompile(`(\d+)\s*(ч|час|мин|сек)`)
+	number_re   = regexp.MustCompile(`\d+`)
+)
+
+func russian_month(word string) (time.Month, bool) {
+	word = strings.Trim(strings.ToLower(word), ".,")
+	for _, m := range russian_months {
+		if strings.HasPrefix(word, m.prefix) {
+			return m.month, true
+		}
+	}
+	return 0, false
+}
+
+// Разбирает дату на русском (прим: "12 января 2024", "4 окт. 2023 г.", "4 окт. 2023г.", "январь 2024", "2024").
+// Если день или месяц не указан, подставляется 1 число или январь. Время - полночь UTC
+func ParseRussianDate(raw string) (time.Time, error) {
+	year, month, day := 0, time.January, 1
+	for _, word := range strings.Fields(strings.ToLower(raw)) {
+		word = strings.Trim(word, ".,")
+		// Год с сокращением без пробела (прим: "2024г.")
+		if trimmed := strings.TrimSuffix(word, "г"); trimmed != word && number_re.MatchString(trimmed) {
+			word = trimmed
+		}
+		if number, err := strconv.Atoi(word); err == nil {
+			if len(word) == 4 {
+				year = number
+			} else if number >= 1 && number <= 31 {
+				day = number
+			}
+			continue
+		}
+		if m, ok := russian_month(word); ok {
+			month = m
+		}
+	}
+	if year == 0 {
+		return time.Time{}, errs.NewUnexpectedBehaviorError(fmt.Sprintf("Dates error : ParseRussianDate : в строке %q не найден год", raw))
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), nil
+}
+
+// Разбирает период на русском (прим: "с 6 января 2024 по 30 марта 2024", "с 4 окт. 2023 г.", "2024").
+// Если конец периода не указан, возвращает нулевое время в end
+func ParseRussianDateRange(raw string) (start, end time.Time, err error) {
+	value := strings.TrimSpace(strings.ToLower(raw))
+	value = strings.TrimPrefix(value, "с ")
+	parts := []string{value}
+	for _, sep := range []string{" по ", " до ", " — ", " – ", " - "} {
+		if strings.Contains(value, sep) {
+			parts = strings.SplitN(value, sep, 2)
+			break
+		}
+	}
+	start, err = ParseRussianDate(parts[0])
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if len(parts) == 2 {
+		end, err = ParseRussianDate(parts[1])
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	return start, end, nil
+}
+
+// Разбирает длительность на русском (прим: "24 мин. ~ серия", "1 ч. 30 мин.", "23 мин.")
+func ParseRussianDuration(raw string) (time.Duration, error) {
+	var result time.Duration
+	matches := duration_re.FindAllStringSubmatch(strings.ToLower(raw), -1)
+	if len(matches) == 0 {
+		return 0, errs.NewUnexpectedBehaviorError(fmt.Sprintf("Dates error : ParseRussianDuration : в строке %q не найдена длительность", raw))
+	}
+	for _, match := range matches {
+		value, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "ч", "час":
+			result += time.Duration(value) * time.Hour
+		case "мин":
+			result += time.Duration(value) * time.Minute
+		case "сек":
+			result += time.Duration(value) * time.Second
+		}
+	}
+	return result, nil
+}
+
+// Возвращает первый год (19xx или 20xx) из строки или 0 (прим: "Зима 2024" > 2024)
+func ParseYear(raw string) int {
+	match := year_re.FindString(raw)
+	if match == "" {
+		return 0
+	}
+	year, _ := strconv.Atoi(match)
+	return year
+}
+
+// Возвращает все числа из строки (прим: "5 / 12" > [5 12], "16+" > [16])
+func ParseNumbers(raw string) []int {
+	result := make([]int, 0)
+	for _, match := range number_re.FindAllString(raw, -1) {
+		value, err := strconv.Atoi(match)
+		if err == nil {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// Часовой пояс сайтов (animego и shikimori показывают время по Москве). Если база часовых поясов недоступна, используется UTC+3
+var MoscowLocation = moscow_location()
+
+func moscow_location() *time.Location {
+	if location, err := time.LoadLocation("Europe/Moscow"); err == nil {
+		return location
+	}
+	return time.FixedZone("MSK", 3*60*60)
+}
+
+// Разбирает время с сайта (прим: "2024-10-19T17:30:00.000+03:00" из data-datetime или api shikimori) и переводит его в MoscowLocation.
+// Время без часового пояса считается московским
+func ParseSiteTime(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	for _, layout := range []string{time.RFC3339Nano, time.RFC3339, "2006-01-02 15:04:05 -0700", "2006-01-02 15:04:05 MST"} {
+		if parsed, err := time.Parse(layout, raw); err == nil {
+			return parsed.In(MoscowLocation), nil
+		}
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if parsed, err := time.ParseInLocation(layout, raw, MoscowLocation); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, errs.NewUnexpectedBehaviorError(fmt.Sprintf("Dates error : ParseSiteTime : не удалось разобрать время %q", raw))
+}
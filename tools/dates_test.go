@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRussianDate(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want time.Time
+	}{
+		{"12 января 2024", time.Date(2024, time.January, 12, 0, 0, 0, 0, time.UTC)},
+		{"4 окт. 2023 г.", time.Date(2023, time.October, 4, 0, 0, 0, 0, time.UTC)},
+		{"4 окт. 2023г.", time.Date(2023, time.October, 4, 0, 0, 0, 0, time.UTC)},
+		{"2024г", time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"январь 2024", time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"3 мая 2022", time.Date(2022, time.May, 3, 0, 0, 0, 0, time.UTC)},
+		{"мар. 2021", time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{"2024", time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseRussianDate(tt.raw)
+		if err != nil {
+			t.Errorf("ParseRussianDate(%q) вернул ошибку: %v", tt.raw, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseRussianDate(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+
+	if _, err := ParseRussianDate("12 января"); err == nil {
+		t.Error("ParseRussianDate без года должен вернуть ошибку")
+	}
+}
+
+func TestParseRussianDateRange(t *testing.T) {
+	tests := []struct {
+		raw        string
+		start, end time.Time
+	}{
+		{"с 6 января 2024 по 30 марта 2024", time.Date(2024, time.January, 6, 0, 0, 0, 0, time.UTC), time.Date(2024, time.March, 30, 0, 0, 0, 0, time.UTC)},
+		{"с 4 окт. 2023г.", time.Date(2023, time.October, 4, 0, 0, 0, 0, time.UTC), time.Time{}},
+		{"2024", time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+	}
+	for _, tt := range tests {
+		start, end, err := ParseRussianDateRange(tt.raw)
+		if err != nil {
+			t.Errorf("ParseRussianDateRange(%q) вернул ошибку: %v", tt.raw, err)
+			continue
+		}
+		if !start.Equal(tt.start) || !end.Equal(tt.end) {
+			t.Errorf("ParseRussianDateRange(%q) = %v, %v, want %v, %v", tt.raw, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestParseRussianDuration(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want time.Duration
+	}{
+		{"24 мин. ~ серия", 24 * time.Minute},
+		{"1 ч. 30 мин.", 90 * time.Minute},
+		{"2 час. 5 сек.", 2*time.Hour + 5*time.Second},
+	}
+	for _, tt := range tests {
+		got, err := ParseRussianDuration(tt.raw)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRussianDuration(%q) = %v, %v, want %v", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseSiteTime(t *testing.T) {
+	want := time.Date(2024, time.October, 19, 17, 30, 0, 0, MoscowLocation)
+	for _, raw := range []string{"2024-10-19T17:30:00.000+03:00", "2024-10-19T14:30:00Z", "2024-10-19 17:30:00", "2024-10-19 17:30"} {
+		got, err := ParseSiteTime(raw)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseSiteTime(%q) = %v, %v, want %v", raw, got, err, want)
+		}
+	}
+}
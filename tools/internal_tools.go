@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -25,30 +26,158 @@ var (
 	maxAttempts = 10
 )
 
+type workers_key struct{}
+
+// Возвращает контекст, запросы с которым (см. RequestWithContext) отправляются workers воркерами вместо numWorkers.
+// Один воркер - каждый запрос отправляется на сервер один раз (прим: когда запросы и так выполняются параллельно).
+// Значения меньше 1 игнорируются
+func WithRequestWorkers(ctx context.Context, workers int) context.Context {
+	if workers < 1 {
+		return ctx
+	}
+	return context.WithValue(ctx, workers_key{}, workers)
+}
+
+// Количество воркеров для запроса с контекстом ctx (см. WithRequestWorkers)
+func request_workers(ctx context.Context) int {
+	if workers, ok := ctx.Value(workers_key{}).(int); ok {
+		return workers
+	}
+	return numWorkers
+}
+
 type worker_params struct {
 	ctx     context.Context
 	method  string
 	URL     string
 	params  models.Params
 	headers models.Headers
+	failure *worker_failure
 }
 
-func worker(w_params *worker_params, ch chan<- *http.Response, wg *sync.WaitGroup, id int) {
+// Код последнего ответа сервера с кодом отличным от 200 среди воркеров (0 - сервер ни разу не ответил)
+type worker_failure struct {
+	mu     sync.Mutex
+	status int
+}
+
+func (f *worker_failure) set(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = status
+}
+
+func (f *worker_failure) get() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.status
+}
+
+var (
+	transport_mu sync.RWMutex
+	transport    http.RoundTripper
+)
+
+// Задает транспорт для всех запросов к сайтам (прим: прокси или подмена сайтов тестовым сервером). nil - http.DefaultTransport
+func SetTransport(rt http.RoundTripper) {
+	transport_mu.Lock()
+	defer transport_mu.Unlock()
+	transport = rt
+}
+
+func request_transport() http.RoundTripper {
+	transport_mu.RLock()
+	defer transport_mu.RUnlock()
+	return transport
+}
+
+// Транспорт NewHTTPClient: запросы через транспорт SetTransport, cookies сессии из контекста запроса и распознавание страниц проверки
+type site_transport struct{}
+
+func (site_transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	rt := request_transport()
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	session := SessionFromContext(request.Context())
+	if session != nil {
+		request = request.Clone(request.Context())
+		for _, cookie := range session.jar.Cookies(request.URL) {
+			request.AddCookie(cookie)
+		}
+	}
+	resp, err := rt.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	if session != nil {
+		if cookies := resp.Cookies(); len(cookies) > 0 {
+			session.jar.SetCookies(request.URL, cookies)
+		}
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, challengeBodyLimit))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if provider := DetectChallenge(resp, body); provider != "" {
+		resp.Body.Close()
+		return nil, challenge_error(provider, request.URL.String(), resp.StatusCode)
+	}
+	// Прочитанная часть тела возвращается в ответ
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	return resp, nil
+}
+
+// Создает http клиент для запросов в обход RequestWithContext (прим: клиенты api), который работает так же, как запросы парсеров:
+// запросы идут через транспорт SetTransport (прокси), с cookies сессии из контекста запроса (см. WithSession),
+// а страница проверки Cloudflare или DDoS-Guard возвращается как ошибка errs.Challenge (обернутая в *url.Error)
+//
+// :timeout: таймаут запроса (0 - без таймаута)
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: site_transport{},
+	}
+}
+
+// Ответ воркера: ответ сервера с кодом 200 или ошибка, после которой повторять запрос бессмысленно (прим: страница проверки)
+type worker_result struct {
+	resp *http.Response
+	err  error
+}
+
+// Сколько байт тела ответа с кодом отличным от 200 читается для поиска страницы проверки
+const challengeBodyLimit = 256 * 1024
+
+func worker(w_params *worker_params, ch chan<- *worker_result, wg *sync.WaitGroup, id int) {
 	defer wg.Done()
 	url_params := url.Values{}
 
+	URL := w_params.URL
 	if w_params.params != nil {
 		for key, value := range w_params.params {
 			url_params.Set(key, value)
 		}
-		w_params.URL = w_params.URL + "?" + url_params.Encode()
+		URL = URL + "?" + url_params.Encode()
 	}
 
 	client := &http.Client{
-		Timeout: 5 * time.Second,
+		Timeout:   5 * time.Second,
+		Transport: request_transport(),
+	}
+	if session := SessionFromContext(w_params.ctx); session != nil {
+		client.Jar = session.jar
 	}
 
-	request, err := http.NewRequestWithContext(w_params.ctx, w_params.method, w_params.URL, nil)
+	request, err := http.NewRequestWithContext(w_params.ctx, w_params.method, URL, nil)
 
 	if err != nil {
 		error_message := fmt.Sprintf("Request error : %d : http не смог создать request. Ошибка: %v", id, err)
@@ -76,7 +205,17 @@ func worker(w_params *worker_params, ch chan<- *http.Response, wg *sync.WaitGrou
 		} else if resp.StatusCode != http.StatusOK {
 			error_message := fmt.Sprintf("Request error : %d : http клиент не смог выполнить запрос. Попытка %d", id, attempt)
 			log.Println(error_message)
+			w_params.failure.set(resp.StatusCode)
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, challengeBodyLimit))
 			resp.Body.Close()
+			if provider := DetectChallenge(resp, body); provider != "" {
+				// Страница проверки не исчезнет при повторе запроса
+				select {
+				case ch <- &worker_result{err: challenge_error(provider, URL, resp.StatusCode)}:
+				default:
+				}
+				return
+			}
 			continue
 		} else if resp.StatusCode == http.StatusTooManyRequests {
 			error_message := fmt.Sprintf("Request error : %d : клиент получил ответ со статусом StatusTooManyRequests. Попытка %d", id, attempt)
@@ -104,15 +243,53 @@ func worker(w_params *worker_params, ch chan<- *http.Response, wg *sync.WaitGrou
 	}
 
 	select {
-	case ch <- resp:
+	case ch <- &worker_result{resp: resp}:
 		return
 	default:
+		resp.Body.Close()
 		return
 	}
 }
 
+func challenge_error(provider, URL string, status_code int) error {
+	error_message := fmt.Sprintf("Request error : сайт вернул страницу проверки %s (код %d) для %s", provider, status_code, URL)
+	log.Println(error_message)
+	return errs.NewChallengeError(provider, URL, status_code, error_message)
+}
+
+// Выполняет запрос несколькими воркерами и возвращает первый успешный ответ.
+//
+// Если вместо ответа сайт вернул страницу проверки Cloudflare или DDoS-Guard, возвращает ошибку errs.Challenge.
+// Если задан решатель проверок (см. SetChallengeSolver), запрос повторяется один раз с заголовками от него.
+// Если в контексте есть сессия (см. WithSession), запрос отправляет и сохраняет ее cookies
 func RequestWithContext(ctx context.Context, method, URL string, params models.Params, headers models.Headers, jsonResp bool, jsonType models.JSONResponse) (*RequestResult, error) {
-	result := make(chan *http.Response, 1)
+	result, err := request_with_context(ctx, method, URL, params, headers, jsonResp, jsonType)
+	challenge, ok := err.(*errs.Challenge)
+	if !ok {
+		return result, err
+	}
+	solve := challenge_solver()
+	if solve == nil {
+		return nil, err
+	}
+
+	solved_headers, solve_err := solve(ctx, challenge)
+	if solve_err != nil {
+		log.Printf("Request error : решатель не смог пройти проверку %s для %s. Ошибка: %v", challenge.Provider, challenge.URL, solve_err)
+		return nil, err
+	}
+	retry_headers := models.Headers{}
+	for key, value := range headers {
+		retry_headers[key] = value
+	}
+	for key, value := range solved_headers {
+		retry_headers[key] = value
+	}
+	return request_with_context(ctx, method, URL, params, retry_headers, jsonResp, jsonType)
+}
+
+func request_with_context(ctx context.Context, method, URL string, params models.Params, headers models.Headers, jsonResp bool, jsonType models.JSONResponse) (*RequestResult, error) {
+	result := make(chan *worker_result, 1)
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -125,9 +302,10 @@ func RequestWithContext(ctx context.Context, method, URL string, params models.P
 		URL:     URL,
 		params:  params,
 		headers: headers,
+		failure: &worker_failure{},
 	}
 
-	for i := 1; i <= numWorkers; i++ {
+	for i := 1; i <= request_workers(ctx); i++ {
 		wg.Add(1)
 		go worker(w_params, result, wg, i)
 	}
@@ -137,31 +315,46 @@ func RequestWithContext(ctx context.Context, method, URL string, params models.P
 		close(result)
 	}()
 
-	if resp, ok := <-result; ok {
-		req_result := &RequestResult{Response: resp}
-
-		if jsonResp {
-			if err := jsonType.Decode(resp.Body); err != nil {
-				return nil, errs.NewJsonDecodeFailureError(fmt.Sprintf("Request error : ошибка декодирования json: %v", err))
-			}
-			req_result.Json = jsonType
-			return req_result, nil
-		} else {
-			bodyBytes, err := io.ReadAll(resp.Body)
-			if err != nil {
-				error_message := fmt.Sprintf("Request error : не удалось прочитать тело ответа. Ошибка: %v", err)
-				log.Println(error_message)
-				return nil, errs.NewServiceError(error_message)
-			}
-			req_result.Data = bodyBytes
-			return req_result, nil
+	w_result, ok := <-result
+	if !ok {
+		status := w_params.failure.get()
+		if status == 0 {
+			error_message := fmt.Sprintf("Request error : ни один воркер не получил ответ сервера для %s", URL)
+			log.Println(error_message)
+			return nil, errs.NewNetworkError(error_message)
 		}
-	} else {
-		error_message := "Request error : ни один воркер не вернул ответ"
+		error_message := fmt.Sprintf("Request error : ни один воркер не вернул ответ. Сервер вернул код %d для %s", status, URL)
+		log.Println(error_message)
+		return nil, errs.NewServiceError(error_message)
+	}
+	if w_result.err != nil {
+		return nil, w_result.err
+	}
+
+	resp := w_result.resp
+	defer resp.Body.Close()
+	req_result := &RequestResult{Response: resp}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		error_message := fmt.Sprintf("Request error : не удалось прочитать тело ответа. Ошибка: %v", err)
 		log.Println(error_message)
 		return nil, errs.NewServiceError(error_message)
 	}
+	// Защита может отдать страницу проверки и с кодом 200
+	if provider := DetectChallenge(resp, bodyBytes); provider != "" {
+		return nil, challenge_error(provider, resp.Request.URL.String(), resp.StatusCode)
+	}
 
+	if jsonResp {
+		if err := jsonType.Decode(bytes.NewReader(bodyBytes)); err != nil {
+			return nil, errs.NewJsonDecodeFailureError(fmt.Sprintf("Request error : ошибка декодирования json: %v", err))
+		}
+		req_result.Json = jsonType
+		return req_result, nil
+	}
+	req_result.Data = bodyBytes
+	return req_result, nil
 }
 
 func TestURL(URL, method string, params models.Params, headers models.Headers) error {
@@ -0,0 +1,283 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+)
+
+// Признаки страницы-заглушки провайдера или РКН вместо ответа сайта (в нижнем регистре)
+var blocked_page_markers = []string{
+	"доступ к ресурсу ограничен",
+	"доступ к информационному ресурсу ограничен",
+	"eais.rkn.gov.ru",
+	"blocklist.rkn.gov.ru",
+}
+
+// Упорядоченный список зеркал (доменов) сайта с активным зеркалом.
+//
+// Запросы через Request отправляются на активное зеркало, а при сетевой ошибке, странице блокировки или проверки
+// повторяются на следующих зеркалах по порядку. Зеркало, на котором запрос удался, становится активным
+type Mirrors struct {
+	mu      sync.RWMutex
+	domains []string
+	active  int
+}
+
+// Создает список зеркал. Пустые значения и повторы пропускаются, протокол и путь отбрасываются (прим: "https://animego.me/" > "animego.me").
+// Если не передано ни одного зеркала, используются defaults
+func NewMirrors(mirrors []string, defaults ...string) *Mirrors {
+	m := &Mirrors{}
+	seen := make(map[string]bool)
+	for _, mirror := range append(append([]string{}, mirrors...), defaults...) {
+		domain := clean_domain(mirror)
+		if domain == "" || seen[domain] {
+			continue
+		}
+		seen[domain] = true
+		m.domains = append(m.domains, domain)
+	}
+	return m
+}
+
+func clean_domain(mirror string) string {
+	mirror = strings.TrimSpace(strings.ToLower(mirror))
+	if i := strings.Index(mirror, "://"); i != -1 {
+		mirror = mirror[i+3:]
+	}
+	if i := strings.IndexAny(mirror, "/?#"); i != -1 {
+		mirror = mirror[:i]
+	}
+	return mirror
+}
+
+// Возвращает активное зеркало (прим: animego.me)
+func (m *Mirrors) Active() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.domains) == 0 {
+		return ""
+	}
+	return m.domains[m.active]
+}
+
+// Возвращает все зеркала в порядке приоритета
+func (m *Mirrors) List() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string{}, m.domains...)
+}
+
+// Делает зеркало активным. Зеркала не из списка игнорируются
+func (m *Mirrors) SetActive(mirror string) {
+	domain := clean_domain(mirror)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, candidate := range m.domains {
+		if candidate == domain {
+			m.active = i
+			return
+		}
+	}
+}
+
+// Проверяет, что host - одно из зеркал или его поддомен. Возвращает найденное зеркало
+func (m *Mirrors) match(host string) (string, bool) {
+	host = strings.ToLower(host)
+	for _, domain := range m.List() {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return domain, true
+		}
+	}
+	return "", false
+}
+
+// Заменяет в ссылке домен зеркала на активное зеркало. Ссылки на другие сайты возвращаются без изменений
+//
+// прим: "https://animego.org/anime/naruto-102" > "https://animego.me/anime/naruto-102"
+func (m *Mirrors) Rewrite(link string) string {
+	return m.rewrite(link, m.Active())
+}
+
+func (m *Mirrors) rewrite(link, target string) string {
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Host == "" {
+		return link
+	}
+	domain, ok := m.match(parsed.Hostname())
+	if !ok || domain == target {
+		return link
+	}
+	port := parsed.Port()
+	parsed.Host = strings.TrimSuffix(parsed.Hostname(), domain) + target
+	if port != "" {
+		parsed.Host += ":" + port
+	}
+	return parsed.String()
+}
+
+// Порядок перебора зеркал: активное, затем остальные по порядку
+func (m *Mirrors) order() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	order := make([]string, 0, len(m.domains))
+	order = append(order, m.domains[m.active:]...)
+	return append(order, m.domains[:m.active]...)
+}
+
+// Проверяет, что ответ пришел с зеркала, а не со страницы блокировки
+func (m *Mirrors) blocked(result *RequestResult, domain string) bool {
+	if result.Response != nil && result.Response.Request != nil && result.Response.Request.URL != nil {
+		host := strings.ToLower(result.Response.Request.URL.Hostname())
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return IsBlockedPage(result.Data)
+}
+
+// Проверяет, стоит ли повторить запрос на следующем зеркале: сайт не ответил, вернул страницу блокировки или проверки.
+// Остальные ошибки (прим: код 404 или неверный json) повторятся на любом зеркале
+func failover(err error) bool {
+	var network *errs.NetworkError
+	var blocked *errs.ContentBlocked
+	var challenge *errs.Challenge
+	return errors.As(err, &network) || errors.As(err, &blocked) || errors.As(err, &challenge)
+}
+
+// Проверяет, похожа ли страница на заглушку блокировки (провайдера или РКН)
+func IsBlockedPage(body []byte) bool {
+	if len(body) == 0 {
+		return false
+	}
+	lower := strings.ToLower(string(body))
+	for _, marker := range blocked_page_markers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Выполняет запрос (см. RequestWithContext) с переключением зеркал.
+//
+// Если URL указывает на одно из зеркал, запрос отправляется на активное зеркало, а если оно не ответило
+// или вернуло страницу блокировки или проверки - на следующие. При остальных ошибках зеркала не переключаются.
+// Домен в заголовке Referer заменяется так же. Запросы на другие сайты выполняются без изменений.
+// Если в контексте есть сессия (см. WithSession), cookies остальных зеркал (прим: cookies входа) копируются на зеркало запроса.
+//
+// Возвращает результат первого успешного запроса или ошибку последнего зеркала
+func (m *Mirrors) Request(ctx context.Context, method, URL string, params models.Params, headers models.Headers, jsonResp bool, jsonType models.JSONResponse) (*RequestResult, error) {
+	parsed, err := url.Parse(URL)
+	if err != nil || len(m.List()) == 0 {
+		return RequestWithContext(ctx, method, URL, params, headers, jsonResp, jsonType)
+	}
+	if _, ok := m.match(parsed.Hostname()); !ok {
+		return RequestWithContext(ctx, method, URL, params, headers, jsonResp, jsonType)
+	}
+
+	var last_err error
+	for _, domain := range m.order() {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if session := SessionFromContext(ctx); session != nil {
+			for _, other := range m.List() {
+				session.jar.CopyDomain(other, domain)
+			}
+		}
+		mirror_headers := models.Headers{}
+		for key, value := range headers {
+			if key == "Referer" || key == "Origin" {
+				value = m.rewrite(value, domain)
+			}
+			mirror_headers[key] = value
+		}
+
+		result, err := RequestWithContext(ctx, method, m.rewrite(URL, domain), params, mirror_headers, jsonResp, jsonType)
+		if err == nil && m.blocked(result, domain) {
+			err = errs.NewContentBlockedError(fmt.Sprintf("Mirrors error : Request : зеркало %s вернуло страницу блокировки", domain))
+		}
+		if err != nil && !failover(err) {
+			return nil, err
+		}
+		if err != nil {
+			log.Printf("Mirrors warning : Request : зеркало %s недоступно, пробую следующее. Ошибка: %v", domain, err)
+			last_err = err
+			continue
+		}
+		m.SetActive(domain)
+		return result, nil
+	}
+	return nil, last_err
+}
+
+// Проверяет доступность одного зеркала запросом главной страницы
+func (m *Mirrors) Check(ctx context.Context, mirror string) error {
+	domain := clean_domain(mirror)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://%s/", domain), nil)
+	if err != nil {
+		return errs.NewServiceError(fmt.Sprintf("Mirrors error : Check : не смог создать request для %s. Ошибка: %v", domain, err))
+	}
+	resp, err := (&http.Client{Transport: request_transport()}).Do(request)
+	if err != nil {
+		return errs.NewServiceError(fmt.Sprintf("Mirrors error : Check : зеркало %s недоступно. Ошибка: %v", domain, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return errs.NewServiceError(fmt.Sprintf("Mirrors error : Check : зеркало %s вернуло код %d", domain, resp.StatusCode))
+	}
+	host := strings.ToLower(resp.Request.URL.Hostname())
+	if host != domain && !strings.HasSuffix(host, "."+domain) {
+		return errs.NewContentBlockedError(fmt.Sprintf("Mirrors error : Check : зеркало %s перенаправило на %s", domain, host))
+	}
+	return nil
+}
+
+// Проверяет все зеркала параллельно и делает активным первое доступное по порядку.
+//
+// Возвращает ошибки по зеркалам (доступные зеркала в результат не попадают)
+// и ошибку errs.ServiceError, если недоступны все зеркала
+func (m *Mirrors) Probe(ctx context.Context) (map[string]error, error) {
+	domains := m.List()
+	results := make([]error, len(domains))
+	wg := &sync.WaitGroup{}
+	for i, domain := range domains {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = m.Check(ctx, domain)
+		}()
+	}
+	wg.Wait()
+
+	failed := make(map[string]error)
+	healthy := ""
+	for i, domain := range domains {
+		if results[i] != nil {
+			failed[domain] = results[i]
+			continue
+		}
+		if healthy == "" {
+			healthy = domain
+		}
+	}
+	if healthy == "" {
+		return failed, errs.NewServiceError(fmt.Sprintf("Mirrors error : Probe : недоступны все зеркала: %v", domains))
+	}
+	m.SetActive(healthy)
+	return failed, nil
+}
@@ -0,0 +1,196 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+// Тестовые зеркала: ответ каждого хоста задается обработчиком, хост без обработчика недоступен (сетевая ошибка)
+type test_mirrors struct {
+	mu       sync.Mutex
+	hosts    map[string]http.HandlerFunc
+	requests map[string]int
+}
+
+func use_test_mirrors(t *testing.T, hosts map[string]http.HandlerFunc) *test_mirrors {
+	s := &test_mirrors{hosts: hosts, requests: make(map[string]int)}
+	SetTransport(s)
+	t.Cleanup(func() { SetTransport(nil) })
+	return s
+}
+
+func (s *test_mirrors) RoundTrip(r *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	s.requests[r.URL.Host]++
+	handler, exists := s.hosts[r.URL.Host]
+	s.mu.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("dial tcp: lookup %s: no such host", r.URL.Host)
+	}
+	recorder := httptest.NewRecorder()
+	handler(recorder, r)
+	resp := recorder.Result()
+	resp.Request = r
+	return resp, nil
+}
+
+func (s *test_mirrors) count(host string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[host]
+}
+
+func respond(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}
+}
+
+func TestMirrorsRewrite(t *testing.T) {
+	m := NewMirrors([]string{"https://animego.me/", "animego.org"})
+	m.SetActive("animego.org")
+	tests := map[string]string{
+		"https://animego.me/anime/naruto-102?a=1": "https://animego.org/anime/naruto-102?a=1",
+		"https://img.animego.me/poster.jpg":       "https://img.animego.org/poster.jpg",
+		"http://animego.me:8080/anime":            "http://animego.org:8080/anime",
+		"https://animego.org/anime":               "https://animego.org/anime",
+		"https://aniboom.one/embed/1":             "https://aniboom.one/embed/1",
+		"/anime/naruto-102":                       "/anime/naruto-102",
+	}
+	for link, want := range tests {
+		if got := m.Rewrite(link); got != want {
+			t.Errorf("Rewrite(%q) = %q, want %q", link, got, want)
+		}
+	}
+}
+
+func TestMirrorsRequestFailover(t *testing.T) {
+	sites := use_test_mirrors(t, map[string]http.HandlerFunc{
+		"blocked.test": respond(http.StatusOK, "<html>Доступ к ресурсу ограничен</html>"),
+		"backup.test":  respond(http.StatusOK, "ok"),
+	})
+	m := NewMirrors([]string{"down.test", "blocked.test", "backup.test"})
+
+	// Недоступное зеркало и страница блокировки пропускаются, зеркало с ответом становится активным
+	result, err := m.Request(context.Background(), "GET", "https://down.test/anime", nil, nil, false, nil)
+	if err != nil {
+		t.Fatalf("Request вернул ошибку: %v", err)
+	}
+	if string(result.Data) != "ok" || m.Active() != "backup.test" {
+		t.Errorf("Request = %q, активное зеркало %s", result.Data, m.Active())
+	}
+	if sites.count("down.test") == 0 || sites.count("blocked.test") == 0 {
+		t.Errorf("запросы к зеркалам: %v", sites.requests)
+	}
+
+	var network *errs.NetworkError
+	m = NewMirrors([]string{"down.test", "down2.test"})
+	if _, err := m.Request(context.Background(), "GET", "https://down.test/", nil, nil, false, nil); !errors.As(err, &network) {
+		t.Errorf("Request без доступных зеркал вернул %T: %v, want *errs.NetworkError", err, err)
+	}
+}
+
+func TestMirrorsRequestNoFailover(t *testing.T) {
+	sites := use_test_mirrors(t, map[string]http.HandlerFunc{
+		"missing.test": respond(http.StatusNotFound, "not found"),
+		"text.test":    respond(http.StatusOK, "not json"),
+		"backup.test":  respond(http.StatusOK, `{"status": "success"}`),
+	})
+
+	// Код 404 повторится на любом зеркале: запрос не переключается на следующее
+	m := NewMirrors([]string{"missing.test", "backup.test"})
+	if _, err := m.Request(context.Background(), "GET", "https://missing.test/anime/1", nil, nil, false, nil); err == nil {
+		t.Error("Request для кода 404 должен вернуть ошибку")
+	}
+	if count := sites.count("backup.test"); count != 0 || m.Active() != "missing.test" {
+		t.Errorf("после кода 404 запросов к следующему зеркалу: %d, активное зеркало %s", count, m.Active())
+	}
+
+	// Ошибка декодирования json
+	m = NewMirrors([]string{"text.test", "backup.test"})
+	var decode *errs.JsonDecodeFailure
+	if _, err := m.Request(context.Background(), "GET", "https://text.test/", nil, nil, true, &test_json{}); !errors.As(err, &decode) {
+		t.Errorf("Request с неверным json вернул %T: %v, want *errs.JsonDecodeFailure", err, err)
+	}
+	if count := sites.count("backup.test"); count != 0 {
+		t.Errorf("после ошибки json запросов к следующему зеркалу: %d", count)
+	}
+}
+
+type test_json struct {
+	Status string `json:"status"`
+}
+
+func (j *test_json) Decode(r io.Reader) error {
+	return json.NewDecoder(r).Decode(j)
+}
+
+func TestMirrorsProbe(t *testing.T) {
+	use_test_mirrors(t, map[string]http.HandlerFunc{
+		"broken.test": respond(http.StatusBadGateway, "bad gateway"),
+		"ok.test":     respond(http.StatusOK, "ok"),
+		"redirect.test": func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "https://ok.test/", http.StatusFound)
+		},
+	})
+	m := NewMirrors([]string{"down.test", "broken.test", "redirect.test", "ok.test"})
+
+	failed, err := m.Probe(context.Background())
+	if err != nil {
+		t.Fatalf("Probe вернул ошибку: %v", err)
+	}
+	if len(failed) != 3 || failed["down.test"] == nil || failed["broken.test"] == nil || failed["ok.test"] != nil {
+		t.Errorf("Probe ошибки = %v", failed)
+	}
+	var blocked *errs.ContentBlocked
+	if !errors.As(failed["redirect.test"], &blocked) {
+		t.Errorf("Probe для перенаправления на другой домен вернул %v, want *errs.ContentBlocked", failed["redirect.test"])
+	}
+	if m.Active() != "ok.test" {
+		t.Errorf("активное зеркало %s, want ok.test", m.Active())
+	}
+
+	m = NewMirrors([]string{"down.test", "broken.test"})
+	if _, err := m.Probe(context.Background()); err == nil {
+		t.Error("Probe без доступных зеркал должен вернуть ошибку")
+	}
+}
+
+func TestMirrorsRequestSessionCookies(t *testing.T) {
+	var sent string
+	use_test_mirrors(t, map[string]http.HandlerFunc{
+		"backup.test": func(w http.ResponseWriter, r *http.Request) {
+			if cookie, err := r.Cookie("REMEMBERME"); err == nil {
+				sent = cookie.Value
+			}
+			fmt.Fprint(w, "ok")
+		},
+	})
+	session, err := NewSession("")
+	if err != nil {
+		t.Fatalf("NewSession вернул ошибку: %v", err)
+	}
+	session.Jar().Add(&Cookie{Name: "REMEMBERME", Value: "token", Domain: "down.test", HostOnly: true, Path: "/"})
+	m := NewMirrors([]string{"down.test", "backup.test"})
+
+	// Cookie входа на недоступное зеркало отправляется и на зеркало, на которое переключился запрос
+	if _, err := m.Request(WithSession(context.Background(), session), "GET", "https://down.test/anime", nil, nil, false, nil); err != nil {
+		t.Fatalf("Request вернул ошибку: %v", err)
+	}
+	if sent != "token" {
+		t.Errorf("cookie на backup.test = %q, want token", sent)
+	}
+	if !session.HasCookie("backup.test", "REMEMBERME") {
+		t.Error("в сессии нет cookie для backup.test")
+	}
+}
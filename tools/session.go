@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+	"github.com/Quavke/AnimeParsersGo/models"
+)
+
+type session_key struct{}
+
+// Сессия: cookies, которые отправляются со всеми запросами через контекст (см. WithSession).
+// Cookies можно сохранить в файл и загрузить из него (формат Netscape), поэтому вход на сайт выполняется один раз
+type Session struct {
+	jar  *CookieJar
+	path string
+	// Защищает файл от одновременной записи
+	mu sync.Mutex
+}
+
+// Создает сессию.
+//
+// :path: путь до файла с cookies в формате Netscape (прим: data/cookies.txt). Если файл существует, cookies загружаются из него.
+// Пустая строка - сессия только в памяти
+func NewSession(path string) (*Session, error) {
+	s := &Session{jar: NewCookieJar(), path: path}
+	if path == "" {
+		return s, nil
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, errs.NewServiceError(fmt.Sprintf("Session error : NewSession : не удалось открыть файл %s. Ошибка: %v", path, err))
+	}
+	defer file.Close()
+	if err := s.jar.ImportNetscape(file); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Хранилище cookies сессии
+func (s *Session) Jar() *CookieJar {
+	return s.jar
+}
+
+// Сохраняет cookies (включая сессионные) в файл сессии. Для сессии без файла ничего не делает
+func (s *Session) Save() error {
+	if s.path == "" {
+		return nil
+	}
+	return s.ExportNetscapeFile(s.path)
+}
+
+// Загружает cookies из файла в формате Netscape (прим: выгруженного из браузера) и добавляет их к cookies сессии
+func (s *Session) ImportNetscapeFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errs.NewServiceError(fmt.Sprintf("Session error : ImportNetscapeFile : не удалось открыть файл %s. Ошибка: %v", path, err))
+	}
+	defer file.Close()
+	return s.jar.ImportNetscape(file)
+}
+
+// Выгружает cookies сессии (включая сессионные) в файл в формате Netscape
+func (s *Session) ExportNetscapeFile(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return errs.NewServiceError(fmt.Sprintf("Session error : ExportNetscapeFile : не удалось создать папку %s. Ошибка: %v", dir, err))
+		}
+	}
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return errs.NewServiceError(fmt.Sprintf("Session error : ExportNetscapeFile : не удалось создать файл %s. Ошибка: %v", tmp, err))
+	}
+	if err := s.jar.ExportNetscape(file, true); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return errs.NewServiceError(fmt.Sprintf("Session error : ExportNetscapeFile : не удалось записать файл %s. Ошибка: %v", tmp, err))
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errs.NewServiceError(fmt.Sprintf("Session error : ExportNetscapeFile : не удалось переименовать %s в %s. Ошибка: %v", tmp, path, err))
+	}
+	return nil
+}
+
+// Проверяет, есть ли в сессии неистекшая cookie с именем name для домена domain (или его поддоменов)
+func (s *Session) HasCookie(domain, name string) bool {
+	domain = strings.ToLower(domain)
+	for _, cookie := range s.jar.All() {
+		if cookie.Name == name && (cookie.Domain == domain || strings.HasSuffix(domain, "."+cookie.Domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Возвращает контекст, запросы с которым (см. RequestWithContext) отправляют и сохраняют cookies сессии
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, session_key{}, s)
+}
+
+// Возвращает сессию из контекста или nil
+func SessionFromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(session_key{}).(*Session)
+	return s
+}
+
+// Отправляет форму POST запросом с cookies сессии (прим: форма входа на сайт). Перенаправления выполняются автоматически.
+//
+// :URL: ссылка, на которую отправляется форма
+//
+// :form: поля формы
+//
+// :headers: заголовки запроса
+//
+// Запрос выполняется один раз (без повторов, так как POST может изменять данные).
+// Если сайт вернул страницу проверки, возвращает ошибку errs.Challenge.
+// Если код ответа не 200, возвращает RequestResult вместе с ошибкой errs.ServiceError (по Response.StatusCode можно отличить отказ сервера от ошибки запроса)
+func (s *Session) PostForm(ctx context.Context, URL string, form url.Values, headers models.Headers) (*RequestResult, error) {
+	request, err := http.NewRequestWithContext(ctx, "POST", URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		error_message := fmt.Sprintf("Session error : PostForm : http не смог создать request. Ошибка: %v", err)
+		log.Println(error_message)
+		return nil, errs.NewServiceError(error_message)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	client := &http.Client{
+		Timeout:   15 * time.Second,
+		Jar:       s.jar,
+		Transport: request_transport(),
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		error_message := fmt.Sprintf("Session error : PostForm : http клиент не смог выполнить запрос. Ошибка: %v", err)
+		log.Println(error_message)
+		return nil, errs.NewServiceError(error_message)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		error_message := fmt.Sprintf("Session error : PostForm : не удалось прочитать тело ответа. Ошибка: %v", err)
+		log.Println(error_message)
+		return nil, errs.NewServiceError(error_message)
+	}
+	if provider := DetectChallenge(resp, body); provider != "" {
+		return nil, challenge_error(provider, URL, resp.StatusCode)
+	}
+	result := &RequestResult{Data: body, Response: resp}
+	if resp.StatusCode != http.StatusOK {
+		error_message := fmt.Sprintf("Session error : PostForm : Сервер не вернул ожидаемый код 200. Код: %d", resp.StatusCode)
+		log.Println(error_message)
+		return result, errs.NewServiceError(error_message)
+	}
+	return result, nil
+}
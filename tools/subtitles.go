@@ -0,0 +1,309 @@
+package tools
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+// Форматы субтитров
+const (
+	SubtitleVTT = "vtt"
+	SubtitleASS = "ass"
+	SubtitleSRT = "srt"
+)
+
+type subtitle_cue struct {
+	start time.Duration
+	end   time.Duration
+	// Текст с тегами <i>, <b>, <u> и переносами строк \n
+	text string
+}
+
+var (
+	ass_override_re = regexp.MustCompile(`\{[^}]*\}`)
+	ass_style_re    = regexp.MustCompile(`\\([ibu])([01])`)
+	html_tag_re     = regexp.MustCompile(`</?([a-zA-Z]+)[^>]*>`)
+	// Теги WebVTT (классы, голоса, время караоке) и html сущности, которые не экранируются в write_vtt
+	vtt_tag_re       = regexp.MustCompile(`^(</?(i|b|u|c|v|lang|ruby|rt)([.\s][^<>]*)?>|<\d[\d:.]*>)`)
+	vtt_timestamp_re = regexp.MustCompile(`<\d[\d:.]*>`)
+	html_entity_re   = regexp.MustCompile(`^&(amp|lt|gt|nbsp|lrm|rlm|quot|apos|#\d+|#[xX][0-9a-fA-F]+);`)
+	m3u8_attr_re     = regexp.MustCompile(`([A-Z0-9-]+)=("[^"]*"|[^,]*)`)
+)
+
+// Разбирает список атрибутов тега m3u8 (прим: TYPE=SUBTITLES,LANGUAGE="ru",URI="subs.m3u8")
+//
+// Возвращает словарь атрибут > значение без кавычек
+func ParseM3U8Attributes(attributes string) map[string]string {
+	result := make(map[string]string)
+	for _, match := range m3u8_attr_re.FindAllStringSubmatch(attributes, -1) {
+		result[match[1]] = strings.Trim(match[2], "\"")
+	}
+	return result
+}
+
+// Конвертирует субтитры из одного формата в другой.
+//
+// :content: содержимое файла субтитров
+//
+// :from: исходный формат (SubtitleVTT, SubtitleASS или SubtitleSRT)
+//
+// :to: формат результата (SubtitleVTT или SubtitleASS)
+func ConvertSubtitles(content, from, to string) (string, error) {
+	var cues []*subtitle_cue
+	var err error
+	switch from {
+	case SubtitleASS:
+		cues, err = parse_ass(content)
+	case SubtitleVTT, SubtitleSRT:
+		cues, err = parse_vtt(content)
+	default:
+		return "", errs.NewUnexpectedBehaviorError(fmt.Sprintf("Subtitles error : ConvertSubtitles : неизвестный исходный формат субтитров %q", from))
+	}
+	if err != nil {
+		return "", err
+	}
+	switch to {
+	case SubtitleVTT:
+		return write_vtt(cues), nil
+	case SubtitleASS:
+		return write_ass(cues), nil
+	default:
+		return "", errs.NewUnexpectedBehaviorError(fmt.Sprintf("Subtitles error : ConvertSubtitles : неизвестный формат результата %q", to))
+	}
+}
+
+// Конвертирует субтитры ASS/SSA в WebVTT. Стили и позиционирование не переносятся, кроме курсива, жирного и подчеркнутого текста
+func AssToVTT(content string) (string, error) {
+	return ConvertSubtitles(content, SubtitleASS, SubtitleVTT)
+}
+
+// Конвертирует субтитры WebVTT (или SRT) в ASS со стилем по умолчанию
+func VTTToAss(content string) (string, error) {
+	return ConvertSubtitles(content, SubtitleVTT, SubtitleASS)
+}
+
+// Разбирает время ASS (прим: 0:01:02.35)
+func parse_ass_time(value string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("неверный формат времени %q", value)
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("неверный формат времени %q", value)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("неверный формат времени %q", value)
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, fmt.Errorf("неверный формат времени %q", value)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second)).Round(time.Millisecond), nil
+}
+
+// Разбирает время WebVTT/SRT (прим: 00:01:02.350, 01:02.350, 00:01:02,350)
+func parse_vtt_time(value string) (time.Duration, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", ".")
+	parts := strings.Split(value, ":")
+	if len(parts) == 2 {
+		parts = append([]string{"0"}, parts...)
+	}
+	return parse_ass_time(strings.Join(parts, ":"))
+}
+
+func parse_ass(content string) ([]*subtitle_cue, error) {
+	cues := make([]*subtitle_cue, 0)
+	in_events := false
+	fields := []string{"Layer", "Start", "End", "Style", "Name", "MarginL", "MarginR", "MarginV", "Effect", "Text"}
+
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+		if strings.HasPrefix(line, "[") {
+			in_events = strings.EqualFold(line, "[Events]")
+			continue
+		}
+		if !in_events {
+			continue
+		}
+		if strings.HasPrefix(line, "Format:") {
+			fields = strings.Split(strings.TrimPrefix(line, "Format:"), ",")
+			for i := range fields {
+				fields[i] = strings.TrimSpace(fields[i])
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, "Dialogue:") {
+			continue
+		}
+		values := strings.SplitN(strings.TrimPrefix(line, "Dialogue:"), ",", len(fields))
+		if len(values) != len(fields) {
+			continue
+		}
+		cue := &subtitle_cue{}
+		for i, field := range fields {
+			var err error
+			switch field {
+			case "Start":
+				cue.start, err = parse_ass_time(values[i])
+			case "End":
+				cue.end, err = parse_ass_time(values[i])
+			case "Text":
+				cue.text = ass_text_to_html(values[i])
+			}
+			if err != nil {
+				return nil, errs.NewUnexpectedBehaviorError(fmt.Sprintf("Subtitles error : parse_ass : строка %q. Ошибка: %v", line, err))
+			}
+		}
+		if cue.text != "" {
+			cues = append(cues, cue)
+		}
+	}
+	if len(cues) == 0 {
+		return nil, errs.NewNoResultsError("Subtitles error : parse_ass : в файле не найдено ни одной строки Dialogue")
+	}
+	return cues, nil
+}
+
+// Переводит текст ASS в текст с тегами <i>, <b>, <u>
+func ass_text_to_html(text string) string {
+	text = ass_override_re.ReplaceAllStringFunc(text, func(block string) string {
+		var b strings.Builder
+		for _, match := range ass_style_re.FindAllStringSubmatch(block, -1) {
+			if match[2] == "1" {
+				b.WriteString("<" + match[1] + ">")
+			} else {
+				b.WriteString("</" + match[1] + ">")
+			}
+		}
+		return b.String()
+	})
+	text = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(text)
+	return strings.TrimSpace(text)
+}
+
+func parse_vtt(content string) ([]*subtitle_cue, error) {
+	cues := make([]*subtitle_cue, 0)
+	blocks := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n")
+	for _, block := range blocks {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		for i, line := range lines {
+			if !strings.Contains(line, "-->") {
+				continue
+			}
+			times := strings.SplitN(line, "-->", 2)
+			start, err := parse_vtt_time(times[0])
+			if err != nil {
+				return nil, errs.NewUnexpectedBehaviorError(fmt.Sprintf("Subtitles error : parse_vtt : строка %q. Ошибка: %v", line, err))
+			}
+			// После времени окончания могут идти настройки отображения (прим: align:start)
+			end_fields := strings.Fields(times[1])
+			if len(end_fields) == 0 {
+				return nil, errs.NewUnexpectedBehaviorError(fmt.Sprintf("Subtitles error : parse_vtt : строка %q без времени окончания", line))
+			}
+			end, err := parse_vtt_time(end_fields[0])
+			if err != nil {
+				return nil, errs.NewUnexpectedBehaviorError(fmt.Sprintf("Subtitles error : parse_vtt : строка %q. Ошибка: %v", line, err))
+			}
+			text := strings.TrimSpace(strings.Join(lines[i+1:], "\n"))
+			if text != "" {
+				cues = append(cues, &subtitle_cue{start: start, end: end, text: text})
+			}
+			break
+		}
+	}
+	if len(cues) == 0 {
+		return nil, errs.NewNoResultsError("Subtitles error : parse_vtt : в файле не найдено ни одной реплики")
+	}
+	return cues, nil
+}
+
+func format_vtt_time(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+func format_ass_time(d time.Duration) string {
+	cs := d.Milliseconds() / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
+
+func write_vtt(cues []*subtitle_cue) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, cue := range cues {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", format_vtt_time(cue.start), format_vtt_time(cue.end), escape_vtt_text(cue.text))
+	}
+	return b.String()
+}
+
+// Экранирует &, < и > в тексте реплики WebVTT. Теги WebVTT (прим: <i>, <c.yellow>) и уже экранированные сущности (прим: &amp;) не меняются
+func escape_vtt_text(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '<':
+			if tag := vtt_tag_re.FindString(text[i:]); tag != "" {
+				b.WriteString(tag)
+				i += len(tag)
+				continue
+			}
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '&':
+			if entity := html_entity_re.FindString(text[i:]); entity != "" {
+				b.WriteString(entity)
+				i += len(entity)
+				continue
+			}
+			b.WriteString("&amp;")
+		default:
+			b.WriteByte(text[i])
+		}
+		i++
+	}
+	return b.String()
+}
+
+const ass_header = `[Script Info]
+ScriptType: v4.00+
+PlayResX: 1920
+PlayResY: 1080
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,54,&H00FFFFFF,&H000000FF,&H00000000,&H64000000,0,0,0,0,100,100,0,0,1,2,1,2,40,40,40,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+func write_ass(cues []*subtitle_cue) string {
+	var b strings.Builder
+	b.WriteString(ass_header)
+	for _, cue := range cues {
+		text := html_tag_re.ReplaceAllStringFunc(cue.text, func(tag string) string {
+			match := html_tag_re.FindStringSubmatch(tag)
+			name := strings.ToLower(match[1])
+			if name != "i" && name != "b" && name != "u" {
+				return ""
+			}
+			if strings.HasPrefix(tag, "</") {
+				return "{\\" + name + "0}"
+			}
+			return "{\\" + name + "1}"
+		})
+		text = html.UnescapeString(vtt_timestamp_re.ReplaceAllString(text, ""))
+		text = strings.ReplaceAll(text, "\n", `\N`)
+		fmt.Fprintf(&b, "Dialogue: 0,%s,%s,Default,,0,0,0,,%s\n", format_ass_time(cue.start), format_ass_time(cue.end), text)
+	}
+	return b.String()
+}
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+const test_ass = `[Script Info]
+Title: test
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize
+Style: Default,Arial,20
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Comment: 0,0:00:00.00,0:00:01.00,Default,,0,0,0,,не реплика
+Dialogue: 0,0:00:01.50,0:00:03.25,Default,,0,0,0,,{\an8}{\i1}Привет{\i0}, мир!\NВторая строка
+Dialogue: 0,0:01:02.00,1:00:00.10,Default,,0,0,0,,Tom & Jerry <3, a, b
+`
+
+func TestAssToVTT(t *testing.T) {
+	got, err := AssToVTT(test_ass)
+	if err != nil {
+		t.Fatalf("AssToVTT вернул ошибку: %v", err)
+	}
+	want := "WEBVTT\n\n" +
+		"00:00:01.500 --> 00:00:03.250\n<i>Привет</i>, мир!\nВторая строка\n\n" +
+		"00:01:02.000 --> 01:00:00.100\nTom &amp; Jerry &lt;3, a, b\n\n"
+	if got != want {
+		t.Errorf("AssToVTT = %q, want %q", got, want)
+	}
+}
+
+func TestVTTToAss(t *testing.T) {
+	vtt := "WEBVTT\n\nNOTE комментарий\n\n1\n00:00:01.500 --> 00:00:03.250 align:start\n<i>Привет</i>, <c.yellow>мир</c>!\nВторая строка\n\n" +
+		"01:02.000 --> 01:03.000\nTom &amp; Jerry &lt;3 <00:01:02.500>караоке\n"
+	got, err := VTTToAss(vtt)
+	if err != nil {
+		t.Fatalf("VTTToAss вернул ошибку: %v", err)
+	}
+	if !strings.HasPrefix(got, "[Script Info]") {
+		t.Errorf("VTTToAss: результат без заголовка ASS: %q", got)
+	}
+	for _, line := range []string{
+		`Dialogue: 0,0:00:01.50,0:00:03.25,Default,,0,0,0,,{\i1}Привет{\i0}, мир!\NВторая строка`,
+		`Dialogue: 0,0:01:02.00,0:01:03.00,Default,,0,0,0,,Tom & Jerry <3 караоке`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("VTTToAss: в результате нет строки %q:\n%s", line, got)
+		}
+	}
+}
+
+func TestConvertSubtitlesSRT(t *testing.T) {
+	srt := "1\r\n00:00:01,000 --> 00:00:02,500\r\nTom & Jerry\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\n<i>a < b</i>\r\n"
+	got, err := ConvertSubtitles(srt, SubtitleSRT, SubtitleVTT)
+	if err != nil {
+		t.Fatalf("ConvertSubtitles(srt, vtt) вернул ошибку: %v", err)
+	}
+	want := "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\nTom &amp; Jerry\n\n00:00:03.000 --> 00:00:04.000\n<i>a &lt; b</i>\n\n"
+	if got != want {
+		t.Errorf("ConvertSubtitles(srt, vtt) = %q, want %q", got, want)
+	}
+
+	// VTT > VTT не экранирует текст повторно
+	again, err := ConvertSubtitles(got, SubtitleVTT, SubtitleVTT)
+	if err != nil || again != got {
+		t.Errorf("ConvertSubtitles(vtt, vtt) = %q, %v, want %q", again, err, got)
+	}
+
+	ass, err := ConvertSubtitles(srt, SubtitleSRT, SubtitleASS)
+	if err != nil {
+		t.Fatalf("ConvertSubtitles(srt, ass) вернул ошибку: %v", err)
+	}
+	if !strings.Contains(ass, `0:00:03.00,0:00:04.00,Default,,0,0,0,,{\i1}a < b{\i0}`) {
+		t.Errorf("ConvertSubtitles(srt, ass) = %q", ass)
+	}
+}
+
+func TestConvertSubtitlesErrors(t *testing.T) {
+	if _, err := ConvertSubtitles("WEBVTT\n\n", SubtitleVTT, SubtitleASS); err == nil {
+		t.Error("ConvertSubtitles для файла без реплик должен вернуть ошибку")
+	}
+	if _, err := ConvertSubtitles(test_ass, "txt", SubtitleVTT); err == nil {
+		t.Error("ConvertSubtitles для неизвестного исходного формата должен вернуть ошибку")
+	}
+	if _, err := ConvertSubtitles(test_ass, SubtitleASS, SubtitleSRT); err == nil {
+		t.Error("ConvertSubtitles для неизвестного формата результата должен вернуть ошибку")
+	}
+	if _, err := VTTToAss("WEBVTT\n\nxx:01.000 --> 00:02.000\ntext\n"); err == nil {
+		t.Error("VTTToAss для неверного времени должен вернуть ошибку")
+	}
+}
+
+func TestEscapeVTTText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Tom & Jerry", "Tom &amp; Jerry"},
+		{"a < b > c", "a &lt; b &gt; c"},
+		{"<i>курсив</i> <b>жирный</b> <u>u</u>", "<i>курсив</i> <b>жирный</b> <u>u</u>"},
+		{"<v Bob>текст</v> <c.yellow.bg_blue>цвет</c>", "<v Bob>текст</v> <c.yellow.bg_blue>цвет</c>"},
+		{"&amp; &lt; &#39; &nbsp;", "&amp; &lt; &#39; &nbsp;"},
+		{"<script>alert(1)</script>", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"a --> b", "a --&gt; b"},
+	}
+	for _, tt := range tests {
+		if got := escape_vtt_text(tt.text); got != tt.want {
+			t.Errorf("escape_vtt_text(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseM3U8Attributes(t *testing.T) {
+	got := ParseM3U8Attributes(`TYPE=SUBTITLES,GROUP-ID="subs",NAME="Русские, полные",LANGUAGE="ru",URI="subs/ru.m3u8"`)
+	want := map[string]string{"TYPE": "SUBTITLES", "GROUP-ID": "subs", "NAME": "Русские, полные", "LANGUAGE": "ru", "URI": "subs/ru.m3u8"}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("ParseM3U8Attributes[%s] = %q, want %q", key, got[key], value)
+		}
+	}
+}
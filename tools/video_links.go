@@ -0,0 +1,208 @@
+package tools
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	errs "github.com/Quavke/AnimeParsersGo/errors"
+)
+
+// Площадки видео в VideoLink.Platform
+const (
+	VideoYouTube     = "youtube"
+	VideoVK          = "vk"
+	VideoRutube      = "rutube"
+	VideoDailymotion = "dailymotion"
+	VideoVimeo       = "vimeo"
+	VideoSibnet      = "sibnet"
+	VideoOK          = "ok"
+	VideoUnknown     = "unknown"
+)
+
+var (
+	youtube_id_re     = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	vk_video_re       = regexp.MustCompile(`video(-?\d+)_(\d+)`)
+	rutube_id_re      = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	dailymotion_id_re = regexp.MustCompile(`^x[0-9a-z]+$`)
+	digits_re         = regexp.MustCompile(`^\d+$`)
+	sibnet_video_re   = regexp.MustCompile(`^video(\d+)`)
+	time_part_re      = regexp.MustCompile(`(\d+)([hms])`)
+)
+
+// Ссылка на видео (трейлер, ролик), приведенная к единому виду
+type VideoLink struct {
+	// Площадка (прим: VideoYouTube). VideoUnknown - площадка не распознана, WatchURL и EmbedURL равны исходной ссылке
+	Platform string `json:"platform"`
+	// id видео на площадке (прим: dQw4w9WgXcQ, для vk: -123_456)
+	ID string `json:"id"`
+	// Исходная ссылка
+	URL      string `json:"url"`
+	WatchURL string `json:"watch_url"`
+	EmbedURL string `json:"embed_url"`
+	// Ссылка на превью ("" - площадка не дает превью по id без запроса)
+	ThumbnailURL string `json:"thumbnail_url"`
+	// Время начала в секундах (0 - с начала)
+	Start int `json:"start"`
+}
+
+// Разбирает ссылку на видео без запросов к сети: определяет площадку, id видео и строит ссылки для просмотра, встраивания и превью.
+//
+// :link: ссылка на видео или плеер (прим: https://www.youtube.com/embed/dQw4w9WgXcQ, //vk.com/video_ext.php?oid=-1&id=2&hash=abc)
+//
+// Если ссылку не удалось разобрать, возвращает ошибку errs.PostArgumentsError. Для нераспознанной площадки ошибки нет (Platform = VideoUnknown)
+//
+// Возвращает ссылку на VideoLink
+func ResolveVideoLink(link string) (*VideoLink, error) {
+	raw := strings.TrimSpace(link)
+	if strings.HasPrefix(raw, "//") {
+		raw = "https:" + raw
+	} else if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return nil, errs.NewPostArgumentsError(fmt.Sprintf("Video links error : ResolveVideoLink : не удалось разобрать ссылку %q", link))
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	segments := strings.FieldsFunc(parsed.Path, func(r rune) bool { return r == '/' })
+	query := parsed.Query()
+	res := &VideoLink{Platform: VideoUnknown, URL: link}
+
+	switch {
+	case host == "youtu.be" || host == "youtube.com" || host == "youtube-nocookie.com" || host == "music.youtube.com":
+		id := query.Get("v")
+		if host == "youtu.be" && len(segments) > 0 {
+			id = segments[0]
+		} else if len(segments) > 1 && (segments[0] == "embed" || segments[0] == "shorts" || segments[0] == "v" || segments[0] == "live") {
+			id = segments[1]
+		}
+		if youtube_id_re.MatchString(id) {
+			res.Platform, res.ID = VideoYouTube, id
+			res.Start = parse_video_start(query.Get("t"), query.Get("start"))
+			res.WatchURL = "https://www.youtube.com/watch?v=" + id
+			res.EmbedURL = "https://www.youtube.com/embed/" + id
+			res.ThumbnailURL = fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", id)
+			if res.Start > 0 {
+				res.WatchURL += fmt.Sprintf("&t=%ds", res.Start)
+				res.EmbedURL += fmt.Sprintf("?start=%d", res.Start)
+			}
+		}
+	case host == "vk.com" || host == "vk.ru" || host == "vkvideo.ru":
+		oid, id := query.Get("oid"), query.Get("id")
+		if oid == "" || id == "" {
+			// video-123_456 в пути или в параметре z (прим: vk.com/videos-1?z=video-1_2)
+			match := vk_video_re.FindStringSubmatch(parsed.Path)
+			if match == nil {
+				match = vk_video_re.FindStringSubmatch(query.Get("z"))
+			}
+			if match != nil {
+				oid, id = match[1], match[2]
+			}
+		}
+		if oid != "" && digits_re.MatchString(strings.TrimPrefix(oid, "-")) && digits_re.MatchString(id) {
+			res.Platform, res.ID = VideoVK, oid+"_"+id
+			res.WatchURL = fmt.Sprintf("https://vk.com/video%s_%s", oid, id)
+			res.EmbedURL = fmt.Sprintf("https://vk.com/video_ext.php?oid=%s&id=%s", oid, id)
+			// Без hash встраивание работает только для публичных видео
+			if hash := query.Get("hash"); hash != "" {
+				res.EmbedURL += "&hash=" + url.QueryEscape(hash)
+			}
+		}
+	case host == "rutube.ru":
+		id := ""
+		for i, segment := range segments {
+			if (segment == "video" || segment == "embed" || segment == "shorts") && i+1 < len(segments) {
+				id = segments[i+1]
+			}
+		}
+		if rutube_id_re.MatchString(id) {
+			res.Platform, res.ID = VideoRutube, id
+			res.WatchURL = fmt.Sprintf("https://rutube.ru/video/%s/", id)
+			res.EmbedURL = "https://rutube.ru/play/embed/" + id
+			res.ThumbnailURL = fmt.Sprintf("https://rutube.ru/api/video/%s/thumbnail/?redirect=1", id)
+		}
+	case host == "dailymotion.com" || host == "dai.ly" || host == "geo.dailymotion.com":
+		id := query.Get("video")
+		if host == "dai.ly" && len(segments) > 0 {
+			id = segments[0]
+		}
+		for i, segment := range segments {
+			if segment == "video" && i+1 < len(segments) {
+				id = segments[i+1]
+			}
+		}
+		// Ссылки вида /video/x7tgad0_naruto-trailer
+		id, _, _ = strings.Cut(id, "_")
+		if dailymotion_id_re.MatchString(id) {
+			res.Platform, res.ID = VideoDailymotion, id
+			res.WatchURL = "https://www.dailymotion.com/video/" + id
+			res.EmbedURL = "https://www.dailymotion.com/embed/video/" + id
+			res.ThumbnailURL = "https://www.dailymotion.com/thumbnail/video/" + id
+		}
+	case host == "vimeo.com" || host == "player.vimeo.com":
+		for _, segment := range segments {
+			if digits_re.MatchString(segment) {
+				res.Platform, res.ID = VideoVimeo, segment
+				res.WatchURL = "https://vimeo.com/" + segment
+				res.EmbedURL = "https://player.vimeo.com/video/" + segment
+				break
+			}
+		}
+	case host == "video.sibnet.ru":
+		id := query.Get("videoid")
+		if id == "" && len(segments) > 0 {
+			if match := sibnet_video_re.FindStringSubmatch(segments[len(segments)-1]); match != nil {
+				id = match[1]
+			}
+		}
+		if digits_re.MatchString(id) {
+			res.Platform, res.ID = VideoSibnet, id
+			res.WatchURL = fmt.Sprintf("https://video.sibnet.ru/video%s/", id)
+			res.EmbedURL = "https://video.sibnet.ru/shell.php?videoid=" + id
+			res.ThumbnailURL = fmt.Sprintf("https://video.sibnet.ru/upload/cover/video_%s.jpg", id)
+		}
+	case host == "ok.ru":
+		if len(segments) > 1 && (segments[0] == "video" || segments[0] == "videoembed") && digits_re.MatchString(segments[1]) {
+			res.Platform, res.ID = VideoOK, segments[1]
+			res.WatchURL = "https://ok.ru/video/" + segments[1]
+			res.EmbedURL = "https://ok.ru/videoembed/" + segments[1]
+		}
+	}
+
+	if res.Platform == VideoUnknown {
+		res.WatchURL, res.EmbedURL = raw, raw
+	}
+	return res, nil
+}
+
+// Время начала видео из параметров t (прим: 90, 90s, 1m30s) или start (в секундах)
+func parse_video_start(t, start string) int {
+	if start != "" {
+		seconds, _ := strconv.Atoi(start)
+		return max(seconds, 0)
+	}
+	if t == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSuffix(t, "s")); err == nil {
+		return max(seconds, 0)
+	}
+	seconds := 0
+	for _, match := range time_part_re.FindAllStringSubmatch(t, -1) {
+		value, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "h":
+			seconds += value * 3600
+		case "m":
+			seconds += value * 60
+		case "s":
+			seconds += value
+		}
+	}
+	return seconds
+}
@@ -0,0 +1,67 @@
+package tools
+
+import "testing"
+
+func TestResolveVideoLink(t *testing.T) {
+	tests := []struct {
+		link     string
+		platform string
+		id       string
+		embed    string
+		start    int
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", VideoYouTube, "dQw4w9WgXcQ", "https://www.youtube.com/embed/dQw4w9WgXcQ", 0},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=1m30s", VideoYouTube, "dQw4w9WgXcQ", "https://www.youtube.com/embed/dQw4w9WgXcQ?start=90", 90},
+		{"//www.youtube.com/embed/dQw4w9WgXcQ?start=15", VideoYouTube, "dQw4w9WgXcQ", "https://www.youtube.com/embed/dQw4w9WgXcQ?start=15", 15},
+		{"https://youtube.com/shorts/dQw4w9WgXcQ", VideoYouTube, "dQw4w9WgXcQ", "https://www.youtube.com/embed/dQw4w9WgXcQ", 0},
+		{"https://youtu.be/dQw4w9WgXcQ?t=42", VideoYouTube, "dQw4w9WgXcQ", "https://www.youtube.com/embed/dQw4w9WgXcQ?start=42", 42},
+		{"https://m.youtube.com/watch?v=dQw4w9WgXcQ", VideoYouTube, "dQw4w9WgXcQ", "https://www.youtube.com/embed/dQw4w9WgXcQ", 0},
+		{"//vk.com/video_ext.php?oid=-123&id=456&hash=abc", VideoVK, "-123_456", "https://vk.com/video_ext.php?oid=-123&id=456&hash=abc", 0},
+		{"https://vk.com/videos-1?z=video-1_2", VideoVK, "-1_2", "https://vk.com/video_ext.php?oid=-1&id=2", 0},
+		{"https://vkvideo.ru/video-1_2", VideoVK, "-1_2", "https://vk.com/video_ext.php?oid=-1&id=2", 0},
+		{"https://rutube.ru/video/0123456789abcdef0123456789abcdef/", VideoRutube, "0123456789abcdef0123456789abcdef", "https://rutube.ru/play/embed/0123456789abcdef0123456789abcdef", 0},
+		{"https://www.dailymotion.com/video/x7tgad0_naruto-trailer", VideoDailymotion, "x7tgad0", "https://www.dailymotion.com/embed/video/x7tgad0", 0},
+		{"https://dai.ly/x7tgad0", VideoDailymotion, "x7tgad0", "https://www.dailymotion.com/embed/video/x7tgad0", 0},
+		{"https://vimeo.com/123456", VideoVimeo, "123456", "https://player.vimeo.com/video/123456", 0},
+		{"https://video.sibnet.ru/video4567-Naruto/", VideoSibnet, "4567", "https://video.sibnet.ru/shell.php?videoid=4567", 0},
+		{"https://video.sibnet.ru/shell.php?videoid=4567", VideoSibnet, "4567", "https://video.sibnet.ru/shell.php?videoid=4567", 0},
+		{"https://ok.ru/video/123456", VideoOK, "123456", "https://ok.ru/videoembed/123456", 0},
+		{"https://ok.ru/videoembed/123456", VideoOK, "123456", "https://ok.ru/videoembed/123456", 0},
+		{"https://example.com/trailer.mp4", VideoUnknown, "", "https://example.com/trailer.mp4", 0},
+		{"https://www.youtube.com/watch?v=short", VideoUnknown, "", "https://www.youtube.com/watch?v=short", 0},
+	}
+	for _, tt := range tests {
+		got, err := ResolveVideoLink(tt.link)
+		if err != nil {
+			t.Errorf("ResolveVideoLink(%q) вернул ошибку: %v", tt.link, err)
+			continue
+		}
+		if got.Platform != tt.platform || got.ID != tt.id || got.EmbedURL != tt.embed || got.Start != tt.start {
+			t.Errorf("ResolveVideoLink(%q) = %s %q %q start=%d, want %s %q %q start=%d",
+				tt.link, got.Platform, got.ID, got.EmbedURL, got.Start, tt.platform, tt.id, tt.embed, tt.start)
+		}
+	}
+
+	if _, err := ResolveVideoLink("://"); err == nil {
+		t.Error("ResolveVideoLink для неверной ссылки должен вернуть ошибку")
+	}
+}
+
+func TestParseVideoStart(t *testing.T) {
+	tests := []struct {
+		t, start string
+		want     int
+	}{
+		{"90", "", 90},
+		{"90s", "", 90},
+		{"1m30s", "", 90},
+		{"1h2m3s", "", 3723},
+		{"", "15", 15},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		if got := parse_video_start(tt.t, tt.start); got != tt.want {
+			t.Errorf("parse_video_start(%q, %q) = %d, want %d", tt.t, tt.start, got, tt.want)
+		}
+	}
+}